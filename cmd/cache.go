@@ -0,0 +1,78 @@
+// Package cmd provides CLI commands for the Notion MCP server.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nixihz/notion-as-mcp/internal/cache"
+	"github.com/nixihz/notion-as-mcp/internal/config"
+)
+
+// cacheCmd returns the cache command.
+func cacheCmd() *cobra.Command {
+	return newCacheCmd(func(cfg *config.Config) (cache.Cache, error) {
+		return cache.NewCache(cache.WithTTL(cfg.CacheTTL), cache.WithDir(cfg.CacheDir))
+	})
+}
+
+// newCacheCmd returns the cache command using openCache to open the
+// configured cache.
+func newCacheCmd(openCache func(*config.Config) (cache.Cache, error)) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Export or import the cache",
+		Long: `Copy the cache in CACHE_DIR to or from a single snapshot file, so a warm
+cache can be shipped with a deployment or restored after a wipe. Set
+CACHE_SNAPSHOT to load a snapshot every time the server starts.`,
+	}
+
+	// run loads the configuration, opens the cache and calls fn with it.
+	run := func(cmd *cobra.Command, fn func(c cache.Cache) error) error {
+		cfg, err := config.LoadFile(configFile)
+		if err != nil {
+			return fmt.Errorf("load config: %w", err)
+		}
+		c, err := openCache(cfg)
+		if err != nil {
+			return fmt.Errorf("open cache: %w", err)
+		}
+		defer c.Close()
+		return fn(c)
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "export <path>",
+		Short: "Write the unexpired cache entries to a snapshot file",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd, func(c cache.Cache) error {
+				n, err := cache.ExportSnapshot(cmd.Context(), c, args[0])
+				if err != nil {
+					return fmt.Errorf("export snapshot: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Exported %d entries to %s\n", n, args[0])
+				return nil
+			})
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "import <path>",
+		Short: "Load a snapshot file into the cache, skipping expired entries",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(cmd, func(c cache.Cache) error {
+				n, err := cache.ImportSnapshot(cmd.Context(), c, args[0])
+				if err != nil {
+					return fmt.Errorf("import snapshot: %w", err)
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "Imported %d entries from %s\n", n, args[0])
+				return nil
+			})
+		},
+	})
+
+	return cmd
+}
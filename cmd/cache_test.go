@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nixihz/notion-as-mcp/internal/cache"
+	"github.com/nixihz/notion-as-mcp/internal/config"
+)
+
+func TestCacheCmd(t *testing.T) {
+	t.Setenv("NOTION_API_KEY", "test-api-key")
+	t.Setenv("NOTION_DATABASE_ID", "test-db-id")
+	ctx := context.Background()
+	snapshot := filepath.Join(t.TempDir(), "cache.json")
+
+	run := func(t *testing.T, c cache.Cache, args ...string) string {
+		t.Helper()
+		cmd := newCacheCmd(func(*config.Config) (cache.Cache, error) { return c, nil })
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("cache %v failed: %v", args, err)
+		}
+		return out.String()
+	}
+
+	source, _ := cache.NewMemoryCache()
+	for key, value := range map[string]string{"mcp:resources": "[]", "render:page-1": "# Page"} {
+		if err := source.Set(ctx, key, []byte(value), time.Hour); err != nil {
+			t.Fatalf("Set() failed: %v", err)
+		}
+	}
+	if out := run(t, source, "export", snapshot); !strings.Contains(out, "Exported 2 entries") {
+		t.Errorf("export output = %q, want 2 entries exported", out)
+	}
+
+	// The command closes the cache, so import into a directory and reopen it
+	dir := t.TempDir()
+	target, _ := cache.NewFileCache(cache.WithDir(dir))
+	if out := run(t, target, "import", snapshot); !strings.Contains(out, "Imported 2 entries") {
+		t.Errorf("import output = %q, want 2 entries imported", out)
+	}
+	reopened, _ := cache.NewFileCache(cache.WithDir(dir))
+	if got, _ := reopened.Get(ctx, "render:page-1"); string(got) != "# Page" {
+		t.Errorf("imported render:page-1 = %q, want %q", got, "# Page")
+	}
+}
@@ -0,0 +1,33 @@
+// Package cmd provides CLI commands for the Notion MCP server.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+)
+
+// configCmd returns the config command.
+func configCmd() *cobra.Command {
+	var flags serverFlags
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show the effective configuration",
+		Long: `Print the resolved configuration and the source each value came from
+(default, file, dotenv, env or flag). Secrets such as the API key are redacted.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadFile(configFile, flags.layer())
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			return cfg.Describe(cmd.OutOrStdout())
+		},
+	}
+
+	flags.register(cmd)
+
+	return cmd
+}
@@ -0,0 +1,72 @@
+// Package cmd provides CLI commands for the Notion MCP server.
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+	"github.com/nixihz/notion-as-mcp/internal/server"
+)
+
+// doctorCmd returns the doctor command.
+func doctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check the Notion API key and database are reachable",
+		Long: `Load the configuration and query a single page of each configured
+database, reporting whether the server can reach Notion. Failures are
+classified as authentication, database not found, access denied, rate
+limit or network errors. Exits nonzero on failure.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadFile(configFile)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			client, err := server.NewNotionClient(cfg)
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			if err := client.CheckAccess(cmd.Context()); err != nil {
+				fmt.Fprintf(out, "FAIL  %s\n      %v\n", diagnose(err), err)
+				cmd.SilenceUsage = true
+				return fmt.Errorf("notion is not reachable")
+			}
+			fmt.Fprintf(out, "OK    Notion API reachable, database %s readable\n", cfg.NotionDatabaseID)
+			return nil
+		},
+	}
+}
+
+// diagnose describes the likely cause of a failed Notion request.
+func diagnose(err error) string {
+	switch {
+	case errors.Is(err, notion.ErrUnauthorized):
+		return "Authentication failed: check NOTION_API_KEY"
+	case errors.Is(err, notion.ErrNotFound):
+		return "Database not found: check NOTION_DATABASE_ID and that the database is shared with the integration"
+	case errors.Is(err, notion.ErrForbidden):
+		return "Access denied: share the database with the integration"
+	case errors.Is(err, notion.ErrRateLimited):
+		return "Rate limited: Notion is throttling requests, try again shortly"
+	}
+	var apiErr *notion.APIError
+	if errors.As(err, &apiErr) {
+		return "Notion API error"
+	}
+
+	var urlErr *url.Error
+	var netErr net.Error
+	if errors.As(err, &urlErr) || errors.As(err, &netErr) {
+		return "Network error: cannot reach the Notion API (check NOTION_BASE_URL and connectivity)"
+	}
+	return "Unexpected error"
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDoctorCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr bool
+		want    string
+	}{
+		{
+			name:   "Reachable",
+			status: http.StatusOK,
+			body:   `{"object":"list","results":[],"has_more":false}`,
+			want:   "OK",
+		},
+		{
+			name:    "Unauthorized",
+			status:  http.StatusUnauthorized,
+			body:    `{"object":"error","status":401,"code":"unauthorized","message":"API token is invalid."}`,
+			wantErr: true,
+			want:    "Authentication failed",
+		},
+		{
+			name:    "Database not found",
+			status:  http.StatusNotFound,
+			body:    `{"object":"error","status":404,"code":"object_not_found","message":"Could not find database."}`,
+			wantErr: true,
+			want:    "Database not found",
+		},
+		{
+			name:    "Forbidden",
+			status:  http.StatusForbidden,
+			body:    `{"object":"error","status":403,"code":"restricted_resource","message":"Insufficient permissions."}`,
+			wantErr: true,
+			want:    "Access denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/databases/test-db-id/query" {
+					t.Errorf("request = %s %s, want POST /databases/test-db-id/query", r.Method, r.URL.Path)
+				}
+				var req struct {
+					PageSize int `json:"page_size"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PageSize != 1 {
+					t.Errorf("page_size = %d (%v), want 1", req.PageSize, err)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			t.Setenv("NOTION_API_KEY", "test-api-key")
+			t.Setenv("NOTION_DATABASE_ID", "test-db-id")
+			t.Setenv("NOTION_BASE_URL", srv.URL)
+
+			cmd := doctorCmd()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&bytes.Buffer{})
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("doctor error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output = %q, want it to contain %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	t.Run("Network error", func(t *testing.T) {
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+
+		t.Setenv("NOTION_API_KEY", "test-api-key")
+		t.Setenv("NOTION_DATABASE_ID", "test-db-id")
+		t.Setenv("NOTION_BASE_URL", srv.URL)
+
+		cmd := doctorCmd()
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		if err := cmd.Execute(); err == nil {
+			t.Fatal("doctor should fail when Notion is unreachable")
+		}
+		if !strings.Contains(out.String(), "Network error") {
+			t.Errorf("output = %q, want a network error", out.String())
+		}
+	})
+}
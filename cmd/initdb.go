@@ -0,0 +1,62 @@
+// Package cmd provides CLI commands for the Notion MCP server.
+package cmd
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+	"github.com/nixihz/notion-as-mcp/internal/server"
+)
+
+// initDBCmd returns the init-db command.
+func initDBCmd() *cobra.Command {
+	var title string
+	cmd := &cobra.Command{
+		Use:   "init-db <parent-page-id>",
+		Short: "Create a Notion database with the properties the server expects",
+		Long: `Create a database under a Notion page, with a Name title, a Description
+text property and a select property named NOTION_TYPE_FIELD (default Type)
+offering the TYPE_PROMPT, TYPE_RESOURCE and TYPE_TOOL values. Share the page
+with the integration first. Prints the new database ID for
+NOTION_DATABASE_ID; only NOTION_API_KEY needs to be configured.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadSetup(configFile)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			client, err := server.NewNotionClient(cfg)
+			if err != nil {
+				return err
+			}
+
+			id, err := client.CreateDatabase(cmd.Context(), args[0], title,
+				cmp.Or(cfg.TypePrompt, "prompt"),
+				cmp.Or(cfg.TypeResource, "resource"),
+				cmp.Or(cfg.TypeTool, "tool"),
+			)
+			if err != nil {
+				cmd.SilenceUsage = true
+				switch {
+				case errors.Is(err, notion.ErrUnauthorized):
+					return fmt.Errorf("create database: %w (check NOTION_API_KEY)", err)
+				case errors.Is(err, notion.ErrNotFound), errors.Is(err, notion.ErrForbidden):
+					return fmt.Errorf("create database: %w (share page %s with the integration via its Connections menu)", err, args[0])
+				}
+				return fmt.Errorf("create database: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			fmt.Fprintf(out, "Created database %q\n", title)
+			fmt.Fprintf(out, "NOTION_DATABASE_ID=%s\n", id)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&title, "title", "MCP", "title of the new database")
+	return cmd
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInitDBCmd(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		wantErr string
+	}{
+		{name: "Created", status: http.StatusOK, body: `{"object":"database","id":"new-db-id"}`},
+		{
+			name:    "Parent not shared",
+			status:  http.StatusNotFound,
+			body:    `{"object":"error","status":404,"code":"object_not_found","message":"Could not find page."}`,
+			wantErr: "share page parent-page with the integration",
+		},
+		{
+			name:    "Bad API key",
+			status:  http.StatusUnauthorized,
+			body:    `{"object":"error","status":401,"code":"unauthorized","message":"API token is invalid."}`,
+			wantErr: "check NOTION_API_KEY",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got map[string]any
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/databases" {
+					t.Errorf("request = %s %s, want POST /databases", r.Method, r.URL.Path)
+				}
+				if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+					t.Errorf("decode request body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			// No NOTION_DATABASE_ID: the database doesn't exist yet
+			t.Setenv("NOTION_API_KEY", "test-api-key")
+			t.Setenv("NOTION_DATABASE_ID", "")
+			t.Setenv("NOTION_BASE_URL", srv.URL)
+			t.Setenv("TYPE_TOOL", "script")
+
+			cmd := initDBCmd()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			cmd.SetErr(&bytes.Buffer{})
+			cmd.SetArgs([]string{"parent-page", "--title", "Prompts"})
+			err := cmd.Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("init-db error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("init-db failed: %v", err)
+			}
+			if !strings.Contains(out.String(), "NOTION_DATABASE_ID=new-db-id") {
+				t.Errorf("output = %q, want the new database ID", out.String())
+			}
+
+			var want map[string]any
+			json.Unmarshal([]byte(`{
+				"parent": {"type": "page_id", "page_id": "parent-page"},
+				"title": [{"type": "text", "text": {"content": "Prompts"}}],
+				"properties": {
+					"Name": {"title": {}},
+					"Description": {"rich_text": {}},
+					"Type": {"select": {"options": [{"name": "prompt"}, {"name": "resource"}, {"name": "script"}]}}
+				}
+			}`), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("request body = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Package cmd provides CLI commands for the Notion MCP server.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+	"github.com/nixihz/notion-as-mcp/internal/server"
+)
+
+// pageLister queries the pages of the configured database.
+type pageLister interface {
+	GetAllPages(ctx context.Context) ([]notion.Page, error)
+}
+
+// listKinds maps the list command's argument to the page type it selects.
+var listKinds = map[string]string{
+	"prompts":   "prompt",
+	"resources": "resource",
+	"tools":     "tool",
+	"all":       "",
+}
+
+// listCmd returns the list command.
+func listCmd() *cobra.Command {
+	return newListCmd(func(cfg *config.Config) (pageLister, error) {
+		return server.NewNotionClient(cfg)
+	})
+}
+
+// newListCmd returns the list command using newLister to reach Notion.
+func newListCmd(newLister func(*config.Config) (pageLister, error)) *cobra.Command {
+	var asJSON bool
+
+	cmd := &cobra.Command{
+		Use:   "list [prompts|resources|tools|all]",
+		Short: "List the prompts, resources and tools the server exposes",
+		Long: `Query the Notion database and print the pages the server would expose,
+classified by the type field, with their MCP name, page ID and description.`,
+		ValidArgs: []string{"prompts", "resources", "tools", "all"},
+		Args:      cobra.MatchAll(cobra.MaximumNArgs(1), cobra.OnlyValidArgs),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			kind := "all"
+			if len(args) > 0 {
+				kind = args[0]
+			}
+
+			cfg, err := config.LoadFile(configFile)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			lister, err := newLister(cfg)
+			if err != nil {
+				return err
+			}
+			pages, err := lister.GetAllPages(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("query pages: %w", err)
+			}
+
+			var entries []server.Entry
+			for _, entry := range server.ListEntries(pages, cfg) {
+				if listKinds[kind] == "" || entry.Type == listKinds[kind] {
+					entries = append(entries, entry)
+				}
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(cmd.OutOrStdout())
+				enc.SetIndent("", "  ")
+				if entries == nil {
+					entries = []server.Entry{}
+				}
+				return enc.Encode(entries)
+			}
+			return writeEntries(cmd.OutOrStdout(), entries)
+		},
+	}
+
+	cmd.Flags().BoolVar(&asJSON, "json", false, "Print JSON instead of a table")
+
+	return cmd
+}
+
+// writeEntries writes entries as a table.
+func writeEntries(w io.Writer, entries []server.Entry) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TYPE\tTITLE\tNAME\tPAGE ID\tDESCRIPTION")
+	for _, e := range entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Type, e.Title, e.Name, e.PageID, e.Description)
+	}
+	return tw.Flush()
+}
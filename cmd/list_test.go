@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+	"github.com/nixihz/notion-as-mcp/internal/server"
+)
+
+// fakeLister returns a fixed set of pages.
+type fakeLister []notion.Page
+
+func (f fakeLister) GetAllPages(ctx context.Context) ([]notion.Page, error) {
+	return f, nil
+}
+
+// testPage builds a page with the given type, title and description.
+func testPage(id, pageType, title, description string) notion.Page {
+	return notion.Page{
+		ID: id,
+		Properties: map[string]notion.Property{
+			"Type":        {Type: notion.PropertyTypeSelect, Select: &notion.Select{Name: pageType}},
+			"Name":        {Type: notion.PropertyTypeTitle, Title: []notion.Title{{PlainText: title}}},
+			"Description": {Type: notion.PropertyTypeRichText, RichText: []notion.RichText{{PlainText: description}}},
+		},
+	}
+}
+
+func TestListCmd(t *testing.T) {
+	t.Setenv("NOTION_API_KEY", "test-api-key")
+	t.Setenv("NOTION_DATABASE_ID", "test-db-id")
+	t.Setenv("NOTION_TYPE_FIELD", "Type")
+
+	pages := fakeLister{
+		testPage("t1", "tool", "Word Count", "Counts words"),
+		testPage("p1", "prompt", "Code Review", "Reviews code"),
+		testPage("r1", "resource", "Style Guide", "House style"),
+		testPage("p2", "prompt", "Summarize", "Summarizes text"),
+		testPage("x1", "draft", "Ignored", ""),
+	}
+
+	run := func(t *testing.T, args ...string) string {
+		t.Helper()
+		cmd := newListCmd(func(*config.Config) (pageLister, error) { return pages, nil })
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("list %v failed: %v", args, err)
+		}
+		return out.String()
+	}
+
+	t.Run("Table of all pages", func(t *testing.T) {
+		out := run(t)
+		lines := strings.Split(strings.TrimSpace(out), "\n")
+		if len(lines) != 5 {
+			t.Fatalf("got %d lines, want header and 4 entries:\n%s", len(lines), out)
+		}
+		for i, want := range []string{"TYPE", "prompt", "prompt", "resource", "tool"} {
+			if got := strings.Fields(lines[i])[0]; got != want {
+				t.Errorf("line %d type = %q, want %q", i, got, want)
+			}
+		}
+		if !strings.Contains(out, "code_review") || !strings.Contains(out, "Reviews code") {
+			t.Errorf("output missing sanitized name or description:\n%s", out)
+		}
+		if strings.Contains(out, "Ignored") {
+			t.Errorf("output includes a page of an unknown type:\n%s", out)
+		}
+	})
+
+	t.Run("JSON filtered by kind", func(t *testing.T) {
+		var entries []server.Entry
+		if err := json.Unmarshal([]byte(run(t, "tools", "--json")), &entries); err != nil {
+			t.Fatalf("output is not JSON: %v", err)
+		}
+		want := server.Entry{Type: "tool", Title: "Word Count", Name: "word_count", PageID: "t1", Description: "Counts words"}
+		if len(entries) != 1 || entries[0] != want {
+			t.Errorf("entries = %+v, want [%+v]", entries, want)
+		}
+	})
+
+	t.Run("Empty JSON is an array", func(t *testing.T) {
+		cmd := newListCmd(func(*config.Config) (pageLister, error) { return pages[4:], nil })
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"--json"})
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("list failed: %v", err)
+		}
+		if got := strings.TrimSpace(out.String()); got != "[]" {
+			t.Errorf("output = %q, want []", got)
+		}
+	})
+
+	t.Run("Invalid kind", func(t *testing.T) {
+		cmd := newListCmd(func(*config.Config) (pageLister, error) { return pages, nil })
+		cmd.SetOut(&bytes.Buffer{})
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs([]string{"widgets"})
+		if err := cmd.Execute(); err == nil {
+			t.Error("list widgets should return error")
+		}
+	})
+}
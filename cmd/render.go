@@ -0,0 +1,80 @@
+// Package cmd provides CLI commands for the Notion MCP server.
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+	"github.com/nixihz/notion-as-mcp/internal/server"
+)
+
+// pageFetcher fetches the content of a page.
+type pageFetcher interface {
+	GetPageContent(ctx context.Context, pageID string) (*notion.PageContent, error)
+}
+
+// renderCmd returns the render command.
+func renderCmd() *cobra.Command {
+	return newRenderCmd(func(cfg *config.Config) (pageFetcher, error) {
+		return server.NewNotionClient(cfg)
+	})
+}
+
+// newRenderCmd returns the render command using newFetcher to reach Notion.
+func newRenderCmd(newFetcher func(*config.Config) (pageFetcher, error)) *cobra.Command {
+	var format string
+
+	cmd := &cobra.Command{
+		Use:   "render <page-id>",
+		Short: "Render a single Notion page to stdout",
+		Long: `Fetch a page's content and print it as the server would convert it,
+without starting the MCP server. Useful for reproducing rendering bugs.
+
+Formats: markdown (default), text (the extracted plain text) and json (the
+fetched blocks).`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			switch format {
+			case "markdown", "text", "json":
+			default:
+				return fmt.Errorf("unknown format %q: use markdown, text or json", format)
+			}
+
+			cfg, err := config.LoadFile(configFile)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+
+			fetcher, err := newFetcher(cfg)
+			if err != nil {
+				return err
+			}
+			content, err := fetcher.GetPageContent(cmd.Context(), args[0])
+			if err != nil {
+				return fmt.Errorf("fetch page: %w", err)
+			}
+
+			out := cmd.OutOrStdout()
+			switch format {
+			case "text":
+				fmt.Fprintln(out, content.Text)
+			case "json":
+				enc := json.NewEncoder(out)
+				enc.SetIndent("", "  ")
+				return enc.Encode(content)
+			default:
+				fmt.Fprintln(out, notion.PageToMarkdown(content))
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&format, "format", "markdown", "Output format: markdown, text or json")
+
+	return cmd
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+)
+
+// fakeFetcher serves fixed page contents by page ID.
+type fakeFetcher map[string]*notion.PageContent
+
+func (f fakeFetcher) GetPageContent(ctx context.Context, pageID string) (*notion.PageContent, error) {
+	content, ok := f[pageID]
+	if !ok {
+		return nil, fmt.Errorf("page %s not found", pageID)
+	}
+	return content, nil
+}
+
+func TestRenderCmd(t *testing.T) {
+	t.Setenv("NOTION_API_KEY", "test-api-key")
+	t.Setenv("NOTION_DATABASE_ID", "test-db-id")
+
+	text := func(s string) []notion.RichText {
+		return []notion.RichText{{Type: "text", Text: notion.Text{Content: s}, PlainText: s}}
+	}
+	pages := fakeFetcher{
+		"page-1": {
+			Page: notion.Page{ID: "page-1"},
+			Blocks: []notion.Block{
+				{Type: notion.BlockTypeHeading1, Content: map[string]any{"rich_text": []any{map[string]any{"plain_text": "Style Guide"}}}},
+				{Type: notion.BlockTypeParagraph, Paragraph: &notion.Paragraph{RichText: text("Use the active voice.")}},
+			},
+			Text: "Style Guide\nUse the active voice.",
+		},
+	}
+
+	run := func(t *testing.T, args ...string) (string, error) {
+		t.Helper()
+		cmd := newRenderCmd(func(*config.Config) (pageFetcher, error) { return pages, nil })
+		var out bytes.Buffer
+		cmd.SetOut(&out)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetArgs(args)
+		err := cmd.Execute()
+		return out.String(), err
+	}
+
+	t.Run("Markdown", func(t *testing.T) {
+		out, err := run(t, "page-1")
+		if err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		if want := "# Style Guide\n\nUse the active voice."; !strings.Contains(out, want) {
+			t.Errorf("output = %q, want it to contain %q", out, want)
+		}
+	})
+
+	t.Run("Text", func(t *testing.T) {
+		out, err := run(t, "page-1", "--format", "text")
+		if err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		if got, want := strings.TrimSpace(out), "Style Guide\nUse the active voice."; got != want {
+			t.Errorf("output = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		out, err := run(t, "page-1", "--format", "json")
+		if err != nil {
+			t.Fatalf("render failed: %v", err)
+		}
+		var content notion.PageContent
+		if err := json.Unmarshal([]byte(out), &content); err != nil {
+			t.Fatalf("output is not JSON: %v", err)
+		}
+		if content.Page.ID != "page-1" || len(content.Blocks) != 2 {
+			t.Fatalf("content = %+v, want page-1 with 2 blocks", content)
+		}
+		if got := notion.PageToMarkdown(&content); !strings.Contains(got, "# Style Guide") {
+			t.Errorf("decoded blocks render as %q, want the heading kept", got)
+		}
+	})
+
+	t.Run("Unknown format", func(t *testing.T) {
+		if _, err := run(t, "page-1", "--format", "html"); err == nil {
+			t.Error("render --format html should return error")
+		}
+	})
+
+	t.Run("Missing page", func(t *testing.T) {
+		if _, err := run(t, "page-2"); err == nil {
+			t.Error("render of a missing page should return error")
+		}
+	})
+}
@@ -2,11 +2,21 @@
 package cmd
 
 import (
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+	"github.com/nixihz/notion-as-mcp/internal/server"
 )
 
+// configFile is the config file named by the --config flag.
+var configFile string
+
+// noEnvFile is set by the --no-env-file flag.
+var noEnvFile bool
+
 // Root returns the root command.
 func Root() *cobra.Command {
 	cmd := &cobra.Command{
@@ -16,11 +26,24 @@ func Root() *cobra.Command {
 access to Notion databases, exposing prompts, resources, and tools based on
 type fields in your Notion database.`,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if noEnvFile {
+				return os.Setenv(config.NoDotEnvEnv, "true")
+			}
 			return nil // Skip config loading for version/help commands
 		},
 	}
 
+	cmd.PersistentFlags().StringVar(&configFile, "config", "", "YAML or JSON config file (default: $"+config.ConfigFileEnv+")")
+	cmd.PersistentFlags().BoolVar(&noEnvFile, "no-env-file", false, "Ignore the .env file; only the environment is read (or set $"+config.NoDotEnvEnv+")")
+
 	cmd.AddCommand(serveCmd())
+	cmd.AddCommand(configCmd())
+	cmd.AddCommand(validateCmd())
+	cmd.AddCommand(listCmd())
+	cmd.AddCommand(doctorCmd())
+	cmd.AddCommand(renderCmd())
+	cmd.AddCommand(cacheCmd())
+	cmd.AddCommand(initDBCmd())
 	cmd.AddCommand(versionCmd())
 	cmd.AddCommand(completionCmd())
 
@@ -33,7 +56,20 @@ func versionCmd() *cobra.Command {
 		Use:   "version",
 		Short: "Show version information",
 		Run: func(cmd *cobra.Command, args []string) {
-			cmd.Println("Notion MCP Server v1.0.0")
+			version := "Notion MCP Server " + server.Version
+			commit, date := server.BuildCommit()
+			if len(commit) > 12 {
+				commit = commit[:12]
+			}
+			switch {
+			case commit != "" && date != "":
+				version += fmt.Sprintf(" (commit %s, built %s)", commit, date)
+			case commit != "":
+				version += fmt.Sprintf(" (commit %s)", commit)
+			case date != "":
+				version += fmt.Sprintf(" (built %s)", date)
+			}
+			cmd.Println(version)
 		},
 	}
 }
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/nixihz/notion-as-mcp/internal/server"
+)
+
+func TestVersionCmd(t *testing.T) {
+	defer func(v, c, d string) { server.Version, server.Commit, server.Date = v, c, d }(server.Version, server.Commit, server.Date)
+
+	tests := []struct {
+		name                  string
+		version, commit, date string
+		want                  string
+	}{
+		{"Version only", "v1.2.3", "", "", "Notion MCP Server v1.2.3"},
+		{"Commit and date", "v1.2.3", "0123456789abcdef", "2026-01-02T03:04:05Z",
+			"Notion MCP Server v1.2.3 (commit 0123456789ab, built 2026-01-02T03:04:05Z)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server.Version, server.Commit, server.Date = tt.version, tt.commit, tt.date
+
+			cmd := versionCmd()
+			var out bytes.Buffer
+			cmd.SetOut(&out)
+			if err := cmd.Execute(); err != nil {
+				t.Fatalf("version failed: %v", err)
+			}
+			if got := strings.TrimSpace(out.String()); got != tt.want {
+				t.Errorf("version = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 
 	"github.com/spf13/cobra"
@@ -19,11 +20,7 @@ import (
 
 // serveCmd returns the serve command.
 func serveCmd() *cobra.Command {
-	var (
-		host      string
-		port      int
-		transport string
-	)
+	var flags serverFlags
 
 	cmd := &cobra.Command{
 		Use:   "serve",
@@ -32,10 +29,13 @@ func serveCmd() *cobra.Command {
 
 The server will listen for MCP protocol messages over stdio or streamable HTTP
 and communicate with Notion to provide prompts, resources,
-and tools based on your Notion database.`,
+and tools based on your Notion database.
+
+With --watch the server polls Notion every POLL_INTERVAL and adds, updates
+or removes prompts and resources as pages change.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			// Load and validate configuration
-			cfg, err := config.Load()
+			// Load and validate configuration; CLI flags take precedence
+			cfg, err := config.LoadFile(configFile, flags.layer())
 			if err != nil {
 				return fmt.Errorf("load config: %w", err)
 			}
@@ -43,17 +43,6 @@ and tools based on your Notion database.`,
 				return fmt.Errorf("validate config: %w", err)
 			}
 
-			// Override config with CLI flags if provided
-			if host != "" {
-				cfg.ServerHost = host
-			}
-			if port != 0 {
-				cfg.ServerPort = port
-			}
-			if transport != "" {
-				cfg.TransportType = transport
-			}
-
 			// Create server (initializes logger internally)
 			srv, err := server.NewServer(cfg)
 			if err != nil {
@@ -94,9 +83,42 @@ and tools based on your Notion database.`,
 	}
 
 	// Add flags
-	cmd.Flags().StringVar(&host, "host", "", "Server host address (default: 0.0.0.0)")
-	cmd.Flags().IntVarP(&port, "port", "p", 0, "Server port (default: 3100)")
-	cmd.Flags().StringVarP(&transport, "transport", "t", "", "Transport type: streamable or stdio (default: streamable)")
+	flags.register(cmd)
 
 	return cmd
 }
+
+// serverFlags holds the flags that override server configuration.
+type serverFlags struct {
+	host      string
+	port      int
+	transport string
+	watch     bool
+}
+
+// register adds the flags to cmd.
+func (f *serverFlags) register(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&f.host, "host", "", "Server host address (default: 0.0.0.0)")
+	cmd.Flags().IntVarP(&f.port, "port", "p", 0, "Server port (default: 3100)")
+	cmd.Flags().StringVarP(&f.transport, "transport", "t", "", "Transport type: streamable or stdio (default: streamable)")
+	cmd.Flags().BoolVarP(&f.watch, "watch", "w", false, "Re-register handlers when Notion pages change")
+}
+
+// layer returns the configuration layer for the flags.
+// Unset flags are left empty so lower layers apply.
+func (f *serverFlags) layer() config.Layer {
+	layer := config.Layer{
+		Source: config.SourceFlag,
+		Values: map[string]string{
+			"SERVER_HOST":    f.host,
+			"TRANSPORT_TYPE": f.transport,
+		},
+	}
+	if f.port != 0 {
+		layer.Values["SERVER_PORT"] = strconv.Itoa(f.port)
+	}
+	if f.watch {
+		layer.Values["WATCH"] = "true"
+	}
+	return layer
+}
@@ -0,0 +1,63 @@
+// Package cmd provides CLI commands for the Notion MCP server.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/nixihz/notion-as-mcp/internal/config"
+	"github.com/nixihz/notion-as-mcp/internal/server"
+)
+
+// validateCmd returns the validate command.
+func validateCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check tool code without executing it",
+		Long: `Check every tool page in the Notion database without running its code.
+
+For each tool the language must be allowed by EXEC_LANGUAGES, its runtime
+must be installed, and the code must pass a syntax check where the language
+has one (bash -n, python -m py_compile, node --check, go vet, ruby -c,
+php -l). Exits nonzero if any tool fails.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := config.LoadFile(configFile)
+			if err != nil {
+				return fmt.Errorf("load config: %w", err)
+			}
+			if err := cfg.Validate(); err != nil {
+				return fmt.Errorf("validate config: %w", err)
+			}
+
+			srv, err := server.NewServer(cfg)
+			if err != nil {
+				return fmt.Errorf("create server: %w", err)
+			}
+			defer func() { _ = srv.Stop() }()
+
+			results, err := srv.ValidateTools(cmd.Context())
+			if err != nil {
+				return err
+			}
+
+			out := cmd.OutOrStdout()
+			invalid := 0
+			for _, r := range results {
+				if r.Err != nil {
+					invalid++
+					fmt.Fprintf(out, "FAIL  %s (%s): %v\n", r.Name, r.Language, r.Err)
+					continue
+				}
+				fmt.Fprintf(out, "OK    %s (%s)\n", r.Name, r.Language)
+			}
+			fmt.Fprintf(out, "\n%d valid, %d invalid\n", len(results)-invalid, invalid)
+
+			if invalid > 0 {
+				cmd.SilenceUsage = true
+				return fmt.Errorf("%d of %d tools failed validation", invalid, len(results))
+			}
+			return nil
+		},
+	}
+}
@@ -3,6 +3,7 @@ package cache
 
 import (
 	"context"
+	"log/slog"
 	"time"
 )
 
@@ -18,10 +19,19 @@ type Cache interface {
 	Has(ctx context.Context, key string) (bool, error)
 	// Clear removes all cached values.
 	Clear(ctx context.Context) error
+	// Entries returns the unexpired entries, for snapshots.
+	Entries(ctx context.Context) ([]Entry, error)
 	// Close cleans up resources.
 	Close() error
 }
 
+// Entry is a cached value with its key and expiry time.
+type Entry struct {
+	Key       string    `json:"key"`
+	Value     []byte    `json:"value"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 // Stats holds cache statistics.
 type Stats struct {
 	Hits      int64 `json:"hits"`
@@ -47,9 +57,34 @@ func WithDir(dir string) CacheOption {
 	}
 }
 
+// WithCompression enables gzip compression of file cache entries.
+func WithCompression() CacheOption {
+	return func(o *cacheOptions) {
+		o.Compress = true
+	}
+}
+
+// WithSweepInterval makes the file cache delete expired entries from its
+// directory every interval, rather than only when they are read.
+func WithSweepInterval(interval time.Duration) CacheOption {
+	return func(o *cacheOptions) {
+		o.SweepInterval = interval
+	}
+}
+
+// WithLogger sets the logger a layered cache reports L2 failures to.
+func WithLogger(logger *slog.Logger) CacheOption {
+	return func(o *cacheOptions) {
+		o.Logger = logger
+	}
+}
+
 type cacheOptions struct {
-	DefaultTTL time.Duration
-	Directory  string
+	DefaultTTL    time.Duration
+	Directory     string
+	Compress      bool
+	SweepInterval time.Duration
+	Logger        *slog.Logger
 }
 
 // NewCache creates a new cache instance based on configuration.
@@ -68,11 +103,18 @@ func NewCache(opts ...CacheOption) (Cache, error) {
 		return nil, err
 	}
 
-	fileCache, err := NewFileCache(WithDir(o.Directory), WithTTL(1*time.Hour))
+	fileOpts := []CacheOption{WithDir(o.Directory), WithTTL(1 * time.Hour)}
+	if o.Compress {
+		fileOpts = append(fileOpts, WithCompression())
+	}
+	if o.SweepInterval > 0 {
+		fileOpts = append(fileOpts, WithSweepInterval(o.SweepInterval))
+	}
+	fileCache, err := NewFileCache(fileOpts...)
 	if err != nil {
 		// If file cache fails, just use memory cache
 		return memoryCache, nil
 	}
 
-	return NewLayeredCache(memoryCache, fileCache), nil
+	return NewLayeredCache(memoryCache, fileCache, WithLogger(o.Logger)), nil
 }
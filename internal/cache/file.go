@@ -2,26 +2,51 @@
 package cache
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/json"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 )
 
+// compressedHeader prefixes gzip-compressed cache files. Legacy plaintext
+// entries are JSON objects and always start with '{'.
+const compressedHeader byte = 0x01
+
 // fileCache implements a file-based cache.
 type fileCache struct {
 	dir        string
 	defaultTTL time.Duration
+	compress   bool
+
+	stop      chan struct{} // closed to stop the sweeper
+	done      chan struct{} // closed when the sweeper has stopped
+	closeOnce sync.Once
 }
 
 // NewFileCache creates a new file-based cache.
 func NewFileCache(opts ...CacheOption) (Cache, error) {
-	fc := &fileCache{
-		defaultTTL: 1 * time.Hour,
+	o := &cacheOptions{
+		DefaultTTL: 1 * time.Hour,
 	}
 	for _, opt := range opts {
-		opt(&cacheOptions{})
+		opt(o)
+	}
+
+	dir, err := ExpandHome(o.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	fc := &fileCache{
+		dir:        dir,
+		defaultTTL: o.DefaultTTL,
+		compress:   o.Compress,
 	}
 
 	// Create cache directory if it doesn't exist
@@ -29,11 +54,20 @@ func NewFileCache(opts ...CacheOption) (Cache, error) {
 		return nil, err
 	}
 
+	if o.SweepInterval > 0 {
+		fc.stop = make(chan struct{})
+		fc.done = make(chan struct{})
+		go fc.sweepEvery(o.SweepInterval)
+	}
+
 	return fc, nil
 }
 
 // Get retrieves a value from the cache.
 func (fc *fileCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	path := fc.cachePath(key)
 
 	data, err := os.ReadFile(path)
@@ -43,9 +77,12 @@ func (fc *fileCache) Get(ctx context.Context, key string) ([]byte, error) {
 		}
 		return nil, err
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 
-	var item fileCacheItem
-	if err := json.Unmarshal(data, &item); err != nil {
+	item, err := decodeFileCacheItem(data)
+	if err != nil {
 		return nil, err
 	}
 
@@ -60,9 +97,13 @@ func (fc *fileCache) Get(ctx context.Context, key string) ([]byte, error) {
 
 // Set stores a value in the cache.
 func (fc *fileCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	path := fc.cachePath(key)
 
 	item := fileCacheItem{
+		Key:       key,
 		Value:     value,
 		ExpiresAt: time.Now().Add(ttl),
 	}
@@ -71,12 +112,23 @@ func (fc *fileCache) Set(ctx context.Context, key string, value []byte, ttl time
 	if err != nil {
 		return err
 	}
+	if fc.compress {
+		if data, err = compressData(data); err != nil {
+			return err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	return os.WriteFile(path, data, 0644)
 }
 
 // Delete removes a value from the cache.
 func (fc *fileCache) Delete(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	path := fc.cachePath(key)
 	os.Remove(path)
 	return nil
@@ -84,6 +136,9 @@ func (fc *fileCache) Delete(ctx context.Context, key string) error {
 
 // Has returns true if the key exists and is not expired.
 func (fc *fileCache) Has(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 	path := fc.cachePath(key)
 
 	data, err := os.ReadFile(path)
@@ -93,9 +148,12 @@ func (fc *fileCache) Has(ctx context.Context, key string) (bool, error) {
 		}
 		return false, err
 	}
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
 
-	var item fileCacheItem
-	if err := json.Unmarshal(data, &item); err != nil {
+	item, err := decodeFileCacheItem(data)
+	if err != nil {
 		return false, err
 	}
 
@@ -109,6 +167,9 @@ func (fc *fileCache) Has(ctx context.Context, key string) (bool, error) {
 
 // Clear removes all cached values.
 func (fc *fileCache) Clear(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 	entries, err := os.ReadDir(fc.dir)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -118,6 +179,10 @@ func (fc *fileCache) Clear(ctx context.Context) error {
 	}
 
 	for _, entry := range entries {
+		// Stop early if the caller gave up; remaining entries stay on disk
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if !entry.IsDir() {
 			os.Remove(filepath.Join(fc.dir, entry.Name()))
 		}
@@ -126,20 +191,159 @@ func (fc *fileCache) Clear(ctx context.Context) error {
 	return nil
 }
 
-// Close cleans up resources.
+// Entries returns the unexpired entries in the cache directory. Files that
+// cannot be decoded, and legacy entries that don't record their key, are
+// skipped.
+func (fc *fileCache) Entries(ctx context.Context) ([]Entry, error) {
+	files, err := os.ReadDir(fc.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	var entries []Entry
+	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".cache") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(fc.dir, file.Name()))
+		if err != nil {
+			continue
+		}
+		item, err := decodeFileCacheItem(data)
+		if err != nil || item.Key == "" || now.After(item.ExpiresAt) {
+			continue
+		}
+		entries = append(entries, Entry{Key: item.Key, Value: item.Value, ExpiresAt: item.ExpiresAt})
+	}
+	return entries, nil
+}
+
+// Close stops the sweeper, if running.
 func (fc *fileCache) Close() error {
+	if fc.stop != nil {
+		fc.closeOnce.Do(func() { close(fc.stop) })
+		<-fc.done
+	}
 	return nil
 }
 
-// cachePath generates the file path for a cache key.
+// sweepEvery runs sweep every interval until the cache is closed.
+func (fc *fileCache) sweepEvery(interval time.Duration) {
+	defer close(fc.done)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-fc.stop:
+			return
+		case <-ticker.C:
+			fc.sweep()
+		}
+	}
+}
+
+// sweep deletes the expired entries in the cache directory. Files that
+// cannot be read or decoded are left alone.
+func (fc *fileCache) sweep() {
+	entries, err := os.ReadDir(fc.dir)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".cache") {
+			continue
+		}
+		path := filepath.Join(fc.dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		item, err := decodeFileCacheItem(data)
+		if err != nil {
+			continue
+		}
+		if now.After(item.ExpiresAt) {
+			os.Remove(path)
+		}
+	}
+}
+
+// maxKeyPrefix is the length of the readable key prefix in cache file names.
+const maxKeyPrefix = 40
+
+// cachePath generates the file path for a cache key: a readable prefix of
+// the key followed by its SHA-256 digest, so distinct keys never share a
+// file whatever characters they contain. Files named by the old scheme, the
+// key's base name, are simply never read again.
 func (fc *fileCache) cachePath(key string) string {
-	// Sanitize key for file system
-	safeKey := filepath.Base(key)
-	return filepath.Join(fc.dir, safeKey+".cache")
+	prefix := strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' {
+			return r
+		}
+		return '_'
+	}, key)
+	if len(prefix) > maxKeyPrefix {
+		prefix = prefix[:maxKeyPrefix]
+	}
+	return filepath.Join(fc.dir, prefix+"-"+HashContent([]byte(key))+".cache")
 }
 
-// fileCacheItem represents a cached item.
+// fileCacheItem represents a cached item. Entries written before keys were
+// hashed into file names have no Key.
 type fileCacheItem struct {
+	Key       string    `json:"key,omitempty"`
 	Value     []byte    `json:"value"`
 	ExpiresAt time.Time `json:"expires_at"`
 }
+
+// decodeFileCacheItem decodes a cache file, transparently decompressing
+// entries written with compression enabled.
+func decodeFileCacheItem(data []byte) (fileCacheItem, error) {
+	var item fileCacheItem
+	if len(data) > 0 && data[0] == compressedHeader {
+		zr, err := gzip.NewReader(bytes.NewReader(data[1:]))
+		if err != nil {
+			return item, err
+		}
+		defer zr.Close()
+		if data, err = io.ReadAll(zr); err != nil {
+			return item, err
+		}
+	}
+	err := json.Unmarshal(data, &item)
+	return item, err
+}
+
+// compressData gzip-compresses data and prepends the compressed header byte.
+func compressData(data []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(compressedHeader)
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExpandHome expands a leading "~" in path to the user's home directory.
+func ExpandHome(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
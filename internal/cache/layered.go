@@ -3,20 +3,34 @@ package cache
 
 import (
 	"context"
+	"log/slog"
+	"slices"
+	"strings"
 	"time"
 )
 
 // layeredCache implements a two-layer cache (L1: memory, L2: file).
 type layeredCache struct {
-	l1 Cache // memory cache
-	l2 Cache // file cache
+	l1     Cache // memory cache
+	l2     Cache // file cache
+	logger *slog.Logger
 }
 
-// NewLayeredCache creates a new layered cache.
-func NewLayeredCache(l1, l2 Cache) Cache {
+// NewLayeredCache creates a new layered cache. L2 failures never fail an
+// operation; pass WithLogger to have them logged.
+func NewLayeredCache(l1, l2 Cache, opts ...CacheOption) Cache {
+	o := &cacheOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	logger := o.Logger
+	if logger == nil {
+		logger = slog.New(slog.DiscardHandler)
+	}
 	return &layeredCache{
-		l1: l1,
-		l2: l2,
+		l1:     l1,
+		l2:     l2,
+		logger: logger,
 	}
 }
 
@@ -52,7 +66,7 @@ func (lc *layeredCache) Set(ctx context.Context, key string, value []byte, ttl t
 	}
 	if err := lc.l2.Set(ctx, key, value, ttl); err != nil {
 		// Log warning but don't fail - L2 is optional
-		_ = err
+		lc.logger.Warn("failed to set L2 cache", slog.String("key", key), slog.String("error", err.Error()))
 	}
 	return nil
 }
@@ -60,7 +74,9 @@ func (lc *layeredCache) Set(ctx context.Context, key string, value []byte, ttl t
 // Delete removes a value from both layers.
 func (lc *layeredCache) Delete(ctx context.Context, key string) error {
 	lc.l1.Delete(ctx, key)
-	lc.l2.Delete(ctx, key)
+	if err := lc.l2.Delete(ctx, key); err != nil {
+		lc.logger.Warn("failed to delete from L2 cache", slog.String("key", key), slog.String("error", err.Error()))
+	}
 	return nil
 }
 
@@ -76,10 +92,36 @@ func (lc *layeredCache) Has(ctx context.Context, key string) (bool, error) {
 // Clear removes all values from both layers.
 func (lc *layeredCache) Clear(ctx context.Context) error {
 	lc.l1.Clear(ctx)
-	lc.l2.Clear(ctx)
+	if err := lc.l2.Clear(ctx); err != nil {
+		lc.logger.Warn("failed to clear L2 cache", slog.String("error", err.Error()))
+	}
 	return nil
 }
 
+// Entries returns the unexpired entries of both layers, preferring L1's
+// copy of a key held by both.
+func (lc *layeredCache) Entries(ctx context.Context) ([]Entry, error) {
+	l2, err := lc.l2.Entries(ctx)
+	if err != nil {
+		lc.logger.Warn("failed to list L2 cache", slog.String("error", err.Error()))
+	}
+	l1, err := lc.l1.Entries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byKey := make(map[string]Entry, len(l1)+len(l2))
+	for _, entry := range append(l2, l1...) {
+		byKey[entry.Key] = entry
+	}
+	entries := make([]Entry, 0, len(byKey))
+	for _, entry := range byKey {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(a.Key, b.Key) })
+	return entries, nil
+}
+
 // Close cleans up resources for both layers.
 func (lc *layeredCache) Close() error {
 	lc.l1.Close()
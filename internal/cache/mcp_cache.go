@@ -6,6 +6,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"log/slog"
+	"sort"
 	"sync"
 	"time"
 )
@@ -14,6 +15,14 @@ import (
 const (
 	CacheKeyResources = "mcp:resources"
 	CacheKeyPrompts   = "mcp:prompts"
+	CacheKeyTools     = "mcp:tools"
+	// CacheKeyRenderPrefix prefixes the page ID for rendered page markdown
+	CacheKeyRenderPrefix = "mcp:render:"
+	// CacheKeyBlobPrefix prefixes the page ID for a resource page's attachment
+	CacheKeyBlobPrefix = "mcp:blob:"
+	// CacheKeyToolPrefix prefixes the hash of a cacheable tool's code and
+	// input for its execution result
+	CacheKeyToolPrefix = "mcp:tool:"
 )
 
 // Fetcher is a function that fetches data to be cached.
@@ -21,10 +30,36 @@ type Fetcher func(ctx context.Context) ([]byte, error)
 
 // MCPCache manages cached MCP resources and prompts.
 type MCPCache struct {
-	cache     Cache
-	logger    *slog.Logger
-	mu        sync.RWMutex
-	stopChans map[string]chan struct{}
+	cache        Cache
+	logger       *slog.Logger
+	mu           sync.RWMutex
+	stopChans    map[string]chan struct{}
+	refreshes    map[string]*refreshState
+	watchdogStop chan struct{}
+	now          func() time.Time
+}
+
+// refreshState tracks a periodic refresh loop so it can be health-checked
+// and restarted. An attempt is recorded whether or not the fetch succeeded,
+// so a loop that keeps failing is unhealthy but not stalled.
+type refreshState struct {
+	ctx         context.Context
+	interval    time.Duration
+	fetcher     Fetcher
+	lastAttempt time.Time
+	lastSuccess time.Time
+}
+
+// RefreshHealth reports the heartbeat of a periodic refresh loop. Healthy
+// means a refresh succeeded within twice the interval; Stalled means the
+// loop has not even tried within twice the interval.
+type RefreshHealth struct {
+	Key         string        `json:"key"`
+	Interval    time.Duration `json:"interval"`
+	LastAttempt time.Time     `json:"last_attempt"`
+	LastRefresh time.Time     `json:"last_refresh"`
+	Healthy     bool          `json:"healthy"`
+	Stalled     bool          `json:"stalled"`
 }
 
 // NewMCPCache creates a new MCP cache manager.
@@ -33,6 +68,8 @@ func NewMCPCache(cache Cache, logger *slog.Logger) *MCPCache {
 		cache:     cache,
 		logger:    logger,
 		stopChans: make(map[string]chan struct{}),
+		refreshes: make(map[string]*refreshState),
+		now:       time.Now,
 	}
 }
 
@@ -70,6 +107,15 @@ func (m *MCPCache) StartPeriodicRefresh(ctx context.Context, key string, interva
 
 	stopChan := make(chan struct{})
 	m.stopChans[key] = stopChan
+	// A new loop starts with a fresh heartbeat
+	now := m.now()
+	m.refreshes[key] = &refreshState{
+		ctx:         ctx,
+		interval:    interval,
+		fetcher:     fetcher,
+		lastAttempt: now,
+		lastSuccess: now,
+	}
 
 	go func() {
 		ticker := time.NewTicker(interval)
@@ -97,6 +143,7 @@ func (m *MCPCache) refreshOnce(ctx context.Context, key string, fetcher Fetcher)
 	m.logger.Debug("refreshing cache", slog.String("key", key))
 
 	newData, err := fetcher(ctx)
+	m.recordAttempt(key)
 	if err != nil {
 		m.logger.Warn("failed to refresh cache", slog.String("key", key), slog.String("error", err.Error()))
 		return
@@ -110,6 +157,7 @@ func (m *MCPCache) refreshOnce(ctx context.Context, key string, fetcher Fetcher)
 			m.logger.Warn("failed to set cache", slog.String("key", key), slog.String("error", err.Error()))
 			return
 		}
+		m.recordRefresh(key)
 		m.logger.Info("cache updated (was empty)", slog.String("key", key))
 		return
 	}
@@ -119,6 +167,7 @@ func (m *MCPCache) refreshOnce(ctx context.Context, key string, fetcher Fetcher)
 	existingHash := HashContent(existingData)
 
 	if newHash == existingHash {
+		m.recordRefresh(key)
 		m.logger.Debug("cache unchanged, skipping update", slog.String("key", key))
 		return
 	}
@@ -129,9 +178,115 @@ func (m *MCPCache) refreshOnce(ctx context.Context, key string, fetcher Fetcher)
 		return
 	}
 
+	m.recordRefresh(key)
 	m.logger.Info("cache updated", slog.String("key", key))
 }
 
+// recordAttempt records that the loop for key tried to refresh.
+func (m *MCPCache) recordAttempt(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.refreshes[key]; ok {
+		state.lastAttempt = m.now()
+	}
+}
+
+// recordRefresh records a successful refresh as the heartbeat for key.
+func (m *MCPCache) recordRefresh(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.refreshes[key]; ok {
+		state.lastSuccess = m.now()
+	}
+}
+
+// RefreshHealth reports the heartbeat of every running refresh loop, sorted
+// by key.
+func (m *MCPCache) RefreshHealth() []RefreshHealth {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := m.now()
+	health := make([]RefreshHealth, 0, len(m.refreshes))
+	for key, state := range m.refreshes {
+		health = append(health, RefreshHealth{
+			Key:         key,
+			Interval:    state.interval,
+			LastAttempt: state.lastAttempt,
+			LastRefresh: state.lastSuccess,
+			Healthy:     now.Sub(state.lastSuccess) <= 2*state.interval,
+			Stalled:     now.Sub(state.lastAttempt) > 2*state.interval,
+		})
+	}
+	sort.Slice(health, func(i, j int) bool { return health[i].Key < health[j].Key })
+	return health
+}
+
+// StartWatchdog starts a background goroutine that checks refresh loops every
+// interval and logs those that have stalled. If restart is true, stalled
+// loops are restarted. A loop whose refreshes fail, e.g. while Notion is
+// down, is still running and is left alone.
+func (m *MCPCache) StartWatchdog(ctx context.Context, interval time.Duration, restart bool) {
+	if interval <= 0 {
+		return
+	}
+
+	m.mu.Lock()
+	if m.watchdogStop != nil {
+		close(m.watchdogStop)
+	}
+	stopChan := make(chan struct{})
+	m.watchdogStop = stopChan
+	m.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stopChan:
+				return
+			case <-ticker.C:
+				m.checkRefreshes(restart)
+			}
+		}
+	}()
+}
+
+// checkRefreshes logs stalled refresh loops, restarting them if requested,
+// and returns their keys.
+func (m *MCPCache) checkRefreshes(restart bool) []string {
+	var stalled []string
+	for _, h := range m.RefreshHealth() {
+		if !h.Stalled {
+			continue
+		}
+		stalled = append(stalled, h.Key)
+		m.logger.Warn("periodic refresh stalled",
+			slog.String("key", h.Key),
+			slog.Time("last_attempt", h.LastAttempt),
+			slog.String("interval", h.Interval.String()),
+		)
+		if !restart {
+			continue
+		}
+
+		m.mu.RLock()
+		state, ok := m.refreshes[h.Key]
+		m.mu.RUnlock()
+		if ok {
+			m.logger.Info("restarting periodic refresh", slog.String("key", h.Key))
+			m.StartPeriodicRefresh(state.ctx, h.Key, state.interval, state.fetcher)
+		}
+	}
+	return stalled
+}
+
 // Get retrieves cached data, returns nil if not found.
 func (m *MCPCache) Get(ctx context.Context, key string) ([]byte, error) {
 	return m.cache.Get(ctx, key)
@@ -151,9 +306,10 @@ func (m *MCPCache) StopPeriodicRefresh(key string) {
 		close(stopChan)
 		delete(m.stopChans, key)
 	}
+	delete(m.refreshes, key)
 }
 
-// StopAll stops all periodic refreshes.
+// StopAll stops all periodic refreshes and the watchdog.
 func (m *MCPCache) StopAll() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -161,6 +317,11 @@ func (m *MCPCache) StopAll() {
 	for key, stopChan := range m.stopChans {
 		close(stopChan)
 		delete(m.stopChans, key)
+		delete(m.refreshes, key)
+	}
+	if m.watchdogStop != nil {
+		close(m.watchdogStop)
+		m.watchdogStop = nil
 	}
 }
 
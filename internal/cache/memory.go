@@ -126,6 +126,22 @@ func (m *memoryCache) Clear(ctx context.Context) error {
 	return nil
 }
 
+// Entries returns the unexpired entries.
+func (m *memoryCache) Entries(ctx context.Context) ([]Entry, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	entries := make([]Entry, 0, len(m.items))
+	for key, item := range m.items {
+		if now.After(item.ExpiresAt) {
+			continue
+		}
+		entries = append(entries, Entry{Key: key, Value: item.Value, ExpiresAt: item.ExpiresAt})
+	}
+	return entries, nil
+}
+
 // Close cleans up resources.
 func (m *memoryCache) Close() error {
 	return m.Clear(context.Background())
@@ -0,0 +1,84 @@
+// Package cache provides caching functionality for the Notion MCP server.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// snapshotVersion is the version of the snapshot file format.
+const snapshotVersion = 1
+
+// snapshot is the on-disk form of a cache snapshot.
+type snapshot struct {
+	Version int       `json:"version"`
+	Created time.Time `json:"created"`
+	Entries []Entry   `json:"entries"`
+}
+
+// ExportSnapshot writes the unexpired entries of c to the file at path,
+// returning how many were written. The file is replaced atomically.
+func ExportSnapshot(ctx context.Context, c Cache, path string) (int, error) {
+	entries, err := c.Entries(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("list cache entries: %w", err)
+	}
+	if entries == nil {
+		entries = []Entry{}
+	}
+	data, err := json.Marshal(snapshot{Version: snapshotVersion, Created: time.Now().UTC(), Entries: entries})
+	if err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".snapshot-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// ImportSnapshot loads the entries of the snapshot file at path into c,
+// each with the time it had left when exported. Entries that have since
+// expired are skipped. It returns how many entries were loaded.
+func ImportSnapshot(ctx context.Context, c Cache, path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return 0, fmt.Errorf("parse snapshot %s: %w", path, err)
+	}
+	if snap.Version != snapshotVersion {
+		return 0, fmt.Errorf("snapshot %s has unsupported version %d", path, snap.Version)
+	}
+
+	loaded := 0
+	for _, entry := range snap.Entries {
+		ttl := time.Until(entry.ExpiresAt)
+		if ttl <= 0 {
+			continue
+		}
+		if err := c.Set(ctx, entry.Key, entry.Value, ttl); err != nil {
+			return loaded, fmt.Errorf("set %s: %w", entry.Key, err)
+		}
+		loaded++
+	}
+	return loaded, nil
+}
@@ -1,186 +1,1069 @@
 // Package config provides configuration loading for the Notion MCP server.
 //
-// It supports environment variables and .env file configuration.
+// It supports a YAML or JSON config file, a .env file and environment
+// variables. Values are resolved from an ordered list of layers; see Resolve
+// for precedence.
 package config
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"os/exec"
+	"reflect"
 	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
+	"unicode"
 
 	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+	"github.com/nixihz/notion-as-mcp/internal/tools"
 )
 
 // Config holds all configuration for the Notion MCP server.
 type Config struct {
 	// Notion API configuration
-	NotionAPIKey     string `json:"notion_api_key"`
-	NotionDatabaseID string `json:"notion_database_id"`
-	NotionTypeField  string `json:"notion_type_field"`
+	NotionAPIKey string `json:"notion_api_key" yaml:"notion_api_key"`
+	// NotionAPIKeyFile and NotionAPIKeyCommand supply the API key from a file
+	// or a command's output instead; either takes precedence over NotionAPIKey
+	NotionAPIKeyFile    string `json:"notion_api_key_file" yaml:"notion_api_key_file"`
+	NotionAPIKeyCommand string `json:"notion_api_key_command" yaml:"notion_api_key_command"`
+	NotionDatabaseID    string `json:"notion_database_id" yaml:"notion_database_id"`
+	NotionTypeField     string `json:"notion_type_field" yaml:"notion_type_field"`
+	// NotionAPIVersion is sent as the Notion-Version header
+	NotionAPIVersion string `json:"notion_api_version" yaml:"notion_api_version"`
+	// NotionBaseURL is the API endpoint, e.g. a proxy in front of Notion
+	NotionBaseURL string `json:"notion_base_url" yaml:"notion_base_url"`
+	// NotionHTTPProxy is the proxy for Notion API requests, overriding
+	// HTTPS_PROXY and the other proxy environment variables
+	NotionHTTPProxy string `json:"notion_http_proxy" yaml:"notion_http_proxy"`
+	// NotionHTTPTimeout bounds each Notion API request (0 = the notion
+	// client's built-in timeout, notion.DefaultTimeout)
+	NotionHTTPTimeout time.Duration `json:"notion_http_timeout" yaml:"notion_http_timeout"`
+	// NotionPageSize is how many pages each database query request returns (1-100)
+	NotionPageSize int `json:"notion_page_size" yaml:"notion_page_size"`
+	// NotionMaxBlockDepth is how deeply nested blocks are fetched, counting a
+	// page's top-level blocks as depth 1
+	NotionMaxBlockDepth int `json:"notion_max_block_depth" yaml:"notion_max_block_depth"`
+	// NotionMaxBlocks is how many blocks of a page are fetched in all
+	// (0 = no limit)
+	NotionMaxBlocks int `json:"notion_max_blocks" yaml:"notion_max_blocks"`
+	// NotionLogBodies logs truncated Notion request and response bodies at
+	// debug level
+	NotionLogBodies bool `json:"notion_log_bodies" yaml:"notion_log_bodies"`
+	// NotionSorts orders query results, e.g. "Name:ascending" (empty = Notion's order)
+	NotionSorts string `json:"notion_sorts" yaml:"notion_sorts"`
+
+	// Type values marking prompt, resource and tool pages (matched case-insensitively)
+	TypePrompt   string `json:"type_prompt" yaml:"type_prompt"`
+	TypeResource string `json:"type_resource" yaml:"type_resource"`
+	TypeTool     string `json:"type_tool" yaml:"type_tool"`
+	// NamePrefixes lists the prefix per page kind (kind=prefix) given to
+	// names that would not start with a letter
+	NamePrefixes string `json:"name_prefixes" yaml:"name_prefixes"`
+	// EnabledTypes lists the page kinds served, e.g. "prompt,resource" to
+	// expose no tools (empty = all)
+	EnabledTypes string `json:"enabled_types" yaml:"enabled_types"`
 
 	// Cache configuration
-	CacheTTL             time.Duration `json:"cache_ttl"`
-	CacheDir             string        `json:"cache_dir"`
-	CacheRefreshInterval time.Duration `json:"cache_refresh_interval"`
+	CacheTTL             time.Duration `json:"cache_ttl" yaml:"cache_ttl"`
+	CacheDir             string        `json:"cache_dir" yaml:"cache_dir"`
+	CacheRefreshInterval time.Duration `json:"cache_refresh_interval" yaml:"cache_refresh_interval"`
+	// RefreshWatchdogRestart restarts refresh loops that miss two intervals
+	RefreshWatchdogRestart bool `json:"refresh_watchdog_restart" yaml:"refresh_watchdog_restart"`
+	// CacheSweepInterval is how often expired file cache entries are purged (0 = only when read)
+	CacheSweepInterval time.Duration `json:"cache_sweep_interval" yaml:"cache_sweep_interval"`
+	// CacheSnapshot is a snapshot file, written by "cache export", loaded into
+	// the cache on startup
+	CacheSnapshot string `json:"cache_snapshot" yaml:"cache_snapshot"`
+
+	// ImageDownload saves page images locally instead of linking Notion's
+	// expiring URLs; images up to ImageInlineMax bytes become data URIs
+	ImageDownload  bool `json:"image_download" yaml:"image_download"`
+	ImageInlineMax int  `json:"image_inline_max" yaml:"image_inline_max"`
+	// ResourceMaxBlobBytes caps the attachment served as a resource page's
+	// binary contents; larger ones are served as a link (0 = always link)
+	ResourceMaxBlobBytes int `json:"resource_max_blob_bytes" yaml:"resource_max_blob_bytes"`
+	// PromptImageMaxBytes caps the images sent as image content in prompt
+	// messages; larger ones stay Markdown links (0 = always link)
+	PromptImageMaxBytes int `json:"prompt_image_max_bytes" yaml:"prompt_image_max_bytes"`
+	// MarkdownColors keeps heading colors as HTML spans in rendered Markdown
+	MarkdownColors bool `json:"markdown_colors" yaml:"markdown_colors"`
+	// MarkdownFrontMatter prepends a resource page's properties to its
+	// Markdown as YAML front matter
+	MarkdownFrontMatter bool `json:"markdown_front_matter" yaml:"markdown_front_matter"`
 
 	// Logging configuration
-	LogLevel string `json:"log_level"`
+	LogLevel string `json:"log_level" yaml:"log_level"`
 
 	// Execution configuration
-	ExecTimeout   time.Duration `json:"exec_timeout"`
-	ExecLanguages string        `json:"exec_languages"`
+	ExecTimeout   time.Duration `json:"exec_timeout" yaml:"exec_timeout"`
+	ExecLanguages string        `json:"exec_languages" yaml:"exec_languages"`
+	ExecRuntimes  string        `json:"exec_runtimes" yaml:"exec_runtimes"`
+	// ExecMaxTimeout caps the Timeout a tool page may declare (0 = no cap)
+	ExecMaxTimeout time.Duration `json:"exec_max_timeout" yaml:"exec_max_timeout"`
+	// ExecDedent strips common leading whitespace from tool code before running it
+	ExecDedent bool `json:"exec_dedent" yaml:"exec_dedent"`
+	// ToolResultWriteback appends each tool run's output, exit code and time
+	// to the tool page as an audit trail
+	ToolResultWriteback bool `json:"tool_result_writeback" yaml:"tool_result_writeback"`
+	// ToolOutputFormat is how tool results are returned: text, or json with
+	// stdout and stderr separated; a tool page's OutputFormat property overrides it
+	ToolOutputFormat string `json:"tool_output_format" yaml:"tool_output_format"`
+	// ToolExitCodeErrors flags tool results with a nonzero exit code as
+	// errors; turn it off for tools whose exit codes carry meaning
+	ToolExitCodeErrors bool `json:"tool_exit_code_errors" yaml:"tool_exit_code_errors"`
+	// ExecInsecureTLS disables TLS certificate verification for TypeScript tools
+	ExecInsecureTLS bool `json:"exec_insecure_tls" yaml:"exec_insecure_tls"`
+	// ExecMaxConcurrent caps tool executions running at once (0 = unlimited)
+	ExecMaxConcurrent int `json:"exec_max_concurrent" yaml:"exec_max_concurrent"`
+	// ExecMaxQueued caps executions waiting for a slot (0 = unlimited)
+	ExecMaxQueued int `json:"exec_max_queued" yaml:"exec_max_queued"`
+	// ExecMaxOutputBytes caps the output kept from a tool execution (0 = unlimited)
+	ExecMaxOutputBytes int `json:"exec_max_output_bytes" yaml:"exec_max_output_bytes"`
+	// ExecEnvPassthrough lists environment variables forwarded to tool code,
+	// comma-separated, in addition to PATH, HOME and the locale
+	ExecEnvPassthrough string `json:"exec_env_passthrough" yaml:"exec_env_passthrough"`
+	// ExecBashDeny lists regular expressions, comma-separated, that bash
+	// tool code must not match; an advisory guardrail, not a sandbox
+	ExecBashDeny string `json:"exec_bash_deny" yaml:"exec_bash_deny"`
 
 	// Change detection configuration
-	PollInterval   time.Duration `json:"poll_interval"`
-	RefreshOnStart bool          `json:"refresh_on_start"`
+	PollInterval   time.Duration `json:"poll_interval" yaml:"poll_interval"`
+	RefreshOnStart bool          `json:"refresh_on_start" yaml:"refresh_on_start"`
+	// StartupFetch is what to do when the initial page fetch fails: "fail"
+	// to exit, or "retry" to start empty and retry in the background
+	StartupFetch string `json:"startup_fetch" yaml:"startup_fetch"`
+	// Watch re-registers handlers when pages change, polling every PollInterval
+	Watch bool `json:"watch" yaml:"watch"`
 
 	// Server configuration
-	ServerHost    string `json:"server_host"`
-	ServerPort    int    `json:"server_port"`
-	TransportType string `json:"transport_type"`
+	// ServerName is the implementation name reported to MCP clients
+	ServerName    string `json:"server_name" yaml:"server_name"`
+	ServerHost    string `json:"server_host" yaml:"server_host"`
+	ServerPort    int    `json:"server_port" yaml:"server_port"`
+	TransportType string `json:"transport_type" yaml:"transport_type"`
+
+	// StdioMaxConcurrency caps in-flight requests over stdio (0 = unlimited, 1 = serial)
+	StdioMaxConcurrency int `json:"stdio_max_concurrency" yaml:"stdio_max_concurrency"`
+	// ListPageSize is how many prompts or resources a list response holds
+	// before it returns a cursor to the next page
+	ListPageSize int `json:"list_page_size" yaml:"list_page_size"`
+	// MetricsAddr is the address of the Prometheus metrics listener
+	// (empty = disabled)
+	MetricsAddr string `json:"metrics_addr" yaml:"metrics_addr"`
+
+	// ResolvedFrom records which source set each key
+	ResolvedFrom map[string]Source `json:"-" yaml:"-"`
 }
 
 // Default values.
 const (
 	defaultTypeField       = "Type"
+	defaultAPIVersion      = "2022-06-28"
+	defaultBaseURL         = "https://api.notion.com/v1"
+	defaultHTTPTimeout     = 30 * time.Second
+	defaultNotionPageSize  = 100
+	defaultNotionLogBodies = false
+	defaultTypePrompt      = "prompt"
+	defaultTypeResource    = "resource"
+	defaultTypeTool        = "tool"
+	defaultNamePrefixes    = "prompt=p_,resource=p_,tool=p_"
+	defaultEnabledTypes    = "prompt,resource,tool"
 	defaultCacheTTL        = 5 * time.Minute
 	defaultCacheDir        = "~/.cache/notion-as-mcp"
 	defaultCacheRefreshInt = 5 * time.Minute
+	defaultCacheSweepInt   = time.Hour
+	defaultImageDownload   = false
+	defaultImageInlineMax  = 16 << 10
+	defaultResourceMaxBlob = 10 << 20
+	defaultPromptImageMax  = 1 << 20
+	defaultMarkdownColors  = false
+	defaultFrontMatter     = false
 	defaultLogLevel        = "info"
 	defaultExecTimeout     = 30 * time.Second
-	defaultExecLang        = "bash,python,js,javascript,ts,typescript"
+	defaultExecMaxTimeout  = 5 * time.Minute
+	defaultExecLang        = "bash,python,js,javascript,ts,typescript,ruby,go,php"
 	defaultPollInt         = 60 * time.Second
 	defaultRefreshOn       = true
+	defaultStartupFetch    = "fail"
+	defaultWatch           = false
+	defaultServerName      = "notion-as-mcp"
 	defaultServerHost      = "0.0.0.0"
 	defaultServerPort      = 3100
 	defaultTransport       = "streamable"
+	defaultStdioMaxConc    = 0
+	defaultListPageSize    = 100
+	defaultWatchdogRestart = false
+	defaultExecDedent      = false
+	defaultToolWriteback   = false
+	defaultToolFormat      = "text"
+	defaultToolExitErrors  = true
+	defaultExecInsecureTLS = false
+	defaultExecMaxConc     = 0
+	defaultExecMaxQueued   = 0
+	defaultExecMaxOutput   = 1 << 20
 )
 
-// Load loads configuration from environment variables and .env file.
-func Load() (*Config, error) {
-	// Load .env file if it exists
-	_ = godotenv.Load()
+// Source identifies where a configuration value came from.
+type Source string
 
-	cfg := &Config{
-		NotionTypeField:      defaultTypeField,
-		CacheTTL:             defaultCacheTTL,
-		CacheDir:             defaultCacheDir,
-		CacheRefreshInterval: defaultCacheRefreshInt,
-		LogLevel:             defaultLogLevel,
-		ExecTimeout:          defaultExecTimeout,
-		ExecLanguages:        defaultExecLang,
-		PollInterval:         defaultPollInt,
-		RefreshOnStart:       defaultRefreshOn,
-		ServerHost:           defaultServerHost,
-		ServerPort:           defaultServerPort,
-		TransportType:        defaultTransport,
-	}
-
-	// Required: Notion API Key
-	if key := os.Getenv("NOTION_API_KEY"); key != "" {
-		cfg.NotionAPIKey = key
-	} else {
-		return nil, fmt.Errorf("NOTION_API_KEY is required")
-	}
-
-	// Required: Notion Database ID
-	if dbID := os.Getenv("NOTION_DATABASE_ID"); dbID != "" {
-		cfg.NotionDatabaseID = dbID
-	} else {
-		return nil, fmt.Errorf("NOTION_DATABASE_ID is required")
-	}
-
-	// Optional: Type field name
-	if tf := os.Getenv("NOTION_TYPE_FIELD"); tf != "" {
-		cfg.NotionTypeField = tf
-	}
-
-	// Optional: Cache TTL
-	if cttl := os.Getenv("CACHE_TTL"); cttl != "" {
-		ttl, err := time.ParseDuration(cttl)
+// Configuration sources, from lowest to highest precedence.
+const (
+	SourceDefault Source = "default"
+	SourceFile    Source = "file"
+	SourceDotEnv  Source = "dotenv"
+	SourceEnv     Source = "env"
+	SourceFlag    Source = "flag"
+)
+
+// Layer is a set of raw configuration values from a single source,
+// keyed by environment variable name. Empty values are treated as unset.
+type Layer struct {
+	Source Source
+	Values map[string]string
+}
+
+// Keys lists every configuration key in resolution order.
+var Keys = []string{
+	"NOTION_API_KEY",
+	"NOTION_API_KEY_FILE",
+	"NOTION_API_KEY_COMMAND",
+	"NOTION_DATABASE_ID",
+	"NOTION_TYPE_FIELD",
+	"NOTION_API_VERSION",
+	"NOTION_BASE_URL",
+	"NOTION_HTTP_PROXY",
+	"NOTION_HTTP_TIMEOUT",
+	"NOTION_PAGE_SIZE",
+	"NOTION_MAX_BLOCK_DEPTH",
+	"NOTION_MAX_BLOCKS",
+	"NOTION_LOG_BODIES",
+	"NOTION_SORTS",
+	"TYPE_PROMPT",
+	"TYPE_RESOURCE",
+	"TYPE_TOOL",
+	"NAME_PREFIXES",
+	"ENABLED_TYPES",
+	"CACHE_TTL",
+	"CACHE_DIR",
+	"CACHE_REFRESH_INTERVAL",
+	"CACHE_SWEEP_INTERVAL",
+	"CACHE_SNAPSHOT",
+	"REFRESH_WATCHDOG_RESTART",
+	"IMAGE_DOWNLOAD",
+	"IMAGE_INLINE_MAX",
+	"RESOURCE_MAX_BLOB_BYTES",
+	"PROMPT_IMAGE_MAX_BYTES",
+	"MARKDOWN_COLORS",
+	"MARKDOWN_FRONT_MATTER",
+	"LOG_LEVEL",
+	"EXEC_TIMEOUT",
+	"EXEC_MAX_TIMEOUT",
+	"EXEC_LANGUAGES",
+	"EXEC_RUNTIMES",
+	"EXEC_DEDENT",
+	"TOOL_RESULT_WRITEBACK",
+	"TOOL_OUTPUT_FORMAT",
+	"TOOL_EXIT_CODE_ERRORS",
+	"EXEC_INSECURE_TLS",
+	"EXEC_MAX_CONCURRENT",
+	"EXEC_MAX_QUEUED",
+	"EXEC_MAX_OUTPUT_BYTES",
+	"EXEC_ENV_PASSTHROUGH",
+	"EXEC_BASH_DENY",
+	"POLL_INTERVAL",
+	"REFRESH_ON_START",
+	"STARTUP_FETCH",
+	"WATCH",
+	"SERVER_NAME",
+	"SERVER_HOST",
+	"SERVER_PORT",
+	"TRANSPORT_TYPE",
+	"STDIO_MAX_CONCURRENCY",
+	"LIST_PAGE_SIZE",
+	"METRICS_ADDR",
+}
+
+// ConfigFileEnv names the environment variable holding the config file path.
+const ConfigFileEnv = "NOTION_MCP_CONFIG"
+
+// NoDotEnvEnv names the environment variable that, set to true or 1, skips
+// the .env file so the process environment is authoritative.
+const NoDotEnvEnv = "NOTION_MCP_NO_DOTENV"
+
+// Load loads configuration from defaults, the config file named by
+// NOTION_MCP_CONFIG, the .env file (unless NOTION_MCP_NO_DOTENV is set) and
+// environment variables, with any extra layers (e.g. CLI flags) applied on
+// top in order.
+func Load(extra ...Layer) (*Config, error) {
+	return LoadFile("", extra...)
+}
+
+// LoadFile is like Load but reads the config file at path, falling back to
+// NOTION_MCP_CONFIG when path is empty.
+func LoadFile(path string, extra ...Layer) (*Config, error) {
+	layers, err := fileLayers(path, extra)
+	if err != nil {
+		return nil, err
+	}
+	return Resolve(layers)
+}
+
+// LoadSetup is like LoadFile but does not require NOTION_DATABASE_ID, for
+// commands that run before the database exists, such as init-db.
+func LoadSetup(path string) (*Config, error) {
+	layers, err := fileLayers(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := resolve(layers)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.validate(false); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// fileLayers returns the layers LoadFile resolves, lowest precedence first.
+func fileLayers(path string, extra []Layer) ([]Layer, error) {
+	layers := []Layer{defaultLayer()}
+	if path == "" {
+		path = os.Getenv(ConfigFileEnv)
+	}
+	if path != "" {
+		file, err := fileLayer(path)
 		if err != nil {
-			return nil, fmt.Errorf("invalid CACHE_TTL: %w", err)
+			return nil, err
 		}
-		cfg.CacheTTL = ttl
+		layers = append(layers, file)
 	}
+	if v := os.Getenv(NoDotEnvEnv); v != "true" && v != "1" {
+		layers = append(layers, dotEnvLayer(".env"))
+	}
+	layers = append(layers, envLayer())
+	return append(layers, extra...), nil
+}
 
-	// Optional: Cache directory
-	if cdir := os.Getenv("CACHE_DIR"); cdir != "" {
-		cfg.CacheDir = cdir
+// Resolve merges layers into a Config. Layers are ordered from lowest to
+// highest precedence: for each key the last layer with a non-empty value wins.
+func Resolve(layers []Layer) (*Config, error) {
+	cfg, err := resolve(layers)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
 	}
+	return cfg, nil
+}
 
-	// Optional: Cache refresh interval
-	if cri := os.Getenv("CACHE_REFRESH_INTERVAL"); cri != "" {
-		interval, err := time.ParseDuration(cri)
-		if err != nil {
-			return nil, fmt.Errorf("invalid CACHE_REFRESH_INTERVAL: %w", err)
+// resolve merges layers into a Config without validating it.
+func resolve(layers []Layer) (*Config, error) {
+	cfg := &Config{
+		ResolvedFrom: make(map[string]Source),
+	}
+
+	for _, key := range Keys {
+		value, source, ok := lookup(layers, key)
+		if !ok {
+			continue
 		}
-		cfg.CacheRefreshInterval = interval
+		if err := cfg.set(key, value); err != nil {
+			return nil, err
+		}
+		cfg.ResolvedFrom[key] = source
 	}
 
-	// Optional: Log level
-	if ll := os.Getenv("LOG_LEVEL"); ll != "" {
-		cfg.LogLevel = ll
+	if err := cfg.resolveAPIKey(); err != nil {
+		return nil, err
 	}
+	return cfg, nil
+}
 
-	// Optional: Execution timeout
-	if et := os.Getenv("EXEC_TIMEOUT"); et != "" {
-		timeout, err := time.ParseDuration(et)
+// lookup returns the value for key from the highest-precedence layer that sets it.
+func lookup(layers []Layer, key string) (string, Source, bool) {
+	for i := len(layers) - 1; i >= 0; i-- {
+		if v := layers[i].Values[key]; v != "" {
+			return v, layers[i].Source, true
+		}
+	}
+	return "", "", false
+}
+
+// apiKeyCommandTimeout bounds how long NOTION_API_KEY_COMMAND may run.
+const apiKeyCommandTimeout = 30 * time.Second
+
+// resolveAPIKey replaces NotionAPIKey with the key read from
+// NotionAPIKeyFile or printed by NotionAPIKeyCommand, if either is set.
+// Trailing whitespace, such as the newline most tools print, is trimmed.
+func (c *Config) resolveAPIKey() error {
+	var key, from string
+	switch {
+	case c.NotionAPIKeyFile != "" && c.NotionAPIKeyCommand != "":
+		return fmt.Errorf("set only one of NOTION_API_KEY_FILE and NOTION_API_KEY_COMMAND")
+	case c.NotionAPIKeyFile != "":
+		data, err := os.ReadFile(c.NotionAPIKeyFile)
+		if err != nil {
+			return fmt.Errorf("read NOTION_API_KEY_FILE: %w", err)
+		}
+		key, from = string(data), "NOTION_API_KEY_FILE"
+	case c.NotionAPIKeyCommand != "":
+		ctx, cancel := context.WithTimeout(context.Background(), apiKeyCommandTimeout)
+		defer cancel()
+		var stderr bytes.Buffer
+		cmd := exec.CommandContext(ctx, "sh", "-c", c.NotionAPIKeyCommand)
+		cmd.Stderr = &stderr
+		out, err := cmd.Output()
 		if err != nil {
-			return nil, fmt.Errorf("invalid EXEC_TIMEOUT: %w", err)
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return fmt.Errorf("run NOTION_API_KEY_COMMAND: %w: %s", err, msg)
+			}
+			return fmt.Errorf("run NOTION_API_KEY_COMMAND: %w", err)
 		}
-		cfg.ExecTimeout = timeout
+		key, from = string(out), "NOTION_API_KEY_COMMAND"
+	default:
+		return nil
 	}
 
-	// Optional: Execution languages
-	if el := os.Getenv("EXEC_LANGUAGES"); el != "" {
-		cfg.ExecLanguages = el
+	key = strings.TrimRightFunc(key, unicode.IsSpace)
+	if key == "" {
+		return fmt.Errorf("%s produced an empty API key", from)
 	}
+	c.NotionAPIKey = key
+	c.ResolvedFrom["NOTION_API_KEY"] = c.ResolvedFrom[from]
+	return nil
+}
 
-	// Optional: Poll interval
-	if pi := os.Getenv("POLL_INTERVAL"); pi != "" {
-		interval, err := time.ParseDuration(pi)
-		if err != nil {
-			return nil, fmt.Errorf("invalid POLL_INTERVAL: %w", err)
+// defaultLayer returns the built-in default values.
+func defaultLayer() Layer {
+	return Layer{
+		Source: SourceDefault,
+		Values: map[string]string{
+			"NOTION_TYPE_FIELD":        defaultTypeField,
+			"NOTION_API_VERSION":       defaultAPIVersion,
+			"NOTION_BASE_URL":          defaultBaseURL,
+			"NOTION_HTTP_TIMEOUT":      defaultHTTPTimeout.String(),
+			"NOTION_PAGE_SIZE":         strconv.Itoa(defaultNotionPageSize),
+			"NOTION_MAX_BLOCK_DEPTH":   strconv.Itoa(notion.DefaultMaxBlockDepth),
+			"NOTION_MAX_BLOCKS":        strconv.Itoa(notion.DefaultMaxBlocks),
+			"NOTION_LOG_BODIES":        strconv.FormatBool(defaultNotionLogBodies),
+			"TYPE_PROMPT":              defaultTypePrompt,
+			"TYPE_RESOURCE":            defaultTypeResource,
+			"TYPE_TOOL":                defaultTypeTool,
+			"NAME_PREFIXES":            defaultNamePrefixes,
+			"ENABLED_TYPES":            defaultEnabledTypes,
+			"CACHE_TTL":                defaultCacheTTL.String(),
+			"CACHE_DIR":                defaultCacheDir,
+			"CACHE_REFRESH_INTERVAL":   defaultCacheRefreshInt.String(),
+			"CACHE_SWEEP_INTERVAL":     defaultCacheSweepInt.String(),
+			"REFRESH_WATCHDOG_RESTART": strconv.FormatBool(defaultWatchdogRestart),
+			"IMAGE_DOWNLOAD":           strconv.FormatBool(defaultImageDownload),
+			"IMAGE_INLINE_MAX":         strconv.Itoa(defaultImageInlineMax),
+			"RESOURCE_MAX_BLOB_BYTES":  strconv.Itoa(defaultResourceMaxBlob),
+			"PROMPT_IMAGE_MAX_BYTES":   strconv.Itoa(defaultPromptImageMax),
+			"MARKDOWN_COLORS":          strconv.FormatBool(defaultMarkdownColors),
+			"MARKDOWN_FRONT_MATTER":    strconv.FormatBool(defaultFrontMatter),
+			"LOG_LEVEL":                defaultLogLevel,
+			"EXEC_TIMEOUT":             defaultExecTimeout.String(),
+			"EXEC_MAX_TIMEOUT":         defaultExecMaxTimeout.String(),
+			"EXEC_LANGUAGES":           defaultExecLang,
+			"EXEC_DEDENT":              strconv.FormatBool(defaultExecDedent),
+			"TOOL_RESULT_WRITEBACK":    strconv.FormatBool(defaultToolWriteback),
+			"TOOL_OUTPUT_FORMAT":       defaultToolFormat,
+			"TOOL_EXIT_CODE_ERRORS":    strconv.FormatBool(defaultToolExitErrors),
+			"EXEC_INSECURE_TLS":        strconv.FormatBool(defaultExecInsecureTLS),
+			"EXEC_MAX_CONCURRENT":      strconv.Itoa(defaultExecMaxConc),
+			"EXEC_MAX_QUEUED":          strconv.Itoa(defaultExecMaxQueued),
+			"EXEC_MAX_OUTPUT_BYTES":    strconv.Itoa(defaultExecMaxOutput),
+			"POLL_INTERVAL":            defaultPollInt.String(),
+			"REFRESH_ON_START":         strconv.FormatBool(defaultRefreshOn),
+			"STARTUP_FETCH":            defaultStartupFetch,
+			"WATCH":                    strconv.FormatBool(defaultWatch),
+			"SERVER_NAME":              defaultServerName,
+			"SERVER_HOST":              defaultServerHost,
+			"SERVER_PORT":              strconv.Itoa(defaultServerPort),
+			"TRANSPORT_TYPE":           defaultTransport,
+			"STDIO_MAX_CONCURRENCY":    strconv.Itoa(defaultStdioMaxConc),
+			"LIST_PAGE_SIZE":           strconv.Itoa(defaultListPageSize),
+		},
+	}
+}
+
+// fileKeys maps config file keys, the yaml tags of Config fields, to
+// configuration keys.
+var fileKeys = func() map[string]string {
+	keys := make(map[string]string)
+	t := reflect.TypeOf(Config{})
+	for i := range t.NumField() {
+		if tag := t.Field(i).Tag.Get("yaml"); tag != "" && tag != "-" {
+			keys[tag] = strings.ToUpper(tag)
+		}
+	}
+	return keys
+}()
+
+// fileLayer reads values from a YAML or JSON config file (JSON being a
+// subset of YAML). Durations are written as strings such as "5m".
+func fileLayer(path string) (Layer, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Layer{}, fmt.Errorf("read config file: %w", err)
+	}
+	var raw map[string]any
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return Layer{}, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	for name, v := range raw {
+		key, ok := fileKeys[name]
+		if !ok {
+			return Layer{}, fmt.Errorf("config file %s: unknown key %q", path, name)
+		}
+		switch v := v.(type) {
+		case nil:
+		case []any:
+			// Lists such as exec_languages join into comma-separated values
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			values[key] = strings.Join(items, ",")
+		default:
+			values[key] = fmt.Sprint(v)
 		}
-		cfg.PollInterval = interval
 	}
+	return Layer{Source: SourceFile, Values: values}, nil
+}
 
-	// Optional: Refresh on start
-	if ros := os.Getenv("REFRESH_ON_START"); ros != "" {
-		cfg.RefreshOnStart = ros == "true" || ros == "1"
+// dotEnvLayer reads values from a .env file, if it exists.
+func dotEnvLayer(path string) Layer {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		values = nil
 	}
+	return Layer{Source: SourceDotEnv, Values: values}
+}
 
-	// Optional: Server host
-	if sh := os.Getenv("SERVER_HOST"); sh != "" {
-		cfg.ServerHost = sh
+// envLayer reads values from the process environment.
+func envLayer() Layer {
+	values := make(map[string]string)
+	for _, key := range Keys {
+		values[key] = os.Getenv(key)
 	}
+	return Layer{Source: SourceEnv, Values: values}
+}
 
-	// Optional: Server port
-	if sp := os.Getenv("SERVER_PORT"); sp != "" {
-		port, err := strconv.Atoi(sp)
-		if err != nil {
-			return nil, fmt.Errorf("invalid SERVER_PORT: %w", err)
+// secretKeys lists keys whose values must never be printed.
+var secretKeys = map[string]bool{
+	"NOTION_API_KEY": true,
+}
+
+// Redact masks a secret, keeping the last four characters of long values
+// so the user can tell which secret is in use.
+func Redact(value string) string {
+	if value == "" {
+		return ""
+	}
+	if len(value) < 12 {
+		return "****"
+	}
+	return "****" + value[len(value)-4:]
+}
+
+// urlKeys lists keys whose values are URLs that may carry a password.
+var urlKeys = map[string]bool{
+	"NOTION_HTTP_PROXY": true,
+}
+
+// RedactURL masks the password of a URL such as a proxy's, leaving values
+// that are not URLs unchanged.
+func RedactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return value
+	}
+	return u.Redacted()
+}
+
+// redacted replaces the API key when a Config is printed or marshaled.
+const redacted = "***"
+
+// redactedConfig has Config's fields but not its methods, so it can be
+// formatted without recursing into String or MarshalJSON.
+type redactedConfig Config
+
+// redact returns a copy of c with the API key and proxy password masked.
+func (c Config) redact() redactedConfig {
+	if c.NotionAPIKey != "" {
+		c.NotionAPIKey = redacted
+	}
+	c.NotionHTTPProxy = RedactURL(c.NotionHTTPProxy)
+	return redactedConfig(c)
+}
+
+// String formats the configuration with the API key redacted, so logging a
+// Config never leaks the token.
+func (c Config) String() string {
+	return fmt.Sprintf("%+v", c.redact())
+}
+
+// MarshalJSON encodes the configuration with the API key redacted.
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.redact())
+}
+
+// Describe writes the effective configuration as a table of key, value and
+// the source that set it. Secret values are redacted.
+func (c *Config) Describe(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, key := range Keys {
+		value := c.get(key)
+		if secretKeys[key] {
+			value = Redact(value)
+		} else if urlKeys[key] {
+			value = RedactURL(value)
+		}
+		source := string(c.ResolvedFrom[key])
+		if source == "" {
+			source = "unset"
 		}
-		cfg.ServerPort = port
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", key, value, source)
 	}
+	return tw.Flush()
+}
 
-	// Optional: Transport type
-	if tt := os.Getenv("TRANSPORT_TYPE"); tt != "" {
-		cfg.TransportType = tt
+// get formats the field for key as a raw value; it is the inverse of set.
+func (c *Config) get(key string) string {
+	switch key {
+	case "NOTION_API_KEY":
+		return c.NotionAPIKey
+	case "NOTION_API_KEY_FILE":
+		return c.NotionAPIKeyFile
+	case "NOTION_API_KEY_COMMAND":
+		return c.NotionAPIKeyCommand
+	case "NOTION_DATABASE_ID":
+		return c.NotionDatabaseID
+	case "NOTION_TYPE_FIELD":
+		return c.NotionTypeField
+	case "NOTION_API_VERSION":
+		return c.NotionAPIVersion
+	case "NOTION_BASE_URL":
+		return c.NotionBaseURL
+	case "NOTION_HTTP_PROXY":
+		return c.NotionHTTPProxy
+	case "NOTION_HTTP_TIMEOUT":
+		return c.NotionHTTPTimeout.String()
+	case "NOTION_PAGE_SIZE":
+		return strconv.Itoa(c.NotionPageSize)
+	case "NOTION_MAX_BLOCK_DEPTH":
+		return strconv.Itoa(c.NotionMaxBlockDepth)
+	case "NOTION_MAX_BLOCKS":
+		return strconv.Itoa(c.NotionMaxBlocks)
+	case "NOTION_LOG_BODIES":
+		return strconv.FormatBool(c.NotionLogBodies)
+	case "NOTION_SORTS":
+		return c.NotionSorts
+	case "TYPE_PROMPT":
+		return c.TypePrompt
+	case "TYPE_RESOURCE":
+		return c.TypeResource
+	case "TYPE_TOOL":
+		return c.TypeTool
+	case "NAME_PREFIXES":
+		return c.NamePrefixes
+	case "ENABLED_TYPES":
+		return c.EnabledTypes
+	case "CACHE_TTL":
+		return c.CacheTTL.String()
+	case "CACHE_DIR":
+		return c.CacheDir
+	case "CACHE_REFRESH_INTERVAL":
+		return c.CacheRefreshInterval.String()
+	case "CACHE_SWEEP_INTERVAL":
+		return c.CacheSweepInterval.String()
+	case "CACHE_SNAPSHOT":
+		return c.CacheSnapshot
+	case "REFRESH_WATCHDOG_RESTART":
+		return strconv.FormatBool(c.RefreshWatchdogRestart)
+	case "IMAGE_DOWNLOAD":
+		return strconv.FormatBool(c.ImageDownload)
+	case "IMAGE_INLINE_MAX":
+		return strconv.Itoa(c.ImageInlineMax)
+	case "RESOURCE_MAX_BLOB_BYTES":
+		return strconv.Itoa(c.ResourceMaxBlobBytes)
+	case "PROMPT_IMAGE_MAX_BYTES":
+		return strconv.Itoa(c.PromptImageMaxBytes)
+	case "MARKDOWN_COLORS":
+		return strconv.FormatBool(c.MarkdownColors)
+	case "MARKDOWN_FRONT_MATTER":
+		return strconv.FormatBool(c.MarkdownFrontMatter)
+	case "LOG_LEVEL":
+		return c.LogLevel
+	case "EXEC_TIMEOUT":
+		return c.ExecTimeout.String()
+	case "EXEC_MAX_TIMEOUT":
+		return c.ExecMaxTimeout.String()
+	case "EXEC_LANGUAGES":
+		return c.ExecLanguages
+	case "EXEC_RUNTIMES":
+		return c.ExecRuntimes
+	case "EXEC_DEDENT":
+		return strconv.FormatBool(c.ExecDedent)
+	case "TOOL_RESULT_WRITEBACK":
+		return strconv.FormatBool(c.ToolResultWriteback)
+	case "TOOL_OUTPUT_FORMAT":
+		return c.ToolOutputFormat
+	case "TOOL_EXIT_CODE_ERRORS":
+		return strconv.FormatBool(c.ToolExitCodeErrors)
+	case "EXEC_INSECURE_TLS":
+		return strconv.FormatBool(c.ExecInsecureTLS)
+	case "EXEC_MAX_CONCURRENT":
+		return strconv.Itoa(c.ExecMaxConcurrent)
+	case "EXEC_MAX_QUEUED":
+		return strconv.Itoa(c.ExecMaxQueued)
+	case "EXEC_MAX_OUTPUT_BYTES":
+		return strconv.Itoa(c.ExecMaxOutputBytes)
+	case "EXEC_ENV_PASSTHROUGH":
+		return c.ExecEnvPassthrough
+	case "EXEC_BASH_DENY":
+		return c.ExecBashDeny
+	case "POLL_INTERVAL":
+		return c.PollInterval.String()
+	case "REFRESH_ON_START":
+		return strconv.FormatBool(c.RefreshOnStart)
+	case "STARTUP_FETCH":
+		return c.StartupFetch
+	case "WATCH":
+		return strconv.FormatBool(c.Watch)
+	case "SERVER_NAME":
+		return c.ServerName
+	case "SERVER_HOST":
+		return c.ServerHost
+	case "SERVER_PORT":
+		return strconv.Itoa(c.ServerPort)
+	case "TRANSPORT_TYPE":
+		return c.TransportType
+	case "STDIO_MAX_CONCURRENCY":
+		return strconv.Itoa(c.StdioMaxConcurrency)
+	case "LIST_PAGE_SIZE":
+		return strconv.Itoa(c.ListPageSize)
+	case "METRICS_ADDR":
+		return c.MetricsAddr
 	}
+	return ""
+}
 
-	return cfg, nil
+// set parses a raw value for key into the corresponding field.
+func (c *Config) set(key, value string) error {
+	switch key {
+	case "NOTION_API_KEY":
+		c.NotionAPIKey = value
+	case "NOTION_API_KEY_FILE":
+		c.NotionAPIKeyFile = value
+	case "NOTION_API_KEY_COMMAND":
+		c.NotionAPIKeyCommand = value
+	case "NOTION_DATABASE_ID":
+		c.NotionDatabaseID = value
+	case "NOTION_TYPE_FIELD":
+		c.NotionTypeField = value
+	case "NOTION_API_VERSION":
+		c.NotionAPIVersion = value
+	case "NOTION_BASE_URL":
+		c.NotionBaseURL = value
+	case "NOTION_HTTP_PROXY":
+		c.NotionHTTPProxy = value
+	case "NOTION_HTTP_TIMEOUT":
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid NOTION_HTTP_TIMEOUT: must be a non-negative duration")
+		}
+		c.NotionHTTPTimeout = timeout
+	case "NOTION_PAGE_SIZE":
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 || size > 100 {
+			return fmt.Errorf("invalid NOTION_PAGE_SIZE: must be between 1 and 100")
+		}
+		c.NotionPageSize = size
+	case "NOTION_MAX_BLOCK_DEPTH":
+		depth, err := strconv.Atoi(value)
+		if err != nil || depth < 1 {
+			return fmt.Errorf("invalid NOTION_MAX_BLOCK_DEPTH: must be a positive integer")
+		}
+		c.NotionMaxBlockDepth = depth
+	case "NOTION_MAX_BLOCKS":
+		blocks, err := strconv.Atoi(value)
+		if err != nil || blocks < 0 {
+			return fmt.Errorf("invalid NOTION_MAX_BLOCKS: must be a non-negative integer")
+		}
+		c.NotionMaxBlocks = blocks
+	case "NOTION_LOG_BODIES":
+		c.NotionLogBodies = value == "true" || value == "1"
+	case "NOTION_SORTS":
+		c.NotionSorts = value
+	case "TYPE_PROMPT":
+		c.TypePrompt = value
+	case "TYPE_RESOURCE":
+		c.TypeResource = value
+	case "TYPE_TOOL":
+		c.TypeTool = value
+	case "NAME_PREFIXES":
+		if err := validateNamePrefixes(value); err != nil {
+			return fmt.Errorf("invalid NAME_PREFIXES: %w", err)
+		}
+		c.NamePrefixes = value
+	case "ENABLED_TYPES":
+		c.EnabledTypes = value
+	case "CACHE_TTL":
+		ttl, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid CACHE_TTL: %w", err)
+		}
+		c.CacheTTL = ttl
+	case "CACHE_DIR":
+		c.CacheDir = value
+	case "CACHE_REFRESH_INTERVAL":
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid CACHE_REFRESH_INTERVAL: %w", err)
+		}
+		c.CacheRefreshInterval = interval
+	case "CACHE_SWEEP_INTERVAL":
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid CACHE_SWEEP_INTERVAL: %w", err)
+		}
+		c.CacheSweepInterval = interval
+	case "CACHE_SNAPSHOT":
+		c.CacheSnapshot = value
+	case "REFRESH_WATCHDOG_RESTART":
+		c.RefreshWatchdogRestart = value == "true" || value == "1"
+	case "IMAGE_DOWNLOAD":
+		c.ImageDownload = value == "true" || value == "1"
+	case "IMAGE_INLINE_MAX":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid IMAGE_INLINE_MAX: must be a non-negative integer")
+		}
+		c.ImageInlineMax = limit
+	case "RESOURCE_MAX_BLOB_BYTES":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid RESOURCE_MAX_BLOB_BYTES: must be a non-negative integer")
+		}
+		c.ResourceMaxBlobBytes = limit
+	case "PROMPT_IMAGE_MAX_BYTES":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid PROMPT_IMAGE_MAX_BYTES: must be a non-negative integer")
+		}
+		c.PromptImageMaxBytes = limit
+	case "MARKDOWN_COLORS":
+		c.MarkdownColors = value == "true" || value == "1"
+	case "MARKDOWN_FRONT_MATTER":
+		c.MarkdownFrontMatter = value == "true" || value == "1"
+	case "LOG_LEVEL":
+		c.LogLevel = value
+	case "EXEC_TIMEOUT":
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid EXEC_TIMEOUT: %w", err)
+		}
+		c.ExecTimeout = timeout
+	case "EXEC_MAX_TIMEOUT":
+		timeout, err := time.ParseDuration(value)
+		if err != nil || timeout < 0 {
+			return fmt.Errorf("invalid EXEC_MAX_TIMEOUT: must be a non-negative duration")
+		}
+		c.ExecMaxTimeout = timeout
+	case "EXEC_LANGUAGES":
+		c.ExecLanguages = value
+	case "EXEC_RUNTIMES":
+		c.ExecRuntimes = value
+	case "EXEC_DEDENT":
+		c.ExecDedent = value == "true" || value == "1"
+	case "TOOL_RESULT_WRITEBACK":
+		c.ToolResultWriteback = value == "true" || value == "1"
+	case "TOOL_OUTPUT_FORMAT":
+		switch value {
+		case "text", "json":
+			c.ToolOutputFormat = value
+		default:
+			return fmt.Errorf("invalid TOOL_OUTPUT_FORMAT %q: must be text or json", value)
+		}
+	case "TOOL_EXIT_CODE_ERRORS":
+		c.ToolExitCodeErrors = value == "true" || value == "1"
+	case "EXEC_INSECURE_TLS":
+		c.ExecInsecureTLS = value == "true" || value == "1"
+	case "EXEC_MAX_CONCURRENT":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid EXEC_MAX_CONCURRENT: must be a non-negative integer")
+		}
+		c.ExecMaxConcurrent = limit
+	case "EXEC_MAX_QUEUED":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid EXEC_MAX_QUEUED: must be a non-negative integer")
+		}
+		c.ExecMaxQueued = limit
+	case "EXEC_MAX_OUTPUT_BYTES":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid EXEC_MAX_OUTPUT_BYTES: must be a non-negative integer")
+		}
+		c.ExecMaxOutputBytes = limit
+	case "EXEC_ENV_PASSTHROUGH":
+		c.ExecEnvPassthrough = value
+	case "EXEC_BASH_DENY":
+		if _, err := tools.ParseDenyPatterns(value); err != nil {
+			return fmt.Errorf("invalid EXEC_BASH_DENY: %w", err)
+		}
+		c.ExecBashDeny = value
+	case "POLL_INTERVAL":
+		interval, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid POLL_INTERVAL: %w", err)
+		}
+		c.PollInterval = interval
+	case "REFRESH_ON_START":
+		c.RefreshOnStart = value == "true" || value == "1"
+	case "STARTUP_FETCH":
+		switch value {
+		case "fail", "retry":
+			c.StartupFetch = value
+		default:
+			return fmt.Errorf("invalid STARTUP_FETCH %q: must be fail or retry", value)
+		}
+	case "WATCH":
+		c.Watch = value == "true" || value == "1"
+	case "SERVER_NAME":
+		c.ServerName = value
+	case "SERVER_HOST":
+		c.ServerHost = value
+	case "SERVER_PORT":
+		port, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid SERVER_PORT: %w", err)
+		}
+		c.ServerPort = port
+	case "TRANSPORT_TYPE":
+		c.TransportType = value
+	case "STDIO_MAX_CONCURRENCY":
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 0 {
+			return fmt.Errorf("invalid STDIO_MAX_CONCURRENCY: must be a non-negative integer")
+		}
+		c.StdioMaxConcurrency = limit
+	case "LIST_PAGE_SIZE":
+		size, err := strconv.Atoi(value)
+		if err != nil || size < 1 {
+			return fmt.Errorf("invalid LIST_PAGE_SIZE: must be a positive integer")
+		}
+		c.ListPageSize = size
+	case "METRICS_ADDR":
+		c.MetricsAddr = value
+	default:
+		return fmt.Errorf("unknown config key %q", key)
+	}
+	return nil
 }
 
 // Validate validates the configuration.
 func (c *Config) Validate() error {
+	return c.validate(true)
+}
+
+// validate validates the configuration, requiring NOTION_DATABASE_ID if
+// requireDatabase is set.
+func (c *Config) validate(requireDatabase bool) error {
 	if c.NotionAPIKey == "" {
 		return fmt.Errorf("NOTION_API_KEY is required")
 	}
-	if c.NotionDatabaseID == "" {
+	if requireDatabase && c.NotionDatabaseID == "" {
 		return fmt.Errorf("NOTION_DATABASE_ID is required")
 	}
+	if c.NotionBaseURL != "" {
+		u, err := url.Parse(c.NotionBaseURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid NOTION_BASE_URL %q: must be an http(s) URL", c.NotionBaseURL)
+		}
+	}
+	if c.NotionHTTPProxy != "" {
+		u, err := url.Parse(c.NotionHTTPProxy)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Host == "" {
+			return fmt.Errorf("invalid NOTION_HTTP_PROXY %q: must be an http(s) or socks5 URL", RedactURL(c.NotionHTTPProxy))
+		}
+	}
+	for _, kind := range strings.Split(c.EnabledTypes, ",") {
+		switch strings.ToLower(strings.TrimSpace(kind)) {
+		case "", "prompt", "resource", "tool":
+		default:
+			return fmt.Errorf("invalid ENABLED_TYPES entry %q: must be prompt, resource or tool", strings.TrimSpace(kind))
+		}
+	}
 	return nil
 }
+
+// NamePrefix returns the prefix NAME_PREFIXES sets for kind ("prompt",
+// "resource" or "tool"), or "" if it sets none.
+func (c *Config) NamePrefix(kind string) string {
+	for _, entry := range strings.Split(c.NamePrefixes, ",") {
+		k, prefix, ok := strings.Cut(entry, "=")
+		if ok && strings.TrimSpace(k) == kind {
+			return strings.TrimSpace(prefix)
+		}
+	}
+	return ""
+}
+
+// validateNamePrefixes checks a NAME_PREFIXES list. Each prefix must be a
+// valid MCP name itself, so prefixed names are too.
+func validateNamePrefixes(value string) error {
+	for _, entry := range strings.Split(value, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		kind, prefix, ok := strings.Cut(entry, "=")
+		switch strings.TrimSpace(kind) {
+		case "prompt", "resource", "tool":
+		default:
+			return fmt.Errorf("%q: kind must be prompt, resource or tool", entry)
+		}
+		prefix = strings.TrimSpace(prefix)
+		valid := ok && prefix != "" && prefix[0] >= 'a' && prefix[0] <= 'z'
+		for _, r := range prefix {
+			valid = valid && (r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '_' || r == '-')
+		}
+		if !valid {
+			return fmt.Errorf("%q: prefix must start with a lowercase letter and contain only a-z, 0-9, _ and -", entry)
+		}
+	}
+	return nil
+}
+
+// TypeEnabled reports whether pages of kind ("prompt", "resource" or
+// "tool") are served. All kinds are served when EnabledTypes is empty.
+func (c *Config) TypeEnabled(kind string) bool {
+	if strings.TrimSpace(c.EnabledTypes) == "" {
+		return true
+	}
+	for _, enabled := range strings.Split(c.EnabledTypes, ",") {
+		if strings.EqualFold(strings.TrimSpace(enabled), kind) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package notion
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxImageSize caps how much of an image ImageStore downloads.
+const maxImageSize = 20 << 20
+
+// ImageStore downloads images referenced by pages so rendered Markdown
+// doesn't point at Notion's signed URLs, which expire after an hour. Small
+// images are inlined as data URIs; the rest are saved under a directory and
+// referenced by local path.
+type ImageStore struct {
+	dir        string
+	inlineMax  int
+	httpClient *http.Client
+}
+
+// NewImageStore creates an ImageStore saving images under dir and inlining
+// those of at most inlineMax bytes.
+func NewImageStore(dir string, inlineMax int) *ImageStore {
+	return &ImageStore{
+		dir:        dir,
+		inlineMax:  inlineMax,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Dir returns the directory images too large to inline are saved under.
+func (s *ImageStore) Dir() string {
+	return s.dir
+}
+
+// Resolve returns a stable reference for the image at src. key identifies
+// the image across requests, typically its block ID, since Notion signs a
+// new URL each time; if empty, src without its query string is used.
+func (s *ImageStore) Resolve(src, key string) (string, error) {
+	if key == "" {
+		u, err := url.Parse(src)
+		if err != nil {
+			return "", fmt.Errorf("parse image URL: %w", err)
+		}
+		u.RawQuery = ""
+		key = u.String()
+	}
+	sum := sha256.Sum256([]byte(key))
+	name := hex.EncodeToString(sum[:16])
+
+	// An image saved earlier stays valid even though src has changed
+	if matches, _ := filepath.Glob(filepath.Join(s.dir, name+".*")); len(matches) > 0 {
+		return matches[0], nil
+	}
+
+	data, contentType, err := s.download(src)
+	if err != nil {
+		return "", err
+	}
+	if len(data) <= s.inlineMax {
+		return "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data), nil
+	}
+
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return "", fmt.Errorf("create image directory: %w", err)
+	}
+	file := filepath.Join(s.dir, name+imageExtension(src, contentType))
+	if err := os.WriteFile(file, data, 0o644); err != nil {
+		return "", fmt.Errorf("save image: %w", err)
+	}
+	return file, nil
+}
+
+// download fetches src and returns its body and content type.
+func (s *ImageStore) download(src string) ([]byte, string, error) {
+	resp, err := s.httpClient.Get(src)
+	if err != nil {
+		return nil, "", fmt.Errorf("download image: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download image: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("download image: %w", err)
+	}
+	if len(data) > maxImageSize {
+		return nil, "", fmt.Errorf("image larger than %d bytes", maxImageSize)
+	}
+
+	contentType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(contentType, "image/") {
+		contentType = http.DetectContentType(data)
+	}
+	return data, contentType, nil
+}
+
+// imageExtension picks a file extension from the URL path, falling back to
+// the content type.
+func imageExtension(src, contentType string) string {
+	if u, err := url.Parse(src); err == nil {
+		if ext := path.Ext(u.Path); ext != "" && len(ext) <= 5 {
+			return strings.ToLower(ext)
+		}
+	}
+	if exts, _ := mime.ExtensionsByType(contentType); len(exts) > 0 {
+		return exts[0]
+	}
+	return ".img"
+}
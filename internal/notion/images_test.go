@@ -0,0 +1,87 @@
+package notion
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestImageStore(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 64)...)
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/small.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(png[:16])
+		case "/large.png", "/photo":
+			w.Write(png)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	store := NewImageStore(dir, 32)
+
+	imageBlock := func(id, source, url string) Block {
+		return Block{
+			ID:      id,
+			Type:    BlockTypeImage,
+			Content: map[string]any{"type": source, source: map[string]any{"url": url}},
+		}
+	}
+	render := func(block Block) string {
+		converter := NewMarkdownConverter(&PageContent{}, WithImageStore(store))
+		converter.RenderImage(block)
+		return converter.Buf.String()
+	}
+
+	t.Run("Small image is inlined", func(t *testing.T) {
+		got := render(imageBlock("b1", "file", srv.URL+"/small.png?X-Amz-Signature=one"))
+		if !strings.HasPrefix(got, "![](data:image/png;base64,") {
+			t.Errorf("RenderImage() = %q, want a data URI", got)
+		}
+	})
+
+	t.Run("Large image is saved under a stable local path", func(t *testing.T) {
+		first := render(imageBlock("b2", "file", srv.URL+"/large.png?X-Amz-Signature=one"))
+		if !strings.HasPrefix(first, "![]("+dir) || !strings.HasSuffix(first, ".png)\n\n") {
+			t.Fatalf("RenderImage() = %q, want a local .png path under %s", first, dir)
+		}
+		path := strings.TrimSuffix(strings.TrimPrefix(first, "![]("), ")\n\n")
+		if data, err := os.ReadFile(path); err != nil || !bytes.Equal(data, png) {
+			t.Errorf("saved image = %d bytes, %v; want the downloaded image", len(data), err)
+		}
+
+		// Notion signs a new URL on every fetch; the block ID keeps the reference stable
+		before := requests.Load()
+		second := render(imageBlock("b2", "file", srv.URL+"/large.png?X-Amz-Signature=two"))
+		if second != first {
+			t.Errorf("re-rendered image = %q, want %q", second, first)
+		}
+		if requests.Load() != before {
+			t.Error("a saved image should not be downloaded again")
+		}
+	})
+
+	t.Run("External image without extension", func(t *testing.T) {
+		got := render(imageBlock("b3", "external", srv.URL+"/photo"))
+		if !strings.HasPrefix(got, "![]("+dir) || !strings.HasSuffix(got, ".png)\n\n") {
+			t.Errorf("RenderImage() = %q, want a local .png path", got)
+		}
+	})
+
+	t.Run("Failed download keeps the URL", func(t *testing.T) {
+		url := srv.URL + "/missing.png"
+		if got, want := render(imageBlock("b4", "external", url)), "![]("+url+")\n\n"; got != want {
+			t.Errorf("RenderImage() = %q, want %q", got, want)
+		}
+	})
+}
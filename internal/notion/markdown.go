@@ -2,22 +2,108 @@ package notion
 
 import (
 	"bytes"
+	"cmp"
 	"fmt"
+	"log/slog"
+	"path"
 	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 // MarkdownConverter converts a Page to Markdown.
 type MarkdownConverter struct {
 	Page *PageContent
 	Buf  *bytes.Buffer
+	MarkdownOptions
+}
+
+// MarkdownOptions controls how a MarkdownConverter renders a page. The zero
+// value renders plain Markdown.
+type MarkdownOptions struct {
+	// Images, if set, replaces image URLs with stable local references
+	Images *ImageStore
+	// ColumnSeparator is written between the columns of a column layout
+	ColumnSeparator string
+	// Colors wraps colored headings in HTML spans
+	Colors bool
+	// FrontMatter prepends the page's properties as YAML front matter
+	FrontMatter bool
+	// RelationTitles holds the titles of related pages by relation
+	// property name, which front matter shows in place of page IDs
+	RelationTitles map[string][]string
+	// ToggleHTML renders toggle blocks as HTML details elements, which
+	// fold, instead of their text followed by their nested blocks
+	ToggleHTML bool
+}
+
+// MarkdownOption configures a MarkdownConverter.
+type MarkdownOption func(*MarkdownConverter)
+
+// WithImageStore downloads images through store while converting.
+func WithImageStore(store *ImageStore) MarkdownOption {
+	return func(c *MarkdownConverter) {
+		c.Images = store
+	}
+}
+
+// WithColumnSeparator writes sep, e.g. "---", between flattened columns.
+func WithColumnSeparator(sep string) MarkdownOption {
+	return func(c *MarkdownConverter) {
+		c.ColumnSeparator = sep
+	}
+}
+
+// WithColors keeps heading colors as HTML spans, which Markdown has no
+// syntax for.
+func WithColors() MarkdownOption {
+	return func(c *MarkdownConverter) {
+		c.Colors = true
+	}
+}
+
+// WithFrontMatter starts the Markdown with the page's non-empty properties
+// and last edited time as YAML front matter.
+func WithFrontMatter() MarkdownOption {
+	return func(c *MarkdownConverter) {
+		c.FrontMatter = true
+	}
+}
+
+// WithRelationTitles shows titles, such as a RelationResolver's PageTitles,
+// in front matter in place of the page IDs of relation properties.
+func WithRelationTitles(titles map[string][]string) MarkdownOption {
+	return func(c *MarkdownConverter) {
+		c.RelationTitles = titles
+	}
+}
+
+// WithToggleHTML renders toggle blocks as HTML details elements, so
+// viewers that allow HTML can fold them.
+func WithToggleHTML() MarkdownOption {
+	return func(c *MarkdownConverter) {
+		c.ToggleHTML = true
+	}
+}
+
+// WithMarkdownOptions replaces all of a converter's options with opts.
+func WithMarkdownOptions(opts MarkdownOptions) MarkdownOption {
+	return func(c *MarkdownConverter) {
+		c.MarkdownOptions = opts
+	}
 }
 
 // NewMarkdownConverter creates a new Markdown converter.
-func NewMarkdownConverter(pageContent *PageContent) *MarkdownConverter {
-	return &MarkdownConverter{
+func NewMarkdownConverter(pageContent *PageContent, opts ...MarkdownOption) *MarkdownConverter {
+	c := &MarkdownConverter{
 		Page: pageContent,
 		Buf:  &bytes.Buffer{},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
 }
 
 // WriteString writes a string to the buffer.
@@ -47,6 +133,10 @@ func (c *MarkdownConverter) RenderRichText(richTexts []RichText) string {
 		if text == "" {
 			text = rt.Text.Content
 		}
+		var link string
+		if rt.Type == "mention" && rt.Mention != nil {
+			text, link = mentionText(*rt.Mention, text)
+		}
 
 		// Apply formatting based on annotations
 		if rt.Annotations.Bold {
@@ -63,7 +153,9 @@ func (c *MarkdownConverter) RenderRichText(richTexts []RichText) string {
 		}
 
 		// Handle links
-		if rt.Text.Link != nil && rt.Text.Link.URL != "" {
+		if link != "" {
+			text = fmt.Sprintf("[%s](%s)", text, link)
+		} else if rt.Text.Link != nil && rt.Text.Link.URL != "" {
 			text = fmt.Sprintf("[%s](%s)", text, rt.Text.Link.URL)
 		} else if rt.Href != nil && *rt.Href != "" {
 			text = fmt.Sprintf("[%s](%s)", text, *rt.Href)
@@ -74,16 +166,36 @@ func (c *MarkdownConverter) RenderRichText(richTexts []RichText) string {
 	return sb.String()
 }
 
+// mentionText returns the text of a mention and, for a page or database,
+// a notion:// link to it. Mentions missing their details keep plainText.
+func mentionText(m Mention, plainText string) (string, string) {
+	switch m.Type {
+	case "page", "database":
+		ref := m.Page
+		if m.Type == "database" {
+			ref = m.Database
+		}
+		if ref != nil && ref.ID != "" {
+			return cmp.Or(plainText, "Untitled"), "notion://" + m.Type + "/" + ref.ID
+		}
+	case "user":
+		if m.User != nil && m.User.Name != "" {
+			return "@" + m.User.Name, ""
+		}
+	case "date":
+		if m.Date != nil && m.Date.Start != "" {
+			if m.Date.End != "" {
+				return m.Date.Start + " → " + m.Date.End, ""
+			}
+			return m.Date.Start, ""
+		}
+	}
+	return plainText, ""
+}
+
 // RenderParagraph renders a paragraph block.
 func (c *MarkdownConverter) RenderParagraph(block Block) {
-	var richTexts []RichText
-	// Try to get from Paragraph field first
-	if block.Paragraph != nil {
-		richTexts = block.Paragraph.RichText
-	} else {
-		// Fallback to Content field
-		richTexts = c.extractRichTexts(block.Content)
-	}
+	richTexts := blockRichText(block)
 	if len(richTexts) == 0 {
 		return
 	}
@@ -93,18 +205,41 @@ func (c *MarkdownConverter) RenderParagraph(block Block) {
 }
 
 // RenderHeading renders a heading block.
+//
+// A toggleable heading is written as a plain heading followed by its nested
+// blocks, as Markdown cannot fold content.
 func (c *MarkdownConverter) RenderHeading(block Block, level int) {
-	richTexts := c.extractRichTexts(block.Content)
-	if len(richTexts) == 0 {
-		return
+	content := headingContent(block)
+	text := strings.TrimSpace(c.RenderRichText(c.extractRichTexts(content)))
+	if text != "" {
+		fields, _ := content.(map[string]any)
+		if color := getMapString(fields, "color"); c.Colors && color != "" && color != "default" {
+			text = colorSpan(text, color)
+		}
+		c.WriteString(strings.Repeat("#", level) + " " + text)
+		c.Newline()
 	}
-	text := c.RenderRichText(richTexts)
-	if text == "" {
-		return
+	c.renderBlocks(block.Children)
+}
+
+// headingContent returns the type-specific content of a heading block,
+// which may also be nested under the block's type key, e.g. "heading_1".
+func headingContent(block Block) any {
+	if fields, ok := block.Content.(map[string]any); ok {
+		if nested, ok := fields[string(block.Type)].(map[string]any); ok {
+			return nested
+		}
 	}
-	prefix := strings.Repeat("#", level) + " "
-	c.WriteString(prefix + strings.TrimSpace(text))
-	c.Newline()
+	return block.Content
+}
+
+// colorSpan wraps text in a span styled with a Notion color such as "red"
+// or "blue_background".
+func colorSpan(text, color string) string {
+	if name, ok := strings.CutSuffix(color, "_background"); ok {
+		return fmt.Sprintf(`<span style="background-color: %s">%s</span>`, name, text)
+	}
+	return fmt.Sprintf(`<span style="color: %s">%s</span>`, color, text)
 }
 
 // RenderBulletedList renders a bulleted list item.
@@ -119,32 +254,79 @@ func (c *MarkdownConverter) RenderBulletedList(block Block) {
 	}
 	c.WriteString("- " + text)
 	c.Eol()
+	c.writeItemChildren(block.Children, 2)
 }
 
 // RenderNumberedList renders a numbered list item.
 func (c *MarkdownConverter) RenderNumberedList(block Block, index int) {
+	c.renderNumberedItem(block, index)
+}
+
+// renderNumberedItem renders a numbered list item, reporting whether it
+// wrote anything.
+func (c *MarkdownConverter) renderNumberedItem(block Block, index int) bool {
 	richTexts := c.extractRichTexts(block.Content)
 	if len(richTexts) == 0 {
-		return
+		return false
 	}
 	text := c.RenderRichText(richTexts)
 	if text == "" {
-		return
+		return false
 	}
-	c.WriteString(fmt.Sprintf("%d. %s", index, text))
+	marker := fmt.Sprintf("%d. ", index)
+	c.WriteString(marker + text)
 	c.Eol()
+	c.writeItemChildren(block.Children, len(marker))
+	return true
+}
+
+// RenderToggle renders a toggle block as its text followed by its nested
+// blocks, as Markdown cannot fold content. With ToggleHTML it is written as
+// an HTML details element instead.
+func (c *MarkdownConverter) RenderToggle(block Block) {
+	if c.ToggleHTML {
+		c.WriteString("<details>\n<summary>" + c.RenderRichText(c.extractRichTexts(block.Content)) + "</summary>")
+		c.Newline()
+		if children := c.renderNested(block.Children); children != "" {
+			c.WriteString(children)
+			c.Newline()
+		}
+		c.WriteString("</details>")
+		c.Newline()
+		return
+	}
+	if text := c.RenderRichText(c.extractRichTexts(block.Content)); text != "" {
+		c.WriteString(text)
+		c.Newline()
+	}
+	c.renderBlocks(block.Children)
+}
+
+// writeItemChildren writes the nested blocks of a list item indented by
+// width spaces, so that they continue the item. Nested blocks other than a
+// list are set off by a blank line, or they would join the item's text.
+func (c *MarkdownConverter) writeItemChildren(children []Block, width int) {
+	nested := c.renderNested(children)
+	if nested == "" {
+		return
+	}
+	if !isListItem(children[0]) {
+		c.WriteString("\n")
+	}
+	indent := strings.Repeat(" ", width)
+	for _, line := range strings.Split(nested, "\n") {
+		if line != "" {
+			c.WriteString(indent + line)
+		}
+		c.WriteString("\n")
+	}
 }
 
 // RenderCode renders a code block.
 func (c *MarkdownConverter) RenderCode(block Block) {
-	codeBlock, ok := block.Content.(CodeBlock)
+	codeBlock, ok := ParseCodeBlock(block)
 	if !ok {
-		// Try to parse from map
-		if contentMap, ok := block.Content.(map[string]any); ok {
-			codeBlock = c.parseCodeBlockFromMap(contentMap)
-		} else {
-			return
-		}
+		return
 	}
 
 	// Extract code text: prefer RichText (Notion API field), fallback to Code
@@ -157,27 +339,57 @@ func (c *MarkdownConverter) RenderCode(block Block) {
 		codeText.WriteString(rt.PlainText)
 	}
 
-	language := codeBlock.Language
-	if language == "" {
-		language = "text"
-	}
+	language := fenceLanguage(codeBlock.Language)
 
 	c.WriteString("```" + language)
 	c.Eol()
 	c.WriteString(codeText.String())
 	c.Eol()
 	c.WriteString("```")
+	// The caption goes on the line after the fence, in italics
+	if caption := strings.TrimSpace(c.RenderRichText(codeBlock.Caption)); caption != "" {
+		c.Eol()
+		c.WriteString("_" + caption + "_")
+	}
 	c.Newline()
 }
 
-// RenderQuote renders a quote block.
-func (c *MarkdownConverter) RenderQuote(block Block) {
-	richTexts := c.extractRichTexts(block.Content)
-	if len(richTexts) == 0 {
-		return
+// fenceLanguages maps Notion code block languages to the identifiers
+// Markdown highlighters expect after a code fence.
+var fenceLanguages = map[string]string{
+	"plain text":    "text",
+	"c++":           "cpp",
+	"c#":            "csharp",
+	"f#":            "fsharp",
+	"shell":         "bash",
+	"objective-c":   "objectivec",
+	"vb.net":        "vbnet",
+	"visual basic":  "vb",
+	"docker":        "dockerfile",
+	"webassembly":   "wasm",
+	"java/c/c++/c#": "java",
+}
+
+// fenceLanguage returns the code fence identifier for a Notion language.
+// Unknown languages pass through without spaces, which would end the
+// identifier; an empty language becomes "text".
+func fenceLanguage(language string) string {
+	language = strings.TrimSpace(language)
+	if fence, ok := fenceLanguages[strings.ToLower(language)]; ok {
+		return fence
 	}
-	text := c.RenderRichText(richTexts)
-	if text == "" {
+	if language == "" {
+		return "text"
+	}
+	return strings.ReplaceAll(language, " ", "")
+}
+
+// RenderQuote renders a quote block. Its nested blocks are rendered inside
+// the blockquote, so multi-paragraph quotes and lists in quotes stay quoted.
+func (c *MarkdownConverter) RenderQuote(block Block) {
+	text := c.RenderRichText(c.extractRichTexts(block.Content))
+	children := c.renderNested(block.Children)
+	if text == "" && children == "" {
 		return
 	}
 	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
@@ -187,9 +399,37 @@ func (c *MarkdownConverter) RenderQuote(block Block) {
 			c.Eol()
 		}
 	}
+	c.writeQuoted(children, text != "")
 	c.Newline()
 }
 
+// writeQuoted writes nested Markdown as blockquote lines, separated from
+// the quote's own text by an empty quote line if there is any.
+func (c *MarkdownConverter) writeQuoted(nested string, separate bool) {
+	if nested == "" {
+		return
+	}
+	if separate {
+		c.WriteString(">\n")
+	}
+	for _, line := range strings.Split(nested, "\n") {
+		c.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+	}
+}
+
+// renderNested renders blocks with the converter's options into a separate
+// buffer and returns the trimmed Markdown, for containers that prefix
+// every line of their content.
+func (c *MarkdownConverter) renderNested(blocks []Block) string {
+	if len(blocks) == 0 {
+		return ""
+	}
+	nested := *c
+	nested.Buf = &bytes.Buffer{}
+	nested.renderBlocks(blocks)
+	return strings.TrimSpace(nested.Buf.String())
+}
+
 // RenderDivider renders a divider block.
 func (c *MarkdownConverter) RenderDivider(block Block) {
 	c.WriteString("---")
@@ -216,124 +456,131 @@ func (c *MarkdownConverter) RenderToDo(block Block) {
 		c.WriteString("- [ ] " + text)
 	}
 	c.Eol()
+	c.writeItemChildren(block.Children, 2)
 }
 
-// RenderCallout renders a callout block.
+// RenderCallout renders a callout block as a blockquote led by its icon,
+// with its nested blocks inside the quote.
 func (c *MarkdownConverter) RenderCallout(block Block) {
-	richTexts := c.extractRichTexts(block.Content)
-	if len(richTexts) == 0 {
+	text := c.RenderRichText(c.extractRichTexts(block.Content))
+	children := c.renderNested(block.Children)
+	if text == "" && children == "" {
 		return
 	}
-	text := c.RenderRichText(richTexts)
-	if text == "" {
-		return
+	c.WriteString(strings.TrimRight("> "+calloutIcon(block)+" "+text, " "))
+	if children != "" {
+		c.Eol()
+		c.writeQuoted(children, true)
 	}
-	c.WriteString("> 💡 " + text)
 	c.Newline()
 }
 
-// RenderImage renders an image block.
+// calloutIcon returns a callout's icon: its emoji, an image for an
+// uploaded, external or custom emoji icon, or 💡 if it has none.
+func calloutIcon(block Block) string {
+	contentMap, _ := block.Content.(map[string]any)
+	icon, _ := contentMap["icon"].(map[string]any)
+	switch iconType := getMapString(icon, "type"); iconType {
+	case "emoji":
+		if emoji := getMapString(icon, "emoji"); emoji != "" {
+			return emoji
+		}
+	case "external", "file", "custom_emoji":
+		fields, _ := icon[iconType].(map[string]any)
+		if url := getMapString(fields, "url"); url != "" {
+			return fmt.Sprintf("![%s](%s)", getMapString(fields, "name"), url)
+		}
+	}
+	return "💡"
+}
+
+// RenderImage renders an image block. Both Notion-hosted ("file") and
+// external images are supported.
 func (c *MarkdownConverter) RenderImage(block Block) {
-	// Extract image URL from content
+	url, ok := FileURL(block)
+	if !ok {
+		return
+	}
+	if c.Images != nil {
+		if local, err := c.Images.Resolve(url, block.ID); err == nil {
+			url = local
+		} else {
+			slog.Warn("failed to download image, keeping its URL", "block_id", block.ID, "error", err.Error())
+		}
+	}
+	caption := ""
 	if contentMap, ok := block.Content.(map[string]any); ok {
-		if file, ok := contentMap["file"].(map[string]any); ok {
-			if url, ok := file["url"].(string); ok {
-				caption := ""
-				if captionArr, ok := contentMap["caption"].([]any); ok && len(captionArr) > 0 {
-					if captionMap, ok := captionArr[0].(map[string]any); ok {
-						if plainText, ok := captionMap["plain_text"].(string); ok {
-							caption = plainText
-						}
-					}
+		if captionArr, ok := contentMap["caption"].([]any); ok && len(captionArr) > 0 {
+			if captionMap, ok := captionArr[0].(map[string]any); ok {
+				if plainText, ok := captionMap["plain_text"].(string); ok {
+					caption = plainText
 				}
-				if caption != "" {
-					c.WriteString(fmt.Sprintf("![%s](%s)", caption, url))
-				} else {
-					c.WriteString(fmt.Sprintf("![](%s)", url))
-				}
-				c.Newline()
 			}
 		}
 	}
+	if caption != "" {
+		c.WriteString(fmt.Sprintf("![%s](%s)", caption, url))
+	} else {
+		c.WriteString(fmt.Sprintf("![](%s)", url))
+	}
+	c.Newline()
 }
 
-// extractRichTexts extracts rich text array from block content.
-func (c *MarkdownConverter) extractRichTexts(content any) []RichText {
-	switch v := content.(type) {
-	case []RichText:
-		return v
-	case Paragraph:
-		return v.RichText
-	case *Paragraph:
-		if v != nil {
-			return v.RichText
-		}
-	case map[string]any:
-		if rt, ok := v["rich_text"].([]any); ok {
-			var richTexts []RichText
-			for _, r := range rt {
-				if m, ok := r.(map[string]any); ok {
-					rt := RichText{
-						Type:      getMapString(m, "type"),
-						PlainText: getMapString(m, "plain_text"),
-					}
-					// Parse text object
-					if textMap, ok := m["text"].(map[string]any); ok {
-						rt.Text = Text{
-							Content: getMapString(textMap, "content"),
-						}
-						if linkMap, ok := textMap["link"].(map[string]any); ok {
-							if url, ok := linkMap["url"].(string); ok {
-								rt.Text.Link = &Link{URL: url}
-							}
-						}
-					}
-					// Parse annotations
-					if ann, ok := m["annotations"].(map[string]any); ok {
-						rt.Annotations = Annotations{
-							Bold:          getMapBool(ann, "bold"),
-							Italic:        getMapBool(ann, "italic"),
-							Strikethrough: getMapBool(ann, "strikethrough"),
-							Underline:     getMapBool(ann, "underline"),
-							Code:          getMapBool(ann, "code"),
-						}
-					}
-					// Parse href
-					if href, ok := m["href"].(string); ok && href != "" {
-						rt.Href = &href
-					}
-					richTexts = append(richTexts, rt)
-				}
-			}
-			return richTexts
+// RenderFile renders a file or PDF block as a link, labelled with its
+// caption, else its file name, else the last element of its URL path.
+func (c *MarkdownConverter) RenderFile(block Block) {
+	url, ok := FileURL(block)
+	if !ok {
+		return
+	}
+	var label string
+	if contentMap, ok := block.Content.(map[string]any); ok {
+		label = strings.TrimSpace(c.RenderRichText(parseRichTextList(contentMap["caption"])))
+		if label == "" {
+			label, _ = contentMap["name"].(string)
 		}
 	}
-	return nil
+	if label == "" {
+		label = path.Base(strings.SplitN(url, "?", 2)[0])
+	}
+	c.WriteString(fmt.Sprintf("[%s](%s)", label, url))
+	c.Newline()
 }
 
-// parseCodeBlockFromMap parses CodeBlock from map.
-func (c *MarkdownConverter) parseCodeBlockFromMap(contentMap map[string]any) CodeBlock {
-	codeBlock := CodeBlock{
-		Language: getMapString(contentMap, "language"),
+// RenderColumnList flattens a column layout, rendering each column's
+// children in order. Empty columns are skipped.
+func (c *MarkdownConverter) RenderColumnList(block Block) {
+	first := true
+	for _, column := range block.Children {
+		if len(column.Children) == 0 {
+			continue
+		}
+		if !first && c.ColumnSeparator != "" {
+			c.WriteString(c.ColumnSeparator)
+			c.Newline()
+		}
+		c.renderBlocks(column.Children)
+		first = false
 	}
+}
 
-	if rt, ok := contentMap["rich_text"].([]any); ok {
-		for _, r := range rt {
-			if m, ok := r.(map[string]any); ok {
-				rt := RichText{
-					PlainText: getMapString(m, "plain_text"),
-				}
-				if textMap, ok := m["text"].(map[string]any); ok {
-					rt.Text = Text{
-						Content: getMapString(textMap, "content"),
-					}
-				}
-				codeBlock.Code = append(codeBlock.Code, rt)
-			}
+// RenderChildReference renders a child page or database as a link to it
+// on Notion; its contents are not inlined.
+func (c *MarkdownConverter) RenderChildReference(block Block) {
+	title := "Untitled"
+	if contentMap, ok := block.Content.(map[string]any); ok {
+		if t := getMapString(contentMap, "title"); t != "" {
+			title = t
 		}
 	}
+	url := "https://www.notion.so/" + strings.ReplaceAll(block.ID, "-", "")
+	c.WriteString(fmt.Sprintf("[%s](%s)", title, url))
+	c.Newline()
+}
 
-	return codeBlock
+// extractRichTexts extracts rich text array from block content.
+func (c *MarkdownConverter) extractRichTexts(content any) []RichText {
+	return richTextOf(content)
 }
 
 // getMapBool gets a bool value from a map.
@@ -370,8 +617,18 @@ func (c *MarkdownConverter) RenderBlock(block Block, numberedListIndex *int) {
 		c.RenderToDo(block)
 	case BlockTypeCallout:
 		c.RenderCallout(block)
+	case BlockTypeToggle:
+		c.RenderToggle(block)
 	case BlockTypeImage:
 		c.RenderImage(block)
+	case BlockTypeFile, BlockTypePDF:
+		c.RenderFile(block)
+	case BlockTypeColumnList:
+		c.RenderColumnList(block)
+	case BlockTypeColumn, BlockTypeSyncedBlock:
+		c.renderBlocks(block.Children)
+	case BlockTypeChildPage, BlockTypeChildDatabase:
+		c.RenderChildReference(block)
 	default:
 		// For unknown types, try to extract text
 		richTexts := c.extractRichTexts(block.Content)
@@ -394,35 +651,120 @@ func (c *MarkdownConverter) ToMarkdown() string {
 		c.Buf = &bytes.Buffer{}
 	}
 
-	// Render all blocks
-	var numberedListIndex int
-	var inNumberedList bool
-	for _, block := range c.Page.Blocks {
-		if block.Type == BlockTypeNumberedListItem {
-			if !inNumberedList {
-				numberedListIndex = 1
-				inNumberedList = true
-			} else {
-				numberedListIndex++
-			}
-			c.RenderNumberedList(block, numberedListIndex)
-		} else {
-			if inNumberedList {
-				inNumberedList = false
-				numberedListIndex = 0
-			}
-			c.RenderBlock(block, nil)
-		}
-	}
+	c.renderBlocks(c.Page.Blocks)
 
 	result := c.Buf.String()
 	// Trim trailing whitespace
 	result = strings.TrimSpace(result)
+	if c.FrontMatter {
+		if fm := frontMatter(c.Page.Page, c.RelationTitles); fm != "" {
+			result = fm + "\n" + result
+		}
+	}
 	return result
 }
 
+// FrontMatter returns a page's non-empty properties and last edited time as
+// a YAML front matter block, or "" if there are none. Dates keep Notion's
+// formatting, relations list page IDs and properties of unsupported types
+// are left out.
+func FrontMatter(page Page) string {
+	return frontMatter(page, nil)
+}
+
+// frontMatter is FrontMatter with relations listing the related pages'
+// titles where relationTitles has them.
+func frontMatter(page Page, relationTitles map[string][]string) string {
+	values := make(map[string]any)
+	for name, prop := range page.Properties {
+		if titles, ok := relationTitles[name]; ok && prop.Type == PropertyTypeRelation {
+			values[name] = titles
+			continue
+		}
+		v, err := PropertyValue(prop)
+		if err != nil {
+			continue
+		}
+		switch v := v.(type) {
+		case nil:
+			continue
+		case string:
+			if v == "" {
+				continue
+			}
+		case []string:
+			if len(v) == 0 {
+				continue
+			}
+		case time.Time:
+			values[name] = PropertyText(prop)
+			continue
+		}
+		values[name] = v
+	}
+	if _, ok := values["last_edited_time"]; !ok && !page.LastEditedTime.IsZero() {
+		values["last_edited_time"] = page.LastEditedTime.UTC().Format(time.RFC3339)
+	}
+	if len(values) == 0 {
+		return ""
+	}
+	data, err := yaml.Marshal(values)
+	if err != nil {
+		return ""
+	}
+	return "---\n" + string(data) + "---\n"
+}
+
+// renderBlocks renders a sequence of sibling blocks.
+//
+// Numbering follows Notion: consecutive numbered list items form one list,
+// numbered from 1, however many blocks are nested under each item, as those
+// render inside the item. Any other sibling, a bulleted list item included,
+// ends the list, and the next numbered item starts again at 1. Nested lists
+// are numbered on their own, and an item that renders nothing takes no
+// number. A blank line separates a list from the block after it, which
+// would otherwise continue the list's last item.
+func (c *MarkdownConverter) renderBlocks(blocks []Block) {
+	index := 0
+	for i, block := range blocks {
+		if block.Type == BlockTypeNumberedListItem {
+			if c.renderNumberedItem(block, index+1) {
+				index++
+			}
+			continue
+		}
+		index = 0
+		if i > 0 && isListItem(blocks[i-1]) && !isListItem(block) {
+			c.endList()
+		}
+		c.RenderBlock(block, nil)
+	}
+}
+
+// isListItem reports whether block renders as a Markdown list item.
+func isListItem(block Block) bool {
+	switch block.Type {
+	case BlockTypeBulletedListItem, BlockTypeNumberedListItem, BlockTypeToDo:
+		return true
+	}
+	return false
+}
+
+// endList ends a list with a blank line, unless one was already written.
+func (c *MarkdownConverter) endList() {
+	d := c.Buf.Bytes()
+	if n := len(d); n > 0 && d[n-1] == '\n' && (n < 2 || d[n-2] != '\n') {
+		c.Buf.WriteByte('\n')
+	}
+}
+
 // PageToMarkdown converts a PageContent to Markdown string.
-func PageToMarkdown(pageContent *PageContent) string {
-	converter := NewMarkdownConverter(pageContent)
+func PageToMarkdown(pageContent *PageContent, opts ...MarkdownOption) string {
+	converter := NewMarkdownConverter(pageContent, opts...)
 	return converter.ToMarkdown()
 }
+
+// PageToMarkdownWithOptions converts a page to Markdown as opts describes.
+func PageToMarkdownWithOptions(pageContent *PageContent, opts MarkdownOptions) string {
+	return PageToMarkdown(pageContent, WithMarkdownOptions(opts))
+}
@@ -1,6 +1,8 @@
 package notion
 
 import (
+	"encoding/json"
+	"strings"
 	"testing"
 )
 
@@ -164,6 +166,65 @@ func TestMarkdownConverter_RenderRichText(t *testing.T) {
 	}
 }
 
+func TestMarkdownConverter_RenderMention(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		expected string
+	}{
+		{
+			name:     "page",
+			json:     `{"type":"mention","mention":{"type":"page","page":{"id":"abc-123"}},"plain_text":"Roadmap","href":"https://www.notion.so/abc123"}`,
+			expected: "[Roadmap](notion://page/abc-123)",
+		},
+		{
+			name:     "date",
+			json:     `{"type":"mention","mention":{"type":"date","date":{"start":"2024-05-01","end":null}},"plain_text":"May 1, 2024"}`,
+			expected: "2024-05-01",
+		},
+		{
+			name:     "date range",
+			json:     `{"type":"mention","mention":{"type":"date","date":{"start":"2024-05-01","end":"2024-05-03"}},"plain_text":"May 1, 2024 → May 3, 2024"}`,
+			expected: "2024-05-01 → 2024-05-03",
+		},
+		{
+			name:     "user",
+			json:     `{"type":"mention","mention":{"type":"user","user":{"object":"user","id":"u1","name":"Ada"}},"plain_text":"@Ada Lovelace"}`,
+			expected: "@Ada",
+		},
+		{
+			name:     "user without name",
+			json:     `{"type":"mention","mention":{"type":"user","user":{"object":"user","id":"u1"}},"plain_text":"@Anonymous"}`,
+			expected: "@Anonymous",
+		},
+		{
+			name:     "bold page",
+			json:     `{"type":"mention","mention":{"type":"page","page":{"id":"abc-123"}},"plain_text":"Roadmap","annotations":{"bold":true}}`,
+			expected: "[**Roadmap**](notion://page/abc-123)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var item map[string]any
+			if err := json.Unmarshal([]byte(tt.json), &item); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			var typed RichText
+			if err := json.Unmarshal([]byte(tt.json), &typed); err != nil {
+				t.Fatalf("unmarshal RichText: %v", err)
+			}
+			converter := NewMarkdownConverter(&PageContent{})
+			if got := converter.RenderRichText(parseRichTextList([]any{item})); got != tt.expected {
+				t.Errorf("RenderRichText() = %q, want %q", got, tt.expected)
+			}
+			if got := converter.RenderRichText([]RichText{typed}); got != tt.expected {
+				t.Errorf("RenderRichText() of decoded run = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestMarkdownConverter_RenderParagraph(t *testing.T) {
 	converter := NewMarkdownConverter(&PageContent{})
 
@@ -183,6 +244,44 @@ func TestMarkdownConverter_RenderParagraph(t *testing.T) {
 	}
 }
 
+func TestMarkdownConverter_RenderParagraphShapes(t *testing.T) {
+	typed := []RichText{
+		{Type: "text", PlainText: "Use ", Text: Text{Content: "Use "}},
+		{Type: "text", PlainText: "bold", Text: Text{Content: "bold"}, Annotations: Annotations{Bold: true}},
+	}
+	raw := `{"rich_text": [
+		{"type": "text", "plain_text": "Use ", "text": {"content": "Use "}},
+		{"type": "text", "plain_text": "bold", "text": {"content": "bold"}, "annotations": {"bold": true}}
+	]}`
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(raw), &decoded); err != nil {
+		t.Fatalf("decode fixture: %v", err)
+	}
+
+	blocks := map[string]Block{
+		"typed content":   {Type: BlockTypeParagraph, Content: Paragraph{RichText: typed}},
+		"paragraph field": {Type: BlockTypeParagraph, Paragraph: &Paragraph{RichText: typed}},
+		"map":             {Type: BlockTypeParagraph, Content: decoded},
+		"raw JSON":        {Type: BlockTypeParagraph, Content: json.RawMessage(raw)},
+		"bulleted map":    {Type: BlockTypeBulletedListItem, Content: decoded},
+	}
+
+	for name, block := range blocks {
+		t.Run(name, func(t *testing.T) {
+			want := "Use **bold**"
+			if block.Type == BlockTypeBulletedListItem {
+				want = "- " + want
+			}
+			if got := PageToMarkdown(&PageContent{Blocks: []Block{block}}); got != want {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, want)
+			}
+			if got := ExtractText([]Block{block}); !strings.HasSuffix(got, "Use bold") {
+				t.Errorf("ExtractText() = %q, want it to end with %q", got, "Use bold")
+			}
+		})
+	}
+}
+
 func TestMarkdownConverter_RenderHeading(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -214,6 +313,66 @@ func TestMarkdownConverter_RenderHeading(t *testing.T) {
 	}
 }
 
+func TestMarkdownConverter_RenderToggleHeading(t *testing.T) {
+	heading := func(fields map[string]any) map[string]any {
+		fields["rich_text"] = []any{map[string]any{"plain_text": "Details"}}
+		return fields
+	}
+	paragraph := Block{Type: BlockTypeParagraph, Content: Paragraph{RichText: []RichText{{PlainText: "Hidden text"}}}}
+
+	tests := []struct {
+		name     string
+		block    Block
+		opts     []MarkdownOption
+		expected string
+	}{
+		{
+			name: "toggleable heading renders its children",
+			block: Block{
+				Type:        BlockTypeHeading2,
+				HasChildren: true,
+				Content:     heading(map[string]any{"is_toggleable": true, "color": "default"}),
+				Children:    []Block{paragraph},
+			},
+			expected: "## Details\n\nHidden text",
+		},
+		{
+			name: "content keyed by block type",
+			block: Block{
+				Type:    BlockTypeHeading2,
+				Content: map[string]any{"heading_2": heading(map[string]any{"is_toggleable": false})},
+			},
+			expected: "## Details",
+		},
+		{
+			name:     "color ignored by default",
+			block:    Block{Type: BlockTypeHeading2, Content: heading(map[string]any{"color": "red"})},
+			expected: "## Details",
+		},
+		{
+			name:     "color kept as span",
+			block:    Block{Type: BlockTypeHeading2, Content: heading(map[string]any{"color": "red"})},
+			opts:     []MarkdownOption{WithColors()},
+			expected: `## <span style="color: red">Details</span>`,
+		},
+		{
+			name:     "background color",
+			block:    Block{Type: BlockTypeHeading3, Content: heading(map[string]any{"color": "yellow_background"})},
+			opts:     []MarkdownOption{WithColors()},
+			expected: `### <span style="background-color: yellow">Details</span>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PageToMarkdown(&PageContent{Blocks: []Block{tt.block}}, tt.opts...)
+			if got != tt.expected {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestMarkdownConverter_RenderBulletedList(t *testing.T) {
 	converter := NewMarkdownConverter(&PageContent{})
 	block := Block{
@@ -293,6 +452,46 @@ func TestMarkdownConverter_RenderCode(t *testing.T) {
 			},
 			expected: "```text\ncode\n```\n\n",
 		},
+		{
+			name: "caption rendered below the fence",
+			block: Block{
+				Type: BlockTypeCode,
+				Content: CodeBlock{
+					Language: "bash",
+					Code:     []RichText{{PlainText: "ls"}},
+					Caption: []RichText{
+						{PlainText: "Lists the "},
+						{PlainText: "current", Annotations: Annotations{Bold: true}},
+						{PlainText: " directory"},
+					},
+				},
+			},
+			expected: "```bash\nls\n```\n_Lists the **current** directory_\n\n",
+		},
+		{
+			name: "caption from map content",
+			block: Block{
+				Type: BlockTypeCode,
+				Content: map[string]any{
+					"language":  "go",
+					"rich_text": []any{map[string]any{"plain_text": "fmt.Println()"}},
+					"caption":   []any{map[string]any{"plain_text": "Prints a newline"}},
+				},
+			},
+			expected: "```go\nfmt.Println()\n```\n_Prints a newline_\n\n",
+		},
+		{
+			name: "blank caption is skipped",
+			block: Block{
+				Type: BlockTypeCode,
+				Content: CodeBlock{
+					Language: "go",
+					Code:     []RichText{{PlainText: "x"}},
+					Caption:  []RichText{{PlainText: "  "}},
+				},
+			},
+			expected: "```go\nx\n```\n\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -306,6 +505,36 @@ func TestMarkdownConverter_RenderCode(t *testing.T) {
 	}
 }
 
+func TestFenceLanguage(t *testing.T) {
+	tests := []struct {
+		language string
+		want     string
+	}{
+		{"plain text", "text"},
+		{"Plain Text", "text"},
+		{"c++", "cpp"},
+		{"c#", "csharp"},
+		{"shell", "bash"},
+		{"objective-c", "objectivec"},
+		{"python", "python"},
+		{"  go ", "go"},
+		{"my lang", "mylang"},
+		{"", "text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.language, func(t *testing.T) {
+			if got := fenceLanguage(tt.language); got != tt.want {
+				t.Errorf("fenceLanguage(%q) = %q, want %q", tt.language, got, tt.want)
+			}
+			block := Block{Type: BlockTypeCode, Content: CodeBlock{Language: tt.language, Code: []RichText{{PlainText: "x"}}}}
+			if got, want := PageToMarkdown(&PageContent{Blocks: []Block{block}}), "```"+tt.want+"\nx\n```"; got != want {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
 func TestMarkdownConverter_RenderQuote(t *testing.T) {
 	converter := NewMarkdownConverter(&PageContent{})
 	block := Block{
@@ -325,6 +554,176 @@ func TestMarkdownConverter_RenderQuote(t *testing.T) {
 	}
 }
 
+func TestMarkdownConverter_RenderQuoteChildren(t *testing.T) {
+	text := func(s string) map[string]any {
+		return map[string]any{"rich_text": []any{map[string]any{"plain_text": s}}}
+	}
+	tests := []struct {
+		name     string
+		quote    Block
+		expected string
+	}{
+		{
+			name: "child paragraphs",
+			quote: Block{Type: BlockTypeQuote, Content: text("First line"), Children: []Block{
+				{Type: BlockTypeParagraph, Content: text("Second paragraph")},
+				{Type: BlockTypeParagraph, Content: text("Third paragraph")},
+			}},
+			expected: "> First line\n>\n> Second paragraph\n>\n> Third paragraph",
+		},
+		{
+			name: "nested list",
+			quote: Block{Type: BlockTypeQuote, Content: text("Steps"), Children: []Block{
+				{Type: BlockTypeBulletedListItem, Content: text("one")},
+				{Type: BlockTypeBulletedListItem, Content: text("two")},
+			}},
+			expected: "> Steps\n>\n> - one\n> - two",
+		},
+		{
+			name: "children only",
+			quote: Block{Type: BlockTypeQuote, Content: text(""), Children: []Block{
+				{Type: BlockTypeParagraph, Content: text("Only child")},
+			}},
+			expected: "> Only child",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PageToMarkdown(&PageContent{Blocks: []Block{tt.quote}})
+			if got != tt.expected {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMarkdownConverter_RenderNestedChildren(t *testing.T) {
+	text := func(s string) map[string]any {
+		return map[string]any{"rich_text": []any{map[string]any{"plain_text": s}}}
+	}
+	child := Block{Type: BlockTypeBulletedListItem, Content: text("child")}
+	tests := []struct {
+		name     string
+		blocks   []Block
+		expected string
+	}{
+		{
+			name: "bulleted item",
+			blocks: []Block{
+				{Type: BlockTypeBulletedListItem, Content: text("parent"), Children: []Block{child}},
+				{Type: BlockTypeBulletedListItem, Content: text("sibling")},
+			},
+			expected: "- parent\n  - child\n- sibling",
+		},
+		{
+			name: "numbered item",
+			blocks: []Block{
+				{Type: BlockTypeNumberedListItem, Content: text("first"), Children: []Block{
+					{Type: BlockTypeNumberedListItem, Content: text("inner")},
+				}},
+				{Type: BlockTypeNumberedListItem, Content: text("second")},
+			},
+			expected: "1. first\n   1. inner\n2. second",
+		},
+		{
+			name: "to-do with paragraph",
+			blocks: []Block{
+				{Type: BlockTypeToDo, Content: text("task"), Children: []Block{
+					{Type: BlockTypeParagraph, Content: text("note")},
+					child,
+				}},
+			},
+			expected: "- [ ] task\n\n  note\n\n  - child",
+		},
+		{
+			name: "toggle",
+			blocks: []Block{
+				{Type: BlockTypeToggle, Content: text("More"), Children: []Block{
+					{Type: BlockTypeParagraph, Content: text("Hidden text")},
+				}},
+			},
+			expected: "More\n\nHidden text",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PageToMarkdown(&PageContent{Blocks: tt.blocks})
+			if got != tt.expected {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	t.Run("children under the type key", func(t *testing.T) {
+		var block Block
+		if err := json.Unmarshal([]byte(`{"type": "toggle", "toggle": {
+			"rich_text": [{"plain_text": "More"}],
+			"children": [{"type": "paragraph", "paragraph": {"rich_text": [{"plain_text": "Hidden text"}]}}]
+		}}`), &block); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if _, ok := block.Content.(map[string]any)["children"]; ok {
+			t.Error("Content still holds children")
+		}
+		if got := PageToMarkdown(&PageContent{Blocks: []Block{block}}); got != "More\n\nHidden text" {
+			t.Errorf("PageToMarkdown() = %q, want the toggle and its child", got)
+		}
+	})
+}
+
+func TestMarkdownConverter_NumberedListResumption(t *testing.T) {
+	text := func(s string) map[string]any {
+		return map[string]any{"rich_text": []any{map[string]any{"plain_text": s}}}
+	}
+	item := func(s string, children ...Block) Block {
+		return Block{Type: BlockTypeNumberedListItem, Content: text(s), Children: children}
+	}
+	paragraph := Block{Type: BlockTypeParagraph, Content: text("note")}
+
+	tests := []struct {
+		name     string
+		blocks   []Block
+		expected string
+	}{
+		{
+			name:     "child paragraph keeps the count",
+			blocks:   []Block{item("one"), item("two", paragraph), item("three")},
+			expected: "1. one\n2. two\n\n   note\n3. three",
+		},
+		{
+			name:     "nested list numbers on its own",
+			blocks:   []Block{item("one", item("inner a"), item("inner b")), item("two")},
+			expected: "1. one\n   1. inner a\n   2. inner b\n2. two",
+		},
+		{
+			name:     "sibling paragraph restarts the list",
+			blocks:   []Block{item("one"), paragraph, item("two")},
+			expected: "1. one\n\nnote\n\n1. two",
+		},
+		{
+			name:     "sibling bulleted item restarts the list",
+			blocks:   []Block{item("one"), {Type: BlockTypeBulletedListItem, Content: text("dot")}, item("two")},
+			expected: "1. one\n- dot\n1. two",
+		},
+		{
+			name:     "empty item takes no number",
+			blocks:   []Block{item("one"), item(""), item("two")},
+			expected: "1. one\n2. two",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PageToMarkdown(&PageContent{Blocks: tt.blocks})
+			if got != tt.expected {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestMarkdownConverter_RenderDivider(t *testing.T) {
 	converter := NewMarkdownConverter(&PageContent{})
 	block := Block{Type: BlockTypeDivider}
@@ -399,6 +798,51 @@ func TestMarkdownConverter_RenderCallout(t *testing.T) {
 	}
 }
 
+func TestMarkdownConverter_RenderCalloutIcon(t *testing.T) {
+	callout := func(icon map[string]any, children ...Block) Block {
+		return Block{Type: BlockTypeCallout, Content: map[string]any{
+			"rich_text": []any{map[string]any{"plain_text": "Careful"}},
+			"icon":      icon,
+		}, Children: children}
+	}
+	tests := []struct {
+		name     string
+		block    Block
+		expected string
+	}{
+		{
+			name:     "emoji",
+			block:    callout(map[string]any{"type": "emoji", "emoji": "⚠️"}),
+			expected: "> ⚠️ Careful",
+		},
+		{
+			name:     "external icon",
+			block:    callout(map[string]any{"type": "external", "external": map[string]any{"url": "https://example.com/icon.png"}}),
+			expected: "> ![](https://example.com/icon.png) Careful",
+		},
+		{
+			name:     "file icon without URL",
+			block:    callout(map[string]any{"type": "file", "file": map[string]any{}}),
+			expected: "> 💡 Careful",
+		},
+		{
+			name: "children",
+			block: callout(map[string]any{"type": "emoji", "emoji": "📌"},
+				Block{Type: BlockTypeParagraph, Content: map[string]any{"rich_text": []any{map[string]any{"plain_text": "Details"}}}}),
+			expected: "> 📌 Careful\n>\n> Details",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PageToMarkdown(&PageContent{Blocks: []Block{tt.block}})
+			if got != tt.expected {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestMarkdownConverter_RenderImage(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -432,6 +876,63 @@ func TestMarkdownConverter_RenderImage(t *testing.T) {
 			},
 			expected: "![](https://example.com/image.png)\n\n",
 		},
+		{
+			name: "file-hosted image by type",
+			block: Block{
+				Type: BlockTypeImage,
+				Content: map[string]any{
+					"type": "file",
+					"file": map[string]any{
+						"url":         "https://prod-files-secure.s3.amazonaws.com/diagram.png?X-Amz-Signature=abc",
+						"expiry_time": "2025-01-01T01:00:00.000Z",
+					},
+					"caption": []any{
+						map[string]any{"plain_text": "Architecture"},
+					},
+				},
+			},
+			expected: "![Architecture](https://prod-files-secure.s3.amazonaws.com/diagram.png?X-Amz-Signature=abc)\n\n",
+		},
+		{
+			name: "external image by type",
+			block: Block{
+				Type: BlockTypeImage,
+				Content: map[string]any{
+					"type": "external",
+					"external": map[string]any{
+						"url": "https://example.com/photo.jpg",
+					},
+					"caption": []any{
+						map[string]any{"plain_text": "Team photo"},
+					},
+				},
+			},
+			expected: "![Team photo](https://example.com/photo.jpg)\n\n",
+		},
+		{
+			name: "external image without type",
+			block: Block{
+				Type: BlockTypeImage,
+				Content: map[string]any{
+					"external": map[string]any{
+						"url": "https://example.com/photo.jpg",
+					},
+				},
+			},
+			expected: "![](https://example.com/photo.jpg)\n\n",
+		},
+		{
+			name: "type selects the source",
+			block: Block{
+				Type: BlockTypeImage,
+				Content: map[string]any{
+					"type":     "external",
+					"file":     map[string]any{"url": "https://example.com/stale.png"},
+					"external": map[string]any{"url": "https://example.com/current.png"},
+				},
+			},
+			expected: "![](https://example.com/current.png)\n\n",
+		},
 		{
 			name:     "empty content",
 			block:    Block{Type: BlockTypeImage},
@@ -450,6 +951,55 @@ func TestMarkdownConverter_RenderImage(t *testing.T) {
 	}
 }
 
+func TestMarkdownConverter_RenderFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    Block
+		expected string
+	}{
+		{
+			name: "pdf with caption",
+			block: Block{Type: BlockTypePDF, Content: map[string]any{
+				"type":    "file",
+				"file":    map[string]any{"url": "https://files.example.com/spec.pdf?sig=abc"},
+				"caption": []any{map[string]any{"plain_text": "API spec"}},
+			}},
+			expected: "[API spec](https://files.example.com/spec.pdf?sig=abc)\n\n",
+		},
+		{
+			name: "file with name",
+			block: Block{Type: BlockTypeFile, Content: map[string]any{
+				"type":     "external",
+				"external": map[string]any{"url": "https://example.com/d/123"},
+				"name":     "report.xlsx",
+			}},
+			expected: "[report.xlsx](https://example.com/d/123)\n\n",
+		},
+		{
+			name: "label from URL",
+			block: Block{Type: BlockTypeFile, Content: map[string]any{
+				"file": map[string]any{"url": "https://files.example.com/a/notes.txt?sig=abc"},
+			}},
+			expected: "[notes.txt](https://files.example.com/a/notes.txt?sig=abc)\n\n",
+		},
+		{
+			name:     "no URL",
+			block:    Block{Type: BlockTypeFile, Content: map[string]any{"type": "file"}},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewMarkdownConverter(&PageContent{})
+			converter.RenderBlock(tt.block, nil)
+			if converter.Buf.String() != tt.expected {
+				t.Errorf("RenderBlock() = %q, want %q", converter.Buf.String(), tt.expected)
+			}
+		})
+	}
+}
+
 func TestMarkdownConverter_RenderBlock(t *testing.T) {
 	converter := NewMarkdownConverter(&PageContent{})
 
@@ -557,6 +1107,102 @@ func TestMarkdownConverter_ToMarkdown(t *testing.T) {
 	}
 }
 
+func TestMarkdownConverter_RenderColumnList(t *testing.T) {
+	paragraph := func(text string) Block {
+		return Block{Type: BlockTypeParagraph, Content: Paragraph{RichText: []RichText{{PlainText: text}}}}
+	}
+	column := func(children ...Block) Block {
+		return Block{Type: BlockTypeColumn, HasChildren: len(children) > 0, Children: children}
+	}
+	page := &PageContent{Blocks: []Block{
+		paragraph("Before"),
+		{
+			Type:        BlockTypeColumnList,
+			HasChildren: true,
+			Children: []Block{
+				column(paragraph("Left")),
+				column(),
+				column(paragraph("Right")),
+			},
+		},
+		paragraph("After"),
+	}}
+
+	tests := []struct {
+		name     string
+		opts     []MarkdownOption
+		expected string
+	}{
+		{
+			name:     "columns flattened in order",
+			expected: "Before\n\nLeft\n\nRight\n\nAfter",
+		},
+		{
+			name:     "separator between columns",
+			opts:     []MarkdownOption{WithColumnSeparator("---")},
+			expected: "Before\n\nLeft\n\n---\n\nRight\n\nAfter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := PageToMarkdown(page, tt.opts...); got != tt.expected {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestMarkdownConverter_RenderSyncedAndChildBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		block    Block
+		expected string
+	}{
+		{
+			name: "synced block renders its children",
+			block: Block{
+				Type:        BlockTypeSyncedBlock,
+				HasChildren: true,
+				Content:     map[string]any{"synced_from": nil},
+				Children: []Block{
+					{Type: BlockTypeParagraph, Content: Paragraph{RichText: []RichText{{PlainText: "Shared"}}}},
+					{Type: BlockTypeNumberedListItem, Content: map[string]any{"rich_text": []any{map[string]any{"plain_text": "Step"}}}},
+				},
+			},
+			expected: "Shared\n\n1. Step",
+		},
+		{
+			name:     "empty synced block",
+			block:    Block{Type: BlockTypeSyncedBlock},
+			expected: "",
+		},
+		{
+			name: "child page link",
+			block: Block{
+				ID:      "8a1b2c3d-0000-4000-8000-000000000001",
+				Type:    BlockTypeChildPage,
+				Content: map[string]any{"title": "Appendix"},
+			},
+			expected: "[Appendix](https://www.notion.so/8a1b2c3d000040008000000000000001)",
+		},
+		{
+			name:     "untitled child database",
+			block:    Block{ID: "db1", Type: BlockTypeChildDatabase, Content: map[string]any{"title": ""}},
+			expected: "[Untitled](https://www.notion.so/db1)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PageToMarkdown(&PageContent{Blocks: []Block{tt.block}})
+			if got != tt.expected {
+				t.Errorf("PageToMarkdown() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
+
 func TestPageToMarkdown(t *testing.T) {
 	pageContent := &PageContent{
 		Blocks: []Block{
@@ -576,6 +1222,112 @@ func TestPageToMarkdown(t *testing.T) {
 	}
 }
 
+func TestPageToMarkdownFrontMatter(t *testing.T) {
+	var page Page
+	if err := json.Unmarshal([]byte(`{
+		"id": "page-1",
+		"last_edited_time": "2024-05-01T10:00:00.000Z",
+		"properties": {
+			"Name": {"type": "title", "title": [{"plain_text": "Notes: \"draft\""}]},
+			"Category": {"type": "select", "select": {"name": "guides"}},
+			"Tags": {"type": "multi_select", "multi_select": [{"name": "go"}, {"name": "mcp"}]},
+			"Due": {"type": "date", "date": {"start": "2024-06-01"}},
+			"Summary": {"type": "rich_text", "rich_text": []},
+			"Owner": {"type": "people", "people": []}
+		}
+	}`), &page); err != nil {
+		t.Fatalf("unmarshal page: %v", err)
+	}
+	heading := Block{Type: BlockTypeHeading1, Content: map[string]any{"rich_text": []any{map[string]any{"plain_text": "Intro"}}}}
+	content := &PageContent{Page: page, Blocks: []Block{heading}}
+
+	got := PageToMarkdown(content, WithFrontMatter())
+	want := `---
+Category: guides
+Due: "2024-06-01"
+Name: 'Notes: "draft"'
+Tags:
+    - go
+    - mcp
+last_edited_time: "2024-05-01T10:00:00Z"
+---
+
+# Intro`
+	if got != want {
+		t.Errorf("PageToMarkdown() = %q, want %q", got, want)
+	}
+
+	if got := PageToMarkdown(content); got != "# Intro" {
+		t.Errorf("PageToMarkdown() without front matter = %q, want %q", got, "# Intro")
+	}
+
+	t.Run("Relations", func(t *testing.T) {
+		related := Page{ID: "page-2", Properties: map[string]Property{
+			"Projects": {Type: PropertyTypeRelation, Relation: []Relation{{ID: "page-a"}, {ID: "page-b"}}},
+		}}
+		content := &PageContent{Page: related, Blocks: []Block{heading}}
+		if got, want := PageToMarkdown(content, WithFrontMatter()), "---\nProjects:\n    - page-a\n    - page-b\n---\n\n# Intro"; got != want {
+			t.Errorf("PageToMarkdown() = %q, want %q", got, want)
+		}
+		got := PageToMarkdown(content, WithFrontMatter(), WithRelationTitles(map[string][]string{"Projects": {"Alpha", "Beta"}}))
+		if want := "---\nProjects:\n    - Alpha\n    - Beta\n---\n\n# Intro"; got != want {
+			t.Errorf("PageToMarkdown() with relation titles = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestPageToMarkdownWithOptions(t *testing.T) {
+	text := func(s string, fields map[string]any) map[string]any {
+		fields["rich_text"] = []any{map[string]any{"plain_text": s}}
+		return fields
+	}
+	page := Page{
+		ID:         "page-1",
+		Properties: map[string]Property{"Name": {Type: PropertyTypeTitle, Title: []Title{{PlainText: "Guide"}}}},
+	}
+	content := &PageContent{Page: page, Blocks: []Block{
+		{Type: BlockTypeHeading2, Content: text("Setup", map[string]any{"color": "red"})},
+		{Type: BlockTypeToggle, Content: text("More", map[string]any{}), Children: []Block{
+			{Type: BlockTypeParagraph, Content: text("Hidden text", map[string]any{})},
+		}},
+	}}
+
+	tests := []struct {
+		name     string
+		opts     MarkdownOptions
+		expected string
+	}{
+		{
+			name:     "defaults",
+			expected: "## Setup\n\nMore\n\nHidden text",
+		},
+		{
+			name: "colors and HTML toggles",
+			opts: MarkdownOptions{Colors: true, ToggleHTML: true},
+			expected: `## <span style="color: red">Setup</span>` + "\n\n" +
+				"<details>\n<summary>More</summary>\n\nHidden text\n\n</details>",
+		},
+		{
+			name:     "front matter and HTML toggles",
+			opts:     MarkdownOptions{FrontMatter: true, ToggleHTML: true},
+			expected: "---\nName: Guide\n---\n\n## Setup\n\n<details>\n<summary>More</summary>\n\nHidden text\n\n</details>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PageToMarkdownWithOptions(content, tt.opts)
+			if got != tt.expected {
+				t.Errorf("PageToMarkdownWithOptions() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+
+	if got, want := PageToMarkdown(content, WithColors()), PageToMarkdownWithOptions(content, MarkdownOptions{Colors: true}); got != want {
+		t.Errorf("PageToMarkdown(WithColors()) = %q, want the same as MarkdownOptions{Colors: true}: %q", got, want)
+	}
+}
+
 func TestMarkdownConverter_extractRichTexts(t *testing.T) {
 	converter := NewMarkdownConverter(&PageContent{})
 
@@ -0,0 +1,103 @@
+package notion
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestEvent describes one attempt of a Notion API request.
+type RequestEvent struct {
+	Method   string
+	Endpoint string // URL path with the object ID replaced, e.g. "/pages/{id}"
+	Attempt  int    // 1 for the first attempt
+	Status   int    // HTTP status code, 0 if the request failed
+	Duration time.Duration
+	Err      error // network error, nil if a response was received
+	Retry    bool  // whether the request will be retried
+}
+
+// RequestObserver is called after every request attempt. It must be safe
+// for concurrent use.
+type RequestObserver func(RequestEvent)
+
+// WithObserver registers an observer of every request attempt, e.g.
+// ClientMetrics.Observe. Without one the client records nothing.
+func WithObserver(observer RequestObserver) ClientOption {
+	return func(c *Client) {
+		c.observer = observer
+	}
+}
+
+// EndpointStats counts the requests made to one endpoint.
+type EndpointStats struct {
+	Attempts    int64         // requests sent, including retries
+	RateLimited int64         // 429 responses
+	Retries     int64         // attempts that were retried
+	Errors      int64         // network errors and error responses
+	Latency     time.Duration // total time spent in attempts
+}
+
+// ClientMetrics aggregates request attempts per endpoint. Register it with
+// WithObserver(metrics.Observe).
+type ClientMetrics struct {
+	mu        sync.Mutex
+	endpoints map[string]*EndpointStats
+}
+
+// NewClientMetrics creates an empty ClientMetrics.
+func NewClientMetrics() *ClientMetrics {
+	return &ClientMetrics{endpoints: make(map[string]*EndpointStats)}
+}
+
+// Observe records a request attempt.
+func (m *ClientMetrics) Observe(e RequestEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := e.Method + " " + e.Endpoint
+	stats, ok := m.endpoints[key]
+	if !ok {
+		stats = &EndpointStats{}
+		m.endpoints[key] = stats
+	}
+	stats.Attempts++
+	stats.Latency += e.Duration
+	if e.Status == 429 {
+		stats.RateLimited++
+	}
+	if e.Retry {
+		stats.Retries++
+	}
+	if e.Err != nil || e.Status >= 400 {
+		stats.Errors++
+	}
+}
+
+// Snapshot returns a copy of the stats, keyed by method and endpoint, e.g.
+// "POST /databases/{id}/query".
+func (m *ClientMetrics) Snapshot() map[string]EndpointStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]EndpointStats, len(m.endpoints))
+	for key, stats := range m.endpoints {
+		snapshot[key] = *stats
+	}
+	return snapshot
+}
+
+// endpoint returns the path of rawURL relative to the client's base URL,
+// with the object ID that follows the resource name replaced by "{id}".
+func (c *Client) endpoint(rawURL string) string {
+	path := strings.TrimPrefix(rawURL, c.baseURL)
+	if u, err := url.Parse(path); err == nil {
+		path = u.Path
+	}
+	parts := strings.Split(strings.TrimPrefix(path, "/"), "/")
+	if len(parts) > 1 {
+		parts[1] = "{id}"
+	}
+	return "/" + strings.Join(parts, "/")
+}
@@ -0,0 +1,57 @@
+package notion
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientMetrics(t *testing.T) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write([]byte(`{"object": "page", "id": "page-1"}`))
+	}))
+	defer srv.Close()
+
+	metrics := NewClientMetrics()
+	client := NewClient("key", "db", "Type", WithBaseURL(srv.URL), WithObserver(metrics.Observe))
+	if _, err := client.GetPage(context.Background(), "page-1"); err != nil {
+		t.Fatalf("GetPage() failed: %v", err)
+	}
+
+	got := metrics.Snapshot()["GET /pages/{id}"]
+	want := EndpointStats{Attempts: 2, RateLimited: 1, Retries: 1, Errors: 1}
+	got.Latency = 0
+	if got != want {
+		t.Errorf("stats = %+v, want %+v (all: %+v)", got, want, metrics.Snapshot())
+	}
+}
+
+func TestClientEndpoint(t *testing.T) {
+	client := NewClient("key", "db", "Type", WithBaseURL("http://localhost/v1"))
+
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"http://localhost/v1/databases/abc-123/query", "/databases/{id}/query"},
+		{"http://localhost/v1/pages/abc", "/pages/{id}"},
+		{"http://localhost/v1/blocks/abc/children?start_cursor=xyz", "/blocks/{id}/children"},
+		{"http://localhost/v1/search", "/search"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := client.endpoint(tt.url); got != tt.want {
+				t.Errorf("endpoint(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
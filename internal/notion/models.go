@@ -13,16 +13,63 @@ type Page struct {
 	LastEditedTime time.Time           `json:"last_edited_time"`
 	Properties     map[string]Property `json:"properties"`
 	Content        []Block             `json:"content,omitempty"`
+	// DatabaseID is the database the page was queried from
+	DatabaseID string `json:"database_id,omitempty"`
 }
 
-// Property represents a Notion property.
+// Property represents a Notion property. Only the field matching Type is set.
 type Property struct {
-	Name     string       `json:"name"`
-	Type     PropertyType `json:"type"`
-	Value    any          `json:"value"`
-	Select   *Select      `json:"select"`
-	Title    []Title      `json:"title"`
-	RichText []RichText   `json:"rich_text"`
+	Name        string       `json:"name"`
+	Type        PropertyType `json:"type"`
+	Value       any          `json:"value"`
+	Select      *Select      `json:"select"`
+	Title       []Title      `json:"title"`
+	RichText    []RichText   `json:"rich_text"`
+	Number      *float64     `json:"number"`
+	MultiSelect []Select     `json:"multi_select"`
+	Status      *Select      `json:"status"`
+	Checkbox    bool         `json:"checkbox"`
+	Date        *Date        `json:"date"`
+	URL         *string      `json:"url"`
+	Email       *string      `json:"email"`
+	Formula     *Formula     `json:"formula"`
+	Rollup      *Rollup      `json:"rollup"`
+	Relation    []Relation   `json:"relation"`
+}
+
+// Relation is a page referenced by a relation property. Use a
+// RelationResolver to look up its title.
+type Relation struct {
+	ID string `json:"id"`
+}
+
+// Formula is the computed value of a formula property. Type says which of
+// String, Number, Boolean and Date holds it.
+type Formula struct {
+	Type    string   `json:"type"`
+	String  *string  `json:"string"`
+	Number  *float64 `json:"number"`
+	Boolean bool     `json:"boolean"`
+	Date    *Date    `json:"date"`
+}
+
+// Rollup is the computed value of a rollup property. Type says which of
+// Number, Date and Array holds it; Array holds the rolled-up properties of
+// the related pages.
+type Rollup struct {
+	Type     string     `json:"type"`
+	Function string     `json:"function"`
+	Number   *float64   `json:"number"`
+	Date     *Date      `json:"date"`
+	Array    []Property `json:"array"`
+}
+
+// Date is the value of a date property. Start and End are ISO 8601 dates
+// or date-times; End is empty unless the date is a range.
+type Date struct {
+	Start    string `json:"start"`
+	End      string `json:"end"`
+	TimeZone string `json:"time_zone"`
 }
 
 /*
@@ -66,6 +113,9 @@ const (
 	PropertyTypeURL         PropertyType = "url"
 	PropertyTypeEmail       PropertyType = "email"
 	PropertyTypeNumber      PropertyType = "number"
+	PropertyTypeFormula     PropertyType = "formula"
+	PropertyTypeRollup      PropertyType = "rollup"
+	PropertyTypeRelation    PropertyType = "relation"
 )
 
 type Block struct {
@@ -82,6 +132,30 @@ type Block struct {
 	Archived       bool       `json:"archived"`
 	InTrash        bool       `json:"in_trash"`
 	Paragraph      *Paragraph `json:"paragraph,omitempty"`
+	// Children holds the nested blocks of a block with HasChildren, if
+	// fetched, or those nested under its type key in the JSON
+	Children []Block `json:"children,omitempty"`
+}
+
+// MarshalJSON writes Content back under the block's type key, so blocks
+// round-trip through JSON.
+func (b Block) MarshalJSON() ([]byte, error) {
+	type Alias Block
+	data, err := json.Marshal(Alias(b))
+	if err != nil || b.Content == nil || b.Type == "" {
+		return data, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	content, err := json.Marshal(b.Content)
+	if err != nil {
+		return nil, err
+	}
+	fields[string(b.Type)] = content
+	return json.Marshal(fields)
 }
 
 // UnmarshalJSON implements custom JSON unmarshaling to populate Content field.
@@ -132,6 +206,20 @@ func (b *Block) UnmarshalJSON(data []byte) error {
 		}
 	}
 
+	// Block objects written for the API, such as append payloads, nest
+	// children under the type key rather than fetching them separately.
+	if typeData, ok := raw[string(b.Type)]; ok && len(b.Children) == 0 {
+		var nested struct {
+			Children []Block `json:"children"`
+		}
+		if err := json.Unmarshal(typeData, &nested); err == nil && len(nested.Children) > 0 {
+			b.Children = nested.Children
+			if fields, ok := b.Content.(map[string]any); ok {
+				delete(fields, "children")
+			}
+		}
+	}
+
 	return nil
 }
 
@@ -148,6 +236,7 @@ type Parent struct {
 type User struct {
 	Object string `json:"object"`
 	ID     string `json:"id"`
+	Name   string `json:"name,omitempty"`
 }
 
 // BlockType represents the type of a Notion block.
@@ -165,8 +254,15 @@ const (
 	BlockTypeDivider          BlockType = "divider"
 	BlockTypeCallout          BlockType = "callout"
 	BlockTypeImage            BlockType = "image"
+	BlockTypeFile             BlockType = "file"
+	BlockTypePDF              BlockType = "pdf"
 	BlockTypeToDo             BlockType = "to_do"
 	BlockTypeToggle           BlockType = "toggle"
+	BlockTypeColumnList       BlockType = "column_list"
+	BlockTypeColumn           BlockType = "column"
+	BlockTypeSyncedBlock      BlockType = "synced_block"
+	BlockTypeChildPage        BlockType = "child_page"
+	BlockTypeChildDatabase    BlockType = "child_database"
 )
 
 // CodeBlock represents a code block content.
@@ -184,6 +280,23 @@ type RichText struct {
 	Annotations Annotations `json:"annotations"`
 	PlainText   string      `json:"plain_text"`
 	Href        *string     `json:"href"`
+	// Mention is set for runs of type "mention"
+	Mention *Mention `json:"mention,omitempty"`
+}
+
+// Mention is the target of a mention rich text run. Type says which of
+// Page, Database, User and Date is set.
+type Mention struct {
+	Type     string     `json:"type"`
+	Page     *Reference `json:"page,omitempty"`
+	Database *Reference `json:"database,omitempty"`
+	User     *User      `json:"user,omitempty"`
+	Date     *Date      `json:"date,omitempty"`
+}
+
+// Reference identifies a mentioned page or database.
+type Reference struct {
+	ID string `json:"id"`
 }
 
 // Link represents a hyperlink in rich text.
@@ -202,9 +315,12 @@ type Annotations struct {
 
 // PageContent represents a page with its content blocks.
 type PageContent struct {
-	Page    Page
-	Blocks  []Block
-	Text    string
+	Page   Page
+	Blocks []Block
+	Text   string
+	// HasCode and Code describe the first code block, if any
 	HasCode bool
 	Code    CodeBlock
+	// CodeBlocks holds every top-level code block in page order
+	CodeBlocks []CodeBlock
 }
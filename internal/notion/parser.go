@@ -2,9 +2,14 @@
 package notion
 
 import (
+	"encoding/json"
+	"regexp"
 	"strings"
 )
 
+// propertyPlaceholder matches {{prop:Name}} placeholders in rendered content.
+var propertyPlaceholder = regexp.MustCompile(`\{\{prop:([^}]+)\}\}`)
+
 // ExtractText extracts plain text from a list of blocks.
 func ExtractText(blocks []Block) string {
 	var sb strings.Builder
@@ -21,7 +26,7 @@ func ExtractText(blocks []Block) string {
 func extractBlockText(block Block) string {
 	switch block.Type {
 	case BlockTypeParagraph:
-		return extractRichText(block.Content)
+		return richTextPlain(blockRichText(block))
 	case BlockTypeHeading1, BlockTypeHeading2, BlockTypeHeading3:
 		return extractRichText(block.Content)
 	case BlockTypeBulletedListItem:
@@ -31,7 +36,7 @@ func extractBlockText(block Block) string {
 	case BlockTypeCode:
 		codeBlock, ok := block.Content.(CodeBlock)
 		if ok {
-			return "```" + codeBlock.Language + "\n" + extractRichText(codeBlock.Code) + "\n```"
+			return "```" + fenceLanguage(codeBlock.Language) + "\n" + extractRichText(codeBlock.Code) + "\n```"
 		}
 	case BlockTypeToDo:
 		checked := false
@@ -55,29 +60,11 @@ func extractBlockText(block Block) string {
 
 // extractRichText extracts text from rich text array.
 func extractRichText(content any) string {
-	var texts []RichText
-
-	switch v := content.(type) {
-	case []RichText:
-		texts = v
-	case map[string]any:
-		if rt, ok := v["rich_text"].([]any); ok {
-			for _, r := range rt {
-				if m, ok := r.(map[string]any); ok {
-					rt := RichText{
-						PlainText: getMapString(m, "plain_text"),
-					}
-					if textMap, ok := m["text"].(map[string]any); ok {
-						rt.Text = Text{
-							Content: getMapString(textMap, "content"),
-						}
-					}
-					texts = append(texts, rt)
-				}
-			}
-		}
-	}
+	return richTextPlain(richTextOf(content))
+}
 
+// richTextPlain joins the plain text of texts.
+func richTextPlain(texts []RichText) string {
 	var sb strings.Builder
 	for _, text := range texts {
 		sb.WriteString(text.PlainText)
@@ -85,6 +72,41 @@ func extractRichText(content any) string {
 	return sb.String()
 }
 
+// blockRichText returns the rich text of a block, from its Content or, for
+// a paragraph built without one, its Paragraph field.
+func blockRichText(block Block) []RichText {
+	if block.Content == nil && block.Paragraph != nil {
+		return block.Paragraph.RichText
+	}
+	return richTextOf(block.Content)
+}
+
+// richTextOf returns the rich text of block content in any of the shapes
+// it arrives in: a rich text slice, a decoded Paragraph or CodeBlock, raw
+// JSON, or a generic map with a "rich_text" array.
+func richTextOf(content any) []RichText {
+	switch v := content.(type) {
+	case []RichText:
+		return v
+	case Paragraph:
+		return v.RichText
+	case *Paragraph:
+		if v != nil {
+			return v.RichText
+		}
+	case CodeBlock:
+		return v.RichText
+	case json.RawMessage:
+		var m map[string]any
+		if json.Unmarshal(v, &m) == nil {
+			return parseRichTextList(m["rich_text"])
+		}
+	case map[string]any:
+		return parseRichTextList(v["rich_text"])
+	}
+	return nil
+}
+
 // getMapString gets a string value from a map.
 func getMapString(m map[string]any, key string) string {
 	if v, ok := m[key]; ok {
@@ -102,43 +124,149 @@ func GetTypeFromProperties(properties map[string]Property, typeField string) str
 			if prop.Type == PropertyTypeSelect && prop.Select != nil {
 				return prop.Select.Name
 			}
+			// A formula can compute the type from other properties
+			if prop.Type == PropertyTypeFormula && prop.Formula != nil && prop.Formula.Type == "string" && prop.Formula.String != nil {
+				return *prop.Formula.String
+			}
 		}
 	}
 	return ""
 }
 
-// ParseCodeBlock parses a code block from content.
+// PropertyText returns the plain text value of a property, or "" if the
+// property type is not supported.
+func PropertyText(prop Property) string {
+	text, _ := AsString(prop)
+	return text
+}
+
+// ExpandPropertyPlaceholders replaces {{prop:Name}} placeholders in text with
+// the value of the named page property. Placeholders naming a property the
+// page does not have are left unchanged.
+func ExpandPropertyPlaceholders(text string, properties map[string]Property) string {
+	return propertyPlaceholder.ReplaceAllStringFunc(text, func(match string) string {
+		name := strings.TrimSpace(propertyPlaceholder.FindStringSubmatch(match)[1])
+		prop, ok := properties[name]
+		if !ok {
+			return match
+		}
+		return PropertyText(prop)
+	})
+}
+
+// FileURL returns the URL of a file-backed block (image, file or pdf).
+// Both Notion-hosted ("file") and external files are supported; the
+// content's type field says which one holds the URL.
+func FileURL(block Block) (string, bool) {
+	contentMap, ok := block.Content.(map[string]any)
+	if !ok {
+		return "", false
+	}
+	source, _ := contentMap["type"].(string)
+	if source != "file" && source != "external" {
+		// Older payloads omit type; use whichever source is present
+		source = "file"
+		if _, ok := contentMap[source]; !ok {
+			source = "external"
+		}
+	}
+	file, ok := contentMap[source].(map[string]any)
+	if !ok {
+		return "", false
+	}
+	url, ok := file["url"].(string)
+	return url, ok && url != ""
+}
+
+// ParseCodeBlock parses a code block from content, which may be a decoded
+// CodeBlock, raw JSON or a generic map. It reports false for other shapes.
 func ParseCodeBlock(block Block) (CodeBlock, bool) {
 	if block.Type != BlockTypeCode {
 		return CodeBlock{}, false
 	}
 
-	content, ok := block.Content.(map[string]any)
-	if !ok {
+	var content map[string]any
+	switch v := block.Content.(type) {
+	case CodeBlock:
+		return v, true
+	case json.RawMessage:
+		if err := json.Unmarshal(v, &content); err != nil {
+			return CodeBlock{}, false
+		}
+	case map[string]any:
+		content = v
+	default:
 		return CodeBlock{}, false
 	}
 
-	lang := getMapString(content, "language")
+	richTexts := parseRichTextList(content["rich_text"])
+	return CodeBlock{
+		Language: getMapString(content, "language"),
+		Caption:  parseRichTextList(content["caption"]),
+		Code:     richTexts,
+		RichText: richTexts,
+	}, true
+}
 
+// parseRichTextList parses a generic rich text array, keeping the text,
+// link, annotations and href of each item.
+func parseRichTextList(v any) []RichText {
+	items, _ := v.([]any)
 	var richTexts []RichText
-	if rt, ok := content["rich_text"].([]any); ok {
-		for _, r := range rt {
-			if m, ok := r.(map[string]any); ok {
-				rt := RichText{
-					PlainText: getMapString(m, "plain_text"),
-				}
-				if textMap, ok := m["text"].(map[string]any); ok {
-					rt.Text = Text{
-						Content: getMapString(textMap, "content"),
-					}
+	for _, item := range items {
+		m, ok := item.(map[string]any)
+		if !ok {
+			continue
+		}
+		rt := RichText{
+			Type:      getMapString(m, "type"),
+			PlainText: getMapString(m, "plain_text"),
+		}
+		if textMap, ok := m["text"].(map[string]any); ok {
+			rt.Text = Text{
+				Content: getMapString(textMap, "content"),
+			}
+			if linkMap, ok := textMap["link"].(map[string]any); ok {
+				if url, ok := linkMap["url"].(string); ok {
+					rt.Text.Link = &Link{URL: url}
 				}
-				richTexts = append(richTexts, rt)
 			}
 		}
+		if ann, ok := m["annotations"].(map[string]any); ok {
+			rt.Annotations = Annotations{
+				Bold:          getMapBool(ann, "bold"),
+				Italic:        getMapBool(ann, "italic"),
+				Strikethrough: getMapBool(ann, "strikethrough"),
+				Underline:     getMapBool(ann, "underline"),
+				Code:          getMapBool(ann, "code"),
+			}
+		}
+		if href, ok := m["href"].(string); ok && href != "" {
+			rt.Href = &href
+		}
+		if mention, ok := m["mention"].(map[string]any); ok {
+			rt.Mention = parseMention(mention)
+		}
+		richTexts = append(richTexts, rt)
 	}
+	return richTexts
+}
 
-	return CodeBlock{
-		Language: lang,
-		Code:     richTexts,
-	}, true
+// parseMention converts the mention object of a rich text run.
+func parseMention(m map[string]any) *Mention {
+	mention := &Mention{Type: getMapString(m, "type")}
+	ref := func(key string) *Reference {
+		if v, ok := m[key].(map[string]any); ok {
+			return &Reference{ID: getMapString(v, "id")}
+		}
+		return nil
+	}
+	mention.Page, mention.Database = ref("page"), ref("database")
+	if user, ok := m["user"].(map[string]any); ok {
+		mention.User = &User{Object: getMapString(user, "object"), ID: getMapString(user, "id"), Name: getMapString(user, "name")}
+	}
+	if date, ok := m["date"].(map[string]any); ok {
+		mention.Date = &Date{Start: getMapString(date, "start"), End: getMapString(date, "end"), TimeZone: getMapString(date, "time_zone")}
+	}
+	return mention
 }
@@ -1,6 +1,7 @@
 package notion
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -203,6 +204,7 @@ func TestExtractText(t *testing.T) {
 }
 
 func TestGetTypeFromProperties(t *testing.T) {
+	toolType := "tool"
 	tests := []struct {
 		name       string
 		properties map[string]Property
@@ -272,6 +274,28 @@ func TestGetTypeFromProperties(t *testing.T) {
 			typeField: "Type",
 			expected:  "",
 		},
+		{
+			name: "formula with string result",
+			properties: map[string]Property{
+				"Type": {
+					Type:    PropertyTypeFormula,
+					Formula: &Formula{Type: "string", String: &toolType},
+				},
+			},
+			typeField: "Type",
+			expected:  "tool",
+		},
+		{
+			name: "formula with number result",
+			properties: map[string]Property{
+				"Type": {
+					Type:    PropertyTypeFormula,
+					Formula: &Formula{Type: "number"},
+				},
+			},
+			typeField: "Type",
+			expected:  "",
+		},
 		{
 			name: "multiple properties",
 			properties: map[string]Property{
@@ -303,6 +327,106 @@ func TestGetTypeFromProperties(t *testing.T) {
 	}
 }
 
+func TestPropertyText(t *testing.T) {
+	tests := []struct {
+		name     string
+		prop     Property
+		expected string
+	}{
+		{
+			name:     "select",
+			prop:     Property{Type: PropertyTypeSelect, Select: &Select{Name: "friendly"}},
+			expected: "friendly",
+		},
+		{
+			name:     "nil select",
+			prop:     Property{Type: PropertyTypeSelect},
+			expected: "",
+		},
+		{
+			name: "title",
+			prop: Property{Type: PropertyTypeTitle, Title: []Title{
+				{PlainText: "Code "}, {PlainText: "Review"},
+			}},
+			expected: "Code Review",
+		},
+		{
+			name: "rich text",
+			prop: Property{Type: PropertyTypeRichText, RichText: []RichText{
+				{PlainText: "senior engineer"},
+			}},
+			expected: "senior engineer",
+		},
+		{
+			name:     "checkbox",
+			prop:     Property{Type: PropertyTypeCheckbox, Checkbox: true},
+			expected: "true",
+		},
+		{
+			name:     "unsupported type",
+			prop:     Property{Type: "formula"},
+			expected: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := PropertyText(tt.prop)
+			if result != tt.expected {
+				t.Errorf("PropertyText() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpandPropertyPlaceholders(t *testing.T) {
+	properties := map[string]Property{
+		"Tone": {Type: PropertyTypeSelect, Select: &Select{Name: "friendly"}},
+		"Role": {Type: PropertyTypeRichText, RichText: []RichText{{PlainText: "reviewer"}}},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "select property substituted",
+			input:    "Respond in a {{prop:Tone}} tone.",
+			expected: "Respond in a friendly tone.",
+		},
+		{
+			name:     "multiple placeholders",
+			input:    "You are a {{prop:Role}}. Be {{prop:Tone}}.",
+			expected: "You are a reviewer. Be friendly.",
+		},
+		{
+			name:     "whitespace around name",
+			input:    "Be {{prop: Tone }}.",
+			expected: "Be friendly.",
+		},
+		{
+			name:     "unknown property left unchanged",
+			input:    "Use {{prop:Missing}} here.",
+			expected: "Use {{prop:Missing}} here.",
+		},
+		{
+			name:     "no placeholders",
+			input:    "Plain prompt {{not a prop}}",
+			expected: "Plain prompt {{not a prop}}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ExpandPropertyPlaceholders(tt.input, properties)
+			if result != tt.expected {
+				t.Errorf("ExpandPropertyPlaceholders() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestParseCodeBlock(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -339,6 +463,33 @@ func TestParseCodeBlock(t *testing.T) {
 			wantOk:   false,
 			wantCode: CodeBlock{},
 		},
+		{
+			name: "decoded code block",
+			block: Block{
+				Type:    BlockTypeCode,
+				Content: CodeBlock{Language: "go", Code: []RichText{{PlainText: "package main"}}},
+			},
+			wantOk:   true,
+			wantCode: CodeBlock{Language: "go", Code: []RichText{{PlainText: "package main"}}},
+		},
+		{
+			name: "raw JSON content",
+			block: Block{
+				Type:    BlockTypeCode,
+				Content: json.RawMessage(`{"language": "bash", "rich_text": [{"plain_text": "echo hi"}]}`),
+			},
+			wantOk:   true,
+			wantCode: CodeBlock{Language: "bash", Code: []RichText{{PlainText: "echo hi"}}},
+		},
+		{
+			name: "invalid raw JSON content",
+			block: Block{
+				Type:    BlockTypeCode,
+				Content: json.RawMessage(`"not an object"`),
+			},
+			wantOk:   false,
+			wantCode: CodeBlock{},
+		},
 		{
 			name: "content is not map",
 			block: Block{
@@ -451,8 +602,7 @@ func TestExtractRichText(t *testing.T) {
 					{PlainText: "graph"},
 				},
 			},
-			// Note: extractRichText doesn't handle Paragraph type as content
-			expected: "",
+			expected: "Paragraph",
 		},
 	}
 
@@ -0,0 +1,243 @@
+package notion
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PropertyValue returns the value of a property as a Go value: string for
+// title, rich_text, select, status, url and email; []string for
+// multi_select; float64 for number; bool for checkbox; and time.Time for
+// the start of a date. Empty select, number, date, url and email
+// properties yield nil. Formulas and rollups yield their result the same
+// way, with a rollup array as the []string of its items' text. Relations
+// yield the []string of related page IDs.
+func PropertyValue(prop Property) (any, error) {
+	switch prop.Type {
+	case PropertyTypeTitle:
+		var sb strings.Builder
+		for _, t := range prop.Title {
+			sb.WriteString(t.PlainText)
+		}
+		return sb.String(), nil
+	case PropertyTypeRichText:
+		var sb strings.Builder
+		for _, rt := range prop.RichText {
+			sb.WriteString(rt.PlainText)
+		}
+		return sb.String(), nil
+	case PropertyTypeSelect:
+		if prop.Select == nil {
+			return nil, nil
+		}
+		return prop.Select.Name, nil
+	case PropertyTypeStatus:
+		if prop.Status == nil {
+			return nil, nil
+		}
+		return prop.Status.Name, nil
+	case PropertyTypeMultiSelect:
+		names := make([]string, len(prop.MultiSelect))
+		for i, opt := range prop.MultiSelect {
+			names[i] = opt.Name
+		}
+		return names, nil
+	case PropertyTypeNumber:
+		if prop.Number == nil {
+			return nil, nil
+		}
+		return *prop.Number, nil
+	case PropertyTypeCheckbox:
+		return prop.Checkbox, nil
+	case PropertyTypeDate:
+		if prop.Date == nil || prop.Date.Start == "" {
+			return nil, nil
+		}
+		return parseDate(prop.Date.Start)
+	case PropertyTypeURL:
+		if prop.URL == nil {
+			return nil, nil
+		}
+		return *prop.URL, nil
+	case PropertyTypeEmail:
+		if prop.Email == nil {
+			return nil, nil
+		}
+		return *prop.Email, nil
+	case PropertyTypeRelation:
+		ids := make([]string, len(prop.Relation))
+		for i, rel := range prop.Relation {
+			ids[i] = rel.ID
+		}
+		return ids, nil
+	case PropertyTypeFormula:
+		return formulaValue(prop.Formula)
+	case PropertyTypeRollup:
+		return rollupValue(prop.Rollup)
+	}
+	return nil, fmt.Errorf("unsupported property type %q", prop.Type)
+}
+
+// formulaValue returns the result of a formula property.
+func formulaValue(f *Formula) (any, error) {
+	if f == nil {
+		return nil, nil
+	}
+	switch f.Type {
+	case "string":
+		if f.String == nil {
+			return nil, nil
+		}
+		return *f.String, nil
+	case "number":
+		if f.Number == nil {
+			return nil, nil
+		}
+		return *f.Number, nil
+	case "boolean":
+		return f.Boolean, nil
+	case "date":
+		if f.Date == nil || f.Date.Start == "" {
+			return nil, nil
+		}
+		return parseDate(f.Date.Start)
+	}
+	return nil, fmt.Errorf("unsupported formula type %q", f.Type)
+}
+
+// rollupValue returns the result of a rollup property.
+func rollupValue(r *Rollup) (any, error) {
+	if r == nil {
+		return nil, nil
+	}
+	switch r.Type {
+	case "number":
+		if r.Number == nil {
+			return nil, nil
+		}
+		return *r.Number, nil
+	case "date":
+		if r.Date == nil || r.Date.Start == "" {
+			return nil, nil
+		}
+		return parseDate(r.Date.Start)
+	case "array":
+		items := make([]string, 0, len(r.Array))
+		for _, item := range r.Array {
+			text, err := AsString(item)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, text)
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("unsupported rollup type %q", r.Type)
+}
+
+// propertyDate returns the date held by a date property or by a formula or
+// rollup with a date result, or nil.
+func propertyDate(prop Property) *Date {
+	switch {
+	case prop.Type == PropertyTypeDate:
+		return prop.Date
+	case prop.Type == PropertyTypeFormula && prop.Formula != nil && prop.Formula.Type == "date":
+		return prop.Formula.Date
+	case prop.Type == PropertyTypeRollup && prop.Rollup != nil && prop.Rollup.Type == "date":
+		return prop.Rollup.Date
+	}
+	return nil
+}
+
+// PageTitle returns the text of a page's title property, or "" if it has
+// none.
+func PageTitle(page Page) string {
+	for _, prop := range page.Properties {
+		if prop.Type == PropertyTypeTitle {
+			return PropertyText(prop)
+		}
+	}
+	return ""
+}
+
+// parseDate parses a Notion date, which is either a date or a date-time.
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.DateOnly, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
+
+// AsString formats any property as text. Multi-select options are joined
+// with ", ", dates keep Notion's formatting and empty properties yield "".
+func AsString(prop Property) (string, error) {
+	if date := propertyDate(prop); date != nil {
+		return date.Start, nil
+	}
+	v, err := PropertyValue(prop)
+	if err != nil {
+		return "", err
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []string:
+		return strings.Join(v, ", "), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	return fmt.Sprint(v), nil
+}
+
+// AsNumber returns the value of a number property, or of a formula or
+// rollup with a number result.
+func AsNumber(prop Property) (float64, error) {
+	number := prop.Number
+	switch {
+	case prop.Type == PropertyTypeFormula && prop.Formula != nil && prop.Formula.Type == "number":
+		number = prop.Formula.Number
+	case prop.Type == PropertyTypeRollup && prop.Rollup != nil && prop.Rollup.Type == "number":
+		number = prop.Rollup.Number
+	case prop.Type != PropertyTypeNumber:
+		return 0, fmt.Errorf("property type %q is not a number", prop.Type)
+	}
+	if number == nil {
+		return 0, fmt.Errorf("number property is empty")
+	}
+	return *number, nil
+}
+
+// AsBool returns the value of a checkbox property, or of a formula with a
+// boolean result.
+func AsBool(prop Property) (bool, error) {
+	if prop.Type == PropertyTypeFormula && prop.Formula != nil && prop.Formula.Type == "boolean" {
+		return prop.Formula.Boolean, nil
+	}
+	if prop.Type != PropertyTypeCheckbox {
+		return false, fmt.Errorf("property type %q is not a checkbox", prop.Type)
+	}
+	return prop.Checkbox, nil
+}
+
+// AsDate returns the start of a date property, or of a formula or rollup
+// with a date result.
+func AsDate(prop Property) (time.Time, error) {
+	date := propertyDate(prop)
+	if date == nil && prop.Type != PropertyTypeDate {
+		return time.Time{}, fmt.Errorf("property type %q is not a date", prop.Type)
+	}
+	if date == nil || date.Start == "" {
+		return time.Time{}, fmt.Errorf("date property is empty")
+	}
+	return parseDate(date.Start)
+}
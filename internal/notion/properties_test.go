@@ -0,0 +1,238 @@
+package notion
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPropertyValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		json       string
+		wantValue  any
+		wantString string
+	}{
+		{
+			name:       "title",
+			json:       `{"type":"title","title":[{"plain_text":"Hello "},{"plain_text":"world"}]}`,
+			wantValue:  "Hello world",
+			wantString: "Hello world",
+		},
+		{
+			name:       "rich_text",
+			json:       `{"type":"rich_text","rich_text":[{"plain_text":"Some text"}]}`,
+			wantValue:  "Some text",
+			wantString: "Some text",
+		},
+		{
+			name:       "select",
+			json:       `{"type":"select","select":{"id":"1","name":"prompt","color":"red"}}`,
+			wantValue:  "prompt",
+			wantString: "prompt",
+		},
+		{
+			name:       "empty select",
+			json:       `{"type":"select","select":null}`,
+			wantValue:  nil,
+			wantString: "",
+		},
+		{
+			name:       "multi_select",
+			json:       `{"type":"multi_select","multi_select":[{"name":"go"},{"name":"mcp"}]}`,
+			wantValue:  []string{"go", "mcp"},
+			wantString: "go, mcp",
+		},
+		{
+			name:       "status",
+			json:       `{"type":"status","status":{"name":"Done"}}`,
+			wantValue:  "Done",
+			wantString: "Done",
+		},
+		{
+			name:       "checkbox",
+			json:       `{"type":"checkbox","checkbox":true}`,
+			wantValue:  true,
+			wantString: "true",
+		},
+		{
+			name:       "date",
+			json:       `{"type":"date","date":{"start":"2024-03-01","end":null,"time_zone":null}}`,
+			wantValue:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantString: "2024-03-01",
+		},
+		{
+			name:       "date-time",
+			json:       `{"type":"date","date":{"start":"2024-03-01T09:30:00.000+00:00"}}`,
+			wantValue:  time.Date(2024, 3, 1, 9, 30, 0, 0, time.UTC),
+			wantString: "2024-03-01T09:30:00.000+00:00",
+		},
+		{
+			name:       "url",
+			json:       `{"type":"url","url":"https://example.com"}`,
+			wantValue:  "https://example.com",
+			wantString: "https://example.com",
+		},
+		{
+			name:       "empty url",
+			json:       `{"type":"url","url":null}`,
+			wantValue:  nil,
+			wantString: "",
+		},
+		{
+			name:       "email",
+			json:       `{"type":"email","email":"a@example.com"}`,
+			wantValue:  "a@example.com",
+			wantString: "a@example.com",
+		},
+		{
+			name:       "number",
+			json:       `{"type":"number","number":42.5}`,
+			wantValue:  42.5,
+			wantString: "42.5",
+		},
+		{
+			name:       "empty number",
+			json:       `{"type":"number","number":null}`,
+			wantValue:  nil,
+			wantString: "",
+		},
+		{
+			name:       "relation",
+			json:       `{"type":"relation","relation":[{"id":"page-a"},{"id":"page-b"}],"has_more":false}`,
+			wantValue:  []string{"page-a", "page-b"},
+			wantString: "page-a, page-b",
+		},
+		{
+			name:       "formula string",
+			json:       `{"type":"formula","formula":{"type":"string","string":"tool"}}`,
+			wantValue:  "tool",
+			wantString: "tool",
+		},
+		{
+			name:       "empty formula string",
+			json:       `{"type":"formula","formula":{"type":"string","string":null}}`,
+			wantValue:  nil,
+			wantString: "",
+		},
+		{
+			name:       "formula number",
+			json:       `{"type":"formula","formula":{"type":"number","number":7}}`,
+			wantValue:  7.0,
+			wantString: "7",
+		},
+		{
+			name:       "formula boolean",
+			json:       `{"type":"formula","formula":{"type":"boolean","boolean":true}}`,
+			wantValue:  true,
+			wantString: "true",
+		},
+		{
+			name:       "formula date",
+			json:       `{"type":"formula","formula":{"type":"date","date":{"start":"2024-03-01","end":null}}}`,
+			wantValue:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantString: "2024-03-01",
+		},
+		{
+			name:       "rollup number",
+			json:       `{"type":"rollup","rollup":{"type":"number","number":12.5,"function":"sum"}}`,
+			wantValue:  12.5,
+			wantString: "12.5",
+		},
+		{
+			name:       "rollup date",
+			json:       `{"type":"rollup","rollup":{"type":"date","date":{"start":"2024-03-01"},"function":"latest_date"}}`,
+			wantValue:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+			wantString: "2024-03-01",
+		},
+		{
+			name:       "rollup array",
+			json:       `{"type":"rollup","rollup":{"type":"array","array":[{"type":"title","title":[{"plain_text":"Alpha"}]},{"type":"select","select":{"name":"beta"}}],"function":"show_original"}}`,
+			wantValue:  []string{"Alpha", "beta"},
+			wantString: "Alpha, beta",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prop Property
+			if err := json.Unmarshal([]byte(tt.json), &prop); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+
+			got, err := PropertyValue(prop)
+			if err != nil {
+				t.Fatalf("PropertyValue() failed: %v", err)
+			}
+			if tm, ok := got.(time.Time); ok {
+				if want, _ := tt.wantValue.(time.Time); !tm.Equal(want) {
+					t.Errorf("PropertyValue() = %v, want %v", tm, want)
+				}
+			} else if !reflect.DeepEqual(got, tt.wantValue) {
+				t.Errorf("PropertyValue() = %#v, want %#v", got, tt.wantValue)
+			}
+
+			s, err := AsString(prop)
+			if err != nil {
+				t.Fatalf("AsString() failed: %v", err)
+			}
+			if s != tt.wantString {
+				t.Errorf("AsString() = %q, want %q", s, tt.wantString)
+			}
+		})
+	}
+
+	t.Run("unsupported type", func(t *testing.T) {
+		if _, err := PropertyValue(Property{Type: "people"}); err == nil {
+			t.Error("PropertyValue() on unsupported type should return error")
+		}
+	})
+}
+
+func TestTypedPropertyHelpers(t *testing.T) {
+	number := 3.0
+	text := Property{Type: PropertyTypeRichText, RichText: []RichText{{PlainText: "3"}}}
+
+	t.Run("AsNumber", func(t *testing.T) {
+		if got, err := AsNumber(Property{Type: PropertyTypeNumber, Number: &number}); err != nil || got != 3 {
+			t.Errorf("AsNumber() = %v, %v, want 3, nil", got, err)
+		}
+		if _, err := AsNumber(Property{Type: PropertyTypeNumber}); err == nil {
+			t.Error("AsNumber() on empty number should return error")
+		}
+		if _, err := AsNumber(text); err == nil {
+			t.Error("AsNumber() on rich_text should return error")
+		}
+		rollup := Property{Type: PropertyTypeRollup, Rollup: &Rollup{Type: "number", Number: &number}}
+		if got, err := AsNumber(rollup); err != nil || got != 3 {
+			t.Errorf("AsNumber() on rollup = %v, %v, want 3, nil", got, err)
+		}
+	})
+
+	t.Run("AsBool", func(t *testing.T) {
+		if got, err := AsBool(Property{Type: PropertyTypeCheckbox, Checkbox: true}); err != nil || !got {
+			t.Errorf("AsBool() = %v, %v, want true, nil", got, err)
+		}
+		if _, err := AsBool(text); err == nil {
+			t.Error("AsBool() on rich_text should return error")
+		}
+		formula := Property{Type: PropertyTypeFormula, Formula: &Formula{Type: "boolean", Boolean: true}}
+		if got, err := AsBool(formula); err != nil || !got {
+			t.Errorf("AsBool() on formula = %v, %v, want true, nil", got, err)
+		}
+	})
+
+	t.Run("AsDate", func(t *testing.T) {
+		got, err := AsDate(Property{Type: PropertyTypeDate, Date: &Date{Start: "2024-12-31"}})
+		if err != nil || !got.Equal(time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)) {
+			t.Errorf("AsDate() = %v, %v, want 2024-12-31, nil", got, err)
+		}
+		if _, err := AsDate(Property{Type: PropertyTypeDate, Date: &Date{Start: "soon"}}); err == nil {
+			t.Error("AsDate() on malformed date should return error")
+		}
+		if _, err := AsDate(Property{Type: PropertyTypeDate}); err == nil {
+			t.Error("AsDate() on empty date should return error")
+		}
+	})
+}
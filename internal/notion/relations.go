@@ -0,0 +1,117 @@
+package notion
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// maxRelationFetches is how many related pages a RelationResolver fetches
+// at once.
+const maxRelationFetches = 4
+
+// PageGetter fetches a page by ID. Client implements it.
+type PageGetter interface {
+	GetPage(ctx context.Context, pageID string) (*Page, error)
+}
+
+// RelationResolver looks up the titles of the pages relation properties
+// point to. Titles are cached, so each related page is fetched at most once
+// per resolver however many properties reference it. It is safe for
+// concurrent use.
+type RelationResolver struct {
+	client PageGetter
+	sem    chan struct{}
+
+	mu     sync.Mutex
+	titles map[string]string
+}
+
+// NewRelationResolver returns a RelationResolver fetching pages with client.
+func NewRelationResolver(client PageGetter) *RelationResolver {
+	return &RelationResolver{
+		client: client,
+		sem:    make(chan struct{}, maxRelationFetches),
+		titles: make(map[string]string),
+	}
+}
+
+// Titles returns the titles of the pages a relation property references,
+// in order. Untitled pages yield their ID.
+func (r *RelationResolver) Titles(ctx context.Context, prop Property) ([]string, error) {
+	if prop.Type != PropertyTypeRelation {
+		return nil, fmt.Errorf("property type %q is not a relation", prop.Type)
+	}
+	titles := make([]string, len(prop.Relation))
+	errs := make([]error, len(prop.Relation))
+	var wg sync.WaitGroup
+	for i, rel := range prop.Relation {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			titles[i], errs[i] = r.title(ctx, rel.ID)
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("resolve related page %s: %w", prop.Relation[i].ID, err)
+		}
+	}
+	return titles, nil
+}
+
+// PageTitles returns the titles of the pages each non-empty relation
+// property of page references, keyed by property name.
+func (r *RelationResolver) PageTitles(ctx context.Context, page Page) (map[string][]string, error) {
+	titles := make(map[string][]string)
+	for name, prop := range page.Properties {
+		if prop.Type != PropertyTypeRelation || len(prop.Relation) == 0 {
+			continue
+		}
+		related, err := r.Titles(ctx, prop)
+		if err != nil {
+			return nil, fmt.Errorf("property %s: %w", name, err)
+		}
+		titles[name] = related
+	}
+	return titles, nil
+}
+
+// title returns the title of a page, fetching it unless it is cached.
+func (r *RelationResolver) title(ctx context.Context, pageID string) (string, error) {
+	r.mu.Lock()
+	title, ok := r.titles[pageID]
+	r.mu.Unlock()
+	if ok {
+		return title, nil
+	}
+
+	select {
+	case r.sem <- struct{}{}:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	defer func() { <-r.sem }()
+
+	// Another caller may have fetched the page while this one waited
+	r.mu.Lock()
+	title, ok = r.titles[pageID]
+	r.mu.Unlock()
+	if ok {
+		return title, nil
+	}
+
+	page, err := r.client.GetPage(ctx, pageID)
+	if err != nil {
+		return "", err
+	}
+	title = PageTitle(*page)
+	if title == "" {
+		title = pageID
+	}
+	r.mu.Lock()
+	r.titles[pageID] = title
+	r.mu.Unlock()
+	return title, nil
+}
@@ -0,0 +1,77 @@
+package notion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRelationResolver(t *testing.T) {
+	titles := map[string]string{"page-a": "Alpha", "page-b": "Beta"}
+	var mu sync.Mutex
+	fetches := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/pages/")
+		mu.Lock()
+		fetches[id]++
+		mu.Unlock()
+		title, ok := titles[id]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"object":"error","status":404,"code":"object_not_found","message":"Could not find page."}`))
+			return
+		}
+		fmt.Fprintf(w, `{"object":"page","id":%q,"properties":{"Name":{"type":"title","title":[{"plain_text":%q}]}}}`, id, title)
+	}))
+	defer srv.Close()
+
+	resolver := NewRelationResolver(NewClient("key", "db", "Type", WithBaseURL(srv.URL)))
+	prop := Property{Type: PropertyTypeRelation, Relation: []Relation{{ID: "page-a"}, {ID: "page-b"}}}
+
+	for range 2 {
+		got, err := resolver.Titles(context.Background(), prop)
+		if err != nil {
+			t.Fatalf("Titles() failed: %v", err)
+		}
+		if want := []string{"Alpha", "Beta"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("Titles() = %v, want %v", got, want)
+		}
+	}
+	if want := map[string]int{"page-a": 1, "page-b": 1}; !reflect.DeepEqual(fetches, want) {
+		t.Errorf("fetches = %v, want each page fetched once", fetches)
+	}
+
+	t.Run("Page titles", func(t *testing.T) {
+		page := Page{Properties: map[string]Property{
+			"Name":     {Type: PropertyTypeTitle, Title: []Title{{PlainText: "Plan"}}},
+			"Projects": {Type: PropertyTypeRelation, Relation: []Relation{{ID: "page-b"}}},
+			"Blockers": {Type: PropertyTypeRelation},
+		}}
+		got, err := resolver.PageTitles(context.Background(), page)
+		if err != nil {
+			t.Fatalf("PageTitles() failed: %v", err)
+		}
+		if want := map[string][]string{"Projects": {"Beta"}}; !reflect.DeepEqual(got, want) {
+			t.Errorf("PageTitles() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("Missing page", func(t *testing.T) {
+		missing := Property{Type: PropertyTypeRelation, Relation: []Relation{{ID: "page-a"}, {ID: "gone"}}}
+		if _, err := resolver.Titles(context.Background(), missing); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Titles() error = %v, want ErrNotFound", err)
+		}
+	})
+
+	t.Run("Not a relation", func(t *testing.T) {
+		if _, err := resolver.Titles(context.Background(), Property{Type: PropertyTypeTitle}); err == nil {
+			t.Error("Titles() on a title property should return error")
+		}
+	})
+}
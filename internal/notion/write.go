@@ -0,0 +1,234 @@
+package notion
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+)
+
+// maxTextLength is the most characters Notion accepts in one rich text
+// object.
+const maxTextLength = 2000
+
+// NewRichText returns a plain text rich text object.
+func NewRichText(text string) RichText {
+	return RichText{Type: "text", Text: Text{Content: text}, PlainText: text}
+}
+
+// NewCodeBlock returns a code block to append with AppendBlockChildren.
+// Text longer than Notion accepts keeps its end, where errors usually are.
+func NewCodeBlock(language, text, caption string) Block {
+	if runes := []rune(text); len(runes) > maxTextLength {
+		text = string(runes[len(runes)-maxTextLength:])
+	}
+	code := CodeBlock{Language: language, RichText: []RichText{NewRichText(text)}}
+	if caption != "" {
+		code.Caption = []RichText{NewRichText(caption)}
+	}
+	return Block{Type: BlockTypeCode, Content: code}
+}
+
+// AppendBlockChildren appends blocks to the end of a page or block. Only
+// the blocks' types, contents and children are sent; IDs, timestamps and
+// other read-only fields are left out.
+func (c *Client) AppendBlockChildren(ctx context.Context, blockID string, blocks []Block) error {
+	children := make([]map[string]any, 0, len(blocks))
+	for _, block := range blocks {
+		child, err := blockRequest(block)
+		if err != nil {
+			return err
+		}
+		children = append(children, child)
+	}
+	body, err := json.Marshal(map[string]any{"children": children})
+	if err != nil {
+		return fmt.Errorf("marshal blocks: %w", err)
+	}
+	url := fmt.Sprintf("%s/blocks/%s/children", c.baseURL, blockID)
+	return c.doRequest(ctx, "PATCH", url, bytes.NewReader(body), nil)
+}
+
+// UpdatePageProperties sets the given properties of a page, keyed by
+// property name, leaving the others unchanged. Each property is sent as its
+// Type's value.
+func (c *Client) UpdatePageProperties(ctx context.Context, pageID string, props map[string]Property) error {
+	values := make(map[string]any, len(props))
+	for name, prop := range props {
+		value, err := propertyValue(prop)
+		if err != nil {
+			return fmt.Errorf("property %q: %w", name, err)
+		}
+		values[name] = value
+	}
+	body, err := json.Marshal(map[string]any{"properties": values})
+	if err != nil {
+		return fmt.Errorf("marshal properties: %w", err)
+	}
+	url := fmt.Sprintf("%s/pages/%s", c.baseURL, pageID)
+	return c.doRequest(ctx, "PATCH", url, bytes.NewReader(body), nil)
+}
+
+// CreateDatabase creates a database under the page parentPageID that the
+// server can serve: a Name title, a Description text property and a select
+// property named after the client's type field with typeValues as options.
+// It returns the new database's ID.
+func (c *Client) CreateDatabase(ctx context.Context, parentPageID, title string, typeValues ...string) (string, error) {
+	options := make([]map[string]string, 0, len(typeValues))
+	for _, value := range typeValues {
+		options = append(options, map[string]string{"name": value})
+	}
+	reqBody := map[string]any{
+		"parent": map[string]string{"type": "page_id", "page_id": parentPageID},
+		"title":  []map[string]any{{"type": "text", "text": map[string]string{"content": title}}},
+		"properties": map[string]any{
+			"Name":        map[string]any{"title": map[string]any{}},
+			"Description": map[string]any{"rich_text": map[string]any{}},
+			c.typeField:   map[string]any{"select": map[string]any{"options": options}},
+		},
+	}
+	body, err := json.Marshal(reqBody)
+	if err != nil {
+		return "", fmt.Errorf("marshal database: %w", err)
+	}
+
+	var resp struct {
+		ID string `json:"id"`
+	}
+	url := fmt.Sprintf("%s/databases", c.baseURL)
+	if err := c.doRequest(ctx, "POST", url, bytes.NewReader(body), &resp); err != nil {
+		return "", err
+	}
+	return resp.ID, nil
+}
+
+// blockRequest returns the block object Notion expects when creating b.
+func blockRequest(b Block) (map[string]any, error) {
+	if b.Type == "" {
+		return nil, fmt.Errorf("block has no type")
+	}
+	var content map[string]any
+	switch c := b.Content.(type) {
+	case Paragraph:
+		content = paragraphRequest(c)
+	case CodeBlock:
+		content = map[string]any{
+			"language":  c.Language,
+			"rich_text": richTextRequest(c.RichText),
+			"caption":   richTextRequest(c.Caption),
+		}
+	case map[string]any:
+		content = maps.Clone(c)
+	case nil:
+		content = map[string]any{}
+		if b.Paragraph != nil {
+			content = paragraphRequest(*b.Paragraph)
+		}
+	default:
+		// Other typed contents and raw JSON are sent as they decode
+		data, err := json.Marshal(c)
+		if err != nil {
+			return nil, fmt.Errorf("marshal %s block: %w", b.Type, err)
+		}
+		if err := json.Unmarshal(data, &content); err != nil {
+			return nil, fmt.Errorf("marshal %s block: %w", b.Type, err)
+		}
+	}
+
+	if len(b.Children) > 0 {
+		children := make([]map[string]any, 0, len(b.Children))
+		for _, child := range b.Children {
+			request, err := blockRequest(child)
+			if err != nil {
+				return nil, err
+			}
+			children = append(children, request)
+		}
+		content["children"] = children
+	}
+	return map[string]any{"object": "block", "type": string(b.Type), string(b.Type): content}, nil
+}
+
+// paragraphRequest returns the paragraph object of a block request.
+func paragraphRequest(p Paragraph) map[string]any {
+	content := map[string]any{"rich_text": richTextRequest(p.RichText)}
+	if p.Color != "" {
+		content["color"] = p.Color
+	}
+	return content
+}
+
+// richTextRequest returns rich text as Notion expects it in requests: text
+// objects with their annotations and links.
+func richTextRequest(texts []RichText) []map[string]any {
+	request := make([]map[string]any, 0, len(texts))
+	for _, rt := range texts {
+		content := rt.Text.Content
+		if content == "" {
+			content = rt.PlainText
+		}
+		text := map[string]any{"content": content}
+		if rt.Text.Link != nil {
+			text["link"] = map[string]string{"url": rt.Text.Link.URL}
+		}
+		request = append(request, map[string]any{
+			"type":        "text",
+			"text":        text,
+			"annotations": rt.Annotations,
+		})
+	}
+	return request
+}
+
+// propertyValue returns the property value object Notion expects when
+// updating a page property of prop's type.
+func propertyValue(prop Property) (map[string]any, error) {
+	option := func(s *Select) any {
+		if s == nil {
+			return nil
+		}
+		return map[string]string{"name": s.Name}
+	}
+	switch prop.Type {
+	case PropertyTypeTitle:
+		texts := make([]RichText, 0, len(prop.Title))
+		for _, t := range prop.Title {
+			texts = append(texts, RichText{Text: t.Text, Annotations: t.Annotations, PlainText: t.PlainText})
+		}
+		return map[string]any{"title": richTextRequest(texts)}, nil
+	case PropertyTypeRichText:
+		return map[string]any{"rich_text": richTextRequest(prop.RichText)}, nil
+	case PropertyTypeSelect:
+		return map[string]any{"select": option(prop.Select)}, nil
+	case PropertyTypeStatus:
+		return map[string]any{"status": option(prop.Status)}, nil
+	case PropertyTypeMultiSelect:
+		options := make([]any, 0, len(prop.MultiSelect))
+		for _, s := range prop.MultiSelect {
+			options = append(options, option(&s))
+		}
+		return map[string]any{"multi_select": options}, nil
+	case PropertyTypeCheckbox:
+		return map[string]any{"checkbox": prop.Checkbox}, nil
+	case PropertyTypeNumber:
+		return map[string]any{"number": prop.Number}, nil
+	case PropertyTypeURL:
+		return map[string]any{"url": prop.URL}, nil
+	case PropertyTypeEmail:
+		return map[string]any{"email": prop.Email}, nil
+	case PropertyTypeDate:
+		if prop.Date == nil {
+			return map[string]any{"date": nil}, nil
+		}
+		date := map[string]any{"start": prop.Date.Start}
+		if prop.Date.End != "" {
+			date["end"] = prop.Date.End
+		}
+		if prop.Date.TimeZone != "" {
+			date["time_zone"] = prop.Date.TimeZone
+		}
+		return map[string]any{"date": date}, nil
+	}
+	return nil, fmt.Errorf("unsupported property type %q", prop.Type)
+}
@@ -0,0 +1,145 @@
+package notion
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// recordServer records the method, path and decoded JSON body of the last
+// request it serves.
+type recordServer struct {
+	*httptest.Server
+	method, path string
+	body         map[string]any
+}
+
+func newRecordServer(t *testing.T) *recordServer {
+	rs := &recordServer{}
+	rs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs.method, rs.path = r.Method, r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&rs.body); err != nil {
+			t.Errorf("decode request body: %v", err)
+		}
+		w.Write([]byte(`{"object":"list","results":[]}`))
+	}))
+	t.Cleanup(rs.Close)
+	return rs
+}
+
+// decodeJSON decodes a JSON literal for comparison with a request body.
+func decodeJSON(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var v map[string]any
+	if err := json.Unmarshal([]byte(s), &v); err != nil {
+		t.Fatalf("decode %s: %v", s, err)
+	}
+	return v
+}
+
+func TestAppendBlockChildren(t *testing.T) {
+	srv := newRecordServer(t)
+	c := NewClient("key", "db", "Type", WithBaseURL(srv.URL))
+
+	bold := NewRichText("Note")
+	bold.Annotations.Bold = true
+	read := Block{
+		Object: "block", ID: "read-only-id", Type: BlockTypeParagraph, HasChildren: true,
+		Paragraph: &Paragraph{RichText: []RichText{bold}},
+		Content:   Paragraph{RichText: []RichText{bold}},
+		Children: []Block{
+			{Type: BlockTypeBulletedListItem, Content: map[string]any{"rich_text": []any{map[string]any{"type": "text", "text": map[string]any{"content": "item"}}}}},
+		},
+	}
+	err := c.AppendBlockChildren(context.Background(), "page-1", []Block{
+		NewCodeBlock("plain text", "hello output", "Run at noon"),
+		read,
+	})
+	if err != nil {
+		t.Fatalf("AppendBlockChildren() failed: %v", err)
+	}
+
+	if srv.method != http.MethodPatch || srv.path != "/blocks/page-1/children" {
+		t.Errorf("request = %s %s, want PATCH /blocks/page-1/children", srv.method, srv.path)
+	}
+	plain := `{"bold": false, "italic": false, "strikethrough": false, "underline": false, "code": false}`
+	want := decodeJSON(t, `{"children": [
+		{"object": "block", "type": "code", "code": {
+			"language": "plain text",
+			"rich_text": [{"type": "text", "text": {"content": "hello output"}, "annotations": `+plain+`}],
+			"caption": [{"type": "text", "text": {"content": "Run at noon"}, "annotations": `+plain+`}]
+		}},
+		{"object": "block", "type": "paragraph", "paragraph": {
+			"rich_text": [{"type": "text", "text": {"content": "Note"}, "annotations": {"bold": true, "italic": false, "strikethrough": false, "underline": false, "code": false}}],
+			"children": [{"object": "block", "type": "bulleted_list_item", "bulleted_list_item": {
+				"rich_text": [{"type": "text", "text": {"content": "item"}}]
+			}}]
+		}}
+	]}`)
+	if !reflect.DeepEqual(srv.body, want) {
+		got, _ := json.MarshalIndent(srv.body, "", "  ")
+		t.Errorf("request body =\n%s", got)
+	}
+}
+
+func TestNewCodeBlock(t *testing.T) {
+	// Long output keeps its end, within Notion's limit
+	block := NewCodeBlock("plain text", strings.Repeat("x", 2500)+"tail", "")
+	code := block.Content.(CodeBlock)
+	if got := code.RichText[0].Text.Content; len(got) != maxTextLength || !strings.HasSuffix(got, "tail") {
+		t.Errorf("content has %d characters, want the last %d", len(got), maxTextLength)
+	}
+	if code.Caption != nil {
+		t.Errorf("caption = %v, want none", code.Caption)
+	}
+}
+
+func TestUpdatePageProperties(t *testing.T) {
+	srv := newRecordServer(t)
+	c := NewClient("key", "db", "Type", WithBaseURL(srv.URL))
+
+	count := 3.0
+	link := "https://example.com"
+	err := c.UpdatePageProperties(context.Background(), "page-1", map[string]Property{
+		"Name":   {Type: PropertyTypeTitle, Title: []Title{{Text: Text{Content: "Renamed"}}}},
+		"Status": {Type: PropertyTypeSelect, Select: &Select{ID: "opt-1", Name: "Done", Color: "green"}},
+		"Tags":   {Type: PropertyTypeMultiSelect, MultiSelect: []Select{{Name: "a"}, {Name: "b"}}},
+		"Runs":   {Type: PropertyTypeNumber, Number: &count},
+		"Active": {Type: PropertyTypeCheckbox, Checkbox: true},
+		"Due":    {Type: PropertyTypeDate, Date: &Date{Start: "2025-01-01"}},
+		"Link":   {Type: PropertyTypeURL, URL: &link},
+		"Owner":  {Type: PropertyTypeEmail},
+	})
+	if err != nil {
+		t.Fatalf("UpdatePageProperties() failed: %v", err)
+	}
+
+	if srv.method != http.MethodPatch || srv.path != "/pages/page-1" {
+		t.Errorf("request = %s %s, want PATCH /pages/page-1", srv.method, srv.path)
+	}
+	want := decodeJSON(t, `{"properties": {
+		"Name": {"title": [{"type": "text", "text": {"content": "Renamed"}, "annotations": {"bold": false, "italic": false, "strikethrough": false, "underline": false, "code": false}}]},
+		"Status": {"select": {"name": "Done"}},
+		"Tags": {"multi_select": [{"name": "a"}, {"name": "b"}]},
+		"Runs": {"number": 3},
+		"Active": {"checkbox": true},
+		"Due": {"date": {"start": "2025-01-01"}},
+		"Link": {"url": "https://example.com"},
+		"Owner": {"email": null}
+	}}`)
+	if !reflect.DeepEqual(srv.body, want) {
+		got, _ := json.MarshalIndent(srv.body, "", "  ")
+		t.Errorf("request body =\n%s", got)
+	}
+
+	t.Run("Unsupported type", func(t *testing.T) {
+		err := c.UpdatePageProperties(context.Background(), "page-1", map[string]Property{"Files": {Type: "files"}})
+		if err == nil || !strings.Contains(err.Error(), `"Files"`) {
+			t.Errorf("UpdatePageProperties() error = %v, want an unsupported property error", err)
+		}
+	})
+}
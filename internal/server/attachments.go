@@ -0,0 +1,300 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/nixihz/notion-as-mcp/internal/cache"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+)
+
+// attachmentClient downloads the files resource pages consist of.
+var attachmentClient = &http.Client{Timeout: 30 * time.Second}
+
+// blob is a downloaded attachment, as cached.
+type blob struct {
+	MIMEType string `json:"mime_type"`
+	Data     []byte `json:"data"`
+}
+
+// resourceContents returns the contents of a resource page: the file it
+// consists of, if it is just one file, PDF or image no larger than
+// RESOURCE_MAX_BLOB_BYTES, else its markdown, in which files are links.
+func (s *Server) resourceContents(ctx context.Context, page notion.Page) (*mcp.ResourceContents, error) {
+	uri := resourceURI(page)
+	if data, err := s.cache.Get(ctx, cache.CacheKeyBlobPrefix+page.ID); err == nil && data != nil {
+		var b blob
+		if json.Unmarshal(data, &b) == nil {
+			return &mcp.ResourceContents{URI: uri, MIMEType: b.MIMEType, Blob: b.Data}, nil
+		}
+	}
+	if data, err := s.cache.Get(ctx, cache.CacheKeyRenderPrefix+page.ID); err == nil && data != nil {
+		return textContents(uri, string(data)), nil
+	}
+
+	content, err := s.client.GetPageContent(ctx, page.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching content: %w", err)
+	}
+	if src, ok := attachmentURL(content); ok && s.cfg.ResourceMaxBlobBytes > 0 {
+		b, err := downloadAttachment(ctx, src, s.cfg.ResourceMaxBlobBytes)
+		if err == nil {
+			s.cacheBlob(ctx, content.Page, b)
+			return &mcp.ResourceContents{URI: uri, MIMEType: b.MIMEType, Blob: b.Data}, nil
+		}
+		s.logger.Warn("serving attachment as a link", slog.String("page_id", page.ID), slog.String("error", err.Error()))
+	}
+	return textContents(uri, s.renderContent(ctx, page.ID, content)), nil
+}
+
+// cacheBlob caches a page's downloaded attachment for the page's TTL.
+func (s *Server) cacheBlob(ctx context.Context, page notion.Page, b *blob) {
+	data, err := json.Marshal(b)
+	if err != nil {
+		return
+	}
+	if err := s.cache.Set(ctx, cache.CacheKeyBlobPrefix+page.ID, data, pageCacheTTL(page, s.cfg.CacheTTL)); err != nil {
+		s.logger.Warn("failed to cache attachment", slog.String("page_id", page.ID), slog.String("error", err.Error()))
+	}
+}
+
+// attachmentURL returns the URL of the file, PDF or image a page consists
+// of, ignoring empty paragraphs around it.
+func attachmentURL(content *notion.PageContent) (string, bool) {
+	var attachment *notion.Block
+	for i, block := range content.Blocks {
+		switch block.Type {
+		case notion.BlockTypeFile, notion.BlockTypePDF, notion.BlockTypeImage:
+			if attachment != nil {
+				return "", false
+			}
+			attachment = &content.Blocks[i]
+		case notion.BlockTypeParagraph:
+			if strings.TrimSpace(notion.ExtractText([]notion.Block{block})) != "" {
+				return "", false
+			}
+		default:
+			return "", false
+		}
+	}
+	if attachment == nil {
+		return "", false
+	}
+	return notion.FileURL(*attachment)
+}
+
+// downloadAttachment fetches src, failing if it is larger than maxSize
+// bytes. The MIME type comes from the response, else the URL's extension,
+// else the content itself.
+func downloadAttachment(ctx context.Context, src string, maxSize int) (*blob, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src, nil)
+	if err != nil {
+		return nil, fmt.Errorf("download attachment: %w", err)
+	}
+	resp, err := attachmentClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("download attachment: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("download attachment: %s", resp.Status)
+	}
+	if resp.ContentLength > int64(maxSize) {
+		return nil, fmt.Errorf("attachment larger than %d bytes", maxSize)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("download attachment: %w", err)
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("attachment larger than %d bytes", maxSize)
+	}
+
+	mimeType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = ""
+		if u, err := url.Parse(src); err == nil {
+			mimeType, _, _ = mime.ParseMediaType(mime.TypeByExtension(path.Ext(u.Path)))
+		}
+	}
+	if mimeType == "" {
+		mimeType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+	return &blob{MIMEType: mimeType, Data: data}, nil
+}
+
+// maxCodeFileBytes is the largest code file a tool page may attach.
+const maxCodeFileBytes = 1 << 20
+
+// codeFileLanguages maps the extensions of code files tool pages may attach
+// to the language they run as.
+var codeFileLanguages = map[string]string{
+	".sh":  "bash",
+	".py":  "python",
+	".js":  "js",
+	".mjs": "js",
+	".ts":  "ts",
+	".rb":  "ruby",
+	".go":  "go",
+	".php": "php",
+}
+
+// codeFile is a code file attached to a tool page.
+type codeFile struct {
+	url      string
+	language string
+}
+
+// codeFile returns the first file attached to a page whose extension maps
+// to a language the executor allows.
+func (s *Server) codeFile(content *notion.PageContent) (codeFile, bool) {
+	for _, block := range content.Blocks {
+		if block.Type != notion.BlockTypeFile {
+			continue
+		}
+		src, ok := notion.FileURL(block)
+		if !ok {
+			continue
+		}
+		fields, _ := block.Content.(map[string]any)
+		name, _ := fields["name"].(string)
+		if name == "" {
+			if u, err := url.Parse(src); err == nil {
+				name = u.Path
+			}
+		}
+		language, ok := codeFileLanguages[strings.ToLower(path.Ext(name))]
+		if ok && s.executor.Supports(language) {
+			return codeFile{url: src, language: language}, true
+		}
+	}
+	return codeFile{}, false
+}
+
+// loadCodeFile downloads the code file attached to a tool page, fetching
+// the page for a fresh file URL.
+func (s *Server) loadCodeFile(ctx context.Context, pageID string) (string, error) {
+	content, err := s.client.GetPageContent(ctx, pageID)
+	if err != nil {
+		return "", fmt.Errorf("error fetching content: %w", err)
+	}
+	file, ok := s.codeFile(content)
+	if !ok {
+		return "", fmt.Errorf("no code file attached")
+	}
+	b, err := downloadAttachment(ctx, file.url, maxCodeFileBytes)
+	if err != nil {
+		return "", fmt.Errorf("code file: %w", err)
+	}
+	return string(b.Data), nil
+}
+
+// promptImages swaps each image block of blocks, nested ones included, for
+// a paragraph holding a marker unique to this call, returning the new
+// blocks and the image blocks by marker. Images are fetched only from these
+// blocks: markdown in prompt arguments can't guess a marker, so it can
+// never make the server fetch a URL.
+func promptImages(blocks []notion.Block) ([]notion.Block, map[string]notion.Block) {
+	images := make(map[string]notion.Block)
+	nonce := rand.Text()
+	var swap func(blocks []notion.Block) []notion.Block
+	swap = func(blocks []notion.Block) []notion.Block {
+		out := make([]notion.Block, len(blocks))
+		for i, block := range blocks {
+			if block.Type == notion.BlockTypeImage {
+				marker := fmt.Sprintf("prompt-image-%s-%d", nonce, len(images))
+				images[marker] = block
+				rt := []notion.RichText{{Type: "text", Text: notion.Text{Content: marker}, PlainText: marker}}
+				out[i] = notion.Block{Type: notion.BlockTypeParagraph, Content: notion.Paragraph{RichText: rt}}
+				continue
+			}
+			block.Children = swap(block.Children)
+			out[i] = block
+		}
+		return out
+	}
+	return swap(blocks), images
+}
+
+// withPromptImages splits text messages at the image markers promptImages
+// left, sending each image as an image message of the same role in its
+// place. Images larger than PROMPT_IMAGE_MAX_BYTES or failing to load are
+// written as Markdown links instead.
+func (s *Server) withPromptImages(ctx context.Context, messages []*mcp.PromptMessage, images map[string]notion.Block) []*mcp.PromptMessage {
+	if len(images) == 0 {
+		return messages
+	}
+	var out []*mcp.PromptMessage
+	for _, message := range messages {
+		text, ok := message.Content.(*mcp.TextContent)
+		if !ok {
+			out = append(out, message)
+			continue
+		}
+
+		var (
+			parts []*mcp.PromptMessage
+			body  strings.Builder
+		)
+		flush := func() {
+			if t := strings.TrimSpace(body.String()); t != "" {
+				parts = append(parts, &mcp.PromptMessage{Role: message.Role, Content: &mcp.TextContent{Text: t}})
+			}
+			body.Reset()
+		}
+		for _, line := range strings.SplitAfter(text.Text, "\n") {
+			marker := strings.TrimSpace(line)
+			block, ok := images[marker]
+			if !ok {
+				body.WriteString(line)
+				continue
+			}
+			b, err := s.loadPromptImage(ctx, block)
+			if err != nil {
+				s.logger.Warn("sending prompt image as a link", slog.String("error", err.Error()))
+				link := s.pageToMarkdown(&notion.PageContent{Blocks: []notion.Block{block}})
+				body.WriteString(strings.Replace(line, marker, link, 1))
+				continue
+			}
+			flush()
+			parts = append(parts, &mcp.PromptMessage{Role: message.Role, Content: &mcp.ImageContent{MIMEType: b.MIMEType, Data: b.Data}})
+		}
+		flush()
+		out = append(out, parts...)
+	}
+	return out
+}
+
+// loadPromptImage downloads the file of an image block.
+func (s *Server) loadPromptImage(ctx context.Context, block notion.Block) (*blob, error) {
+	maxSize := s.cfg.PromptImageMaxBytes
+	src, ok := notion.FileURL(block)
+	if !ok || !(strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://")) {
+		return nil, fmt.Errorf("unsupported image source %q", src)
+	}
+	b, err := downloadAttachment(ctx, src, maxSize)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.Data) > maxSize {
+		return nil, fmt.Errorf("image larger than %d bytes", maxSize)
+	}
+	if !strings.HasPrefix(b.MIMEType, "image/") {
+		return nil, fmt.Errorf("not an image: %s", cmp.Or(b.MIMEType, "unknown type"))
+	}
+	return b, nil
+}
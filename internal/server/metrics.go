@@ -0,0 +1,220 @@
+package server
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/nixihz/notion-as-mcp/internal/cache"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+	"github.com/nixihz/notion-as-mcp/internal/tools"
+)
+
+// Tool execution outcomes reported by the metrics.
+const (
+	outcomeSuccess = "success" // exit code 0
+	outcomeFailure = "failure" // nonzero exit code
+	outcomeError   = "error"   // the tool could not run, timed out or was cancelled
+)
+
+// Metrics counts what the server has served and renders the counts in the
+// Prometheus text format. A nil *Metrics records nothing, so callers need
+// not check whether METRICS_ADDR is set.
+type Metrics struct {
+	mu          sync.Mutex
+	requests    map[string]int64    // by MCP method
+	executions  map[[2]string]int64 // by language and outcome
+	cacheHits   int64
+	cacheMisses int64
+	// notion aggregates Notion API requests; register its Observe with
+	// notion.WithObserver
+	notion *notion.ClientMetrics
+	// refreshHealth reports the list cache refresh loops, if set
+	refreshHealth func() []cache.RefreshHealth
+}
+
+// NewMetrics creates an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{
+		requests:   make(map[string]int64),
+		executions: make(map[[2]string]int64),
+		notion:     notion.NewClientMetrics(),
+	}
+}
+
+// middleware counts every MCP request by method.
+func (m *Metrics) middleware() mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if m != nil {
+				m.mu.Lock()
+				m.requests[method]++
+				m.mu.Unlock()
+			}
+			return next(ctx, method, req)
+		}
+	}
+}
+
+// recordExecution counts a tool run in language by its outcome.
+func (m *Metrics) recordExecution(language string, result *tools.ExecutionResult, err error) {
+	if m == nil {
+		return
+	}
+	outcome := outcomeSuccess
+	switch {
+	case err != nil || result == nil || result.Error != "":
+		outcome = outcomeError
+	case result.ExitCode != 0:
+		outcome = outcomeFailure
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.executions[[2]string{language, outcome}]++
+}
+
+// recordCache counts a cache read as a hit or a miss.
+func (m *Metrics) recordCache(hit bool) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if hit {
+		m.cacheHits++
+	} else {
+		m.cacheMisses++
+	}
+}
+
+// ServeHTTP writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var sb strings.Builder
+	if m != nil {
+		m.mu.Lock()
+		writeMetric(&sb, "notion_mcp_requests_total", "counter", "MCP requests handled, by method.")
+		for _, method := range slices.Sorted(maps.Keys(m.requests)) {
+			fmt.Fprintf(&sb, "notion_mcp_requests_total{method=%s} %d\n", labelValue(method), m.requests[method])
+		}
+		writeMetric(&sb, "notion_mcp_tool_executions_total", "counter", "Tool executions, by language and outcome.")
+		for _, key := range slices.SortedFunc(maps.Keys(m.executions), compareKeys) {
+			fmt.Fprintf(&sb, "notion_mcp_tool_executions_total{language=%s,outcome=%s} %d\n",
+				labelValue(key[0]), labelValue(key[1]), m.executions[key])
+		}
+		writeMetric(&sb, "notion_mcp_cache_requests_total", "counter", "Cache reads, by result.")
+		fmt.Fprintf(&sb, "notion_mcp_cache_requests_total{result=\"hit\"} %d\n", m.cacheHits)
+		fmt.Fprintf(&sb, "notion_mcp_cache_requests_total{result=\"miss\"} %d\n", m.cacheMisses)
+		m.mu.Unlock()
+
+		endpoints := m.notion.Snapshot()
+		keys := slices.Sorted(maps.Keys(endpoints))
+		writeMetric(&sb, "notion_mcp_notion_requests_total", "counter", "Notion API request attempts, by endpoint.")
+		for _, key := range keys {
+			fmt.Fprintf(&sb, "notion_mcp_notion_requests_total{endpoint=%s} %d\n", labelValue(key), endpoints[key].Attempts)
+		}
+		writeMetric(&sb, "notion_mcp_notion_request_errors_total", "counter", "Notion API request attempts that failed, by endpoint.")
+		for _, key := range keys {
+			fmt.Fprintf(&sb, "notion_mcp_notion_request_errors_total{endpoint=%s} %d\n", labelValue(key), endpoints[key].Errors)
+		}
+		writeMetric(&sb, "notion_mcp_notion_request_duration_seconds", "summary", "Time spent in Notion API request attempts, by endpoint.")
+		for _, key := range keys {
+			fmt.Fprintf(&sb, "notion_mcp_notion_request_duration_seconds_sum{endpoint=%s} %g\n", labelValue(key), endpoints[key].Latency.Seconds())
+			fmt.Fprintf(&sb, "notion_mcp_notion_request_duration_seconds_count{endpoint=%s} %d\n", labelValue(key), endpoints[key].Attempts)
+		}
+
+		if m.refreshHealth != nil {
+			health := m.refreshHealth()
+			writeMetric(&sb, "notion_mcp_cache_refresh_healthy", "gauge", "Whether a list cache refresh succeeded within twice its interval, by key.")
+			for _, h := range health {
+				fmt.Fprintf(&sb, "notion_mcp_cache_refresh_healthy{key=%s} %d\n", labelValue(h.Key), boolValue(h.Healthy))
+			}
+			writeMetric(&sb, "notion_mcp_cache_refresh_last_attempt_timestamp_seconds", "gauge", "When a list cache refresh was last attempted, by key.")
+			for _, h := range health {
+				fmt.Fprintf(&sb, "notion_mcp_cache_refresh_last_attempt_timestamp_seconds{key=%s} %d\n", labelValue(h.Key), h.LastAttempt.Unix())
+			}
+			writeMetric(&sb, "notion_mcp_cache_refresh_last_success_timestamp_seconds", "gauge", "When a list cache refresh last succeeded, by key.")
+			for _, h := range health {
+				fmt.Fprintf(&sb, "notion_mcp_cache_refresh_last_success_timestamp_seconds{key=%s} %d\n", labelValue(h.Key), h.LastRefresh.Unix())
+			}
+		}
+	}
+	n, err := io.WriteString(w, sb.String())
+	return int64(n), err
+}
+
+// writeMetric writes the HELP and TYPE lines of a metric.
+func writeMetric(sb *strings.Builder, name, typ, help string) {
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// compareKeys orders execution keys by language, then outcome.
+func compareKeys(a, b [2]string) int {
+	return cmp.Or(strings.Compare(a[0], b[0]), strings.Compare(a[1], b[1]))
+}
+
+// boolValue renders a boolean gauge value.
+func boolValue(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// labelEscaper escapes a Prometheus label value.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelValue quotes a Prometheus label value.
+func labelValue(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+// serveMetrics serves the metrics on METRICS_ADDR at /metrics until ctx is
+// done. A listener that fails is logged and does not stop the server.
+func (s *Server) serveMetrics(ctx context.Context) {
+	if s.cfg.MetricsAddr == "" || s.metrics == nil {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", s.metrics)
+	srv := &http.Server{Addr: s.cfg.MetricsAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	s.logger.Info("serving metrics", slog.String("addr", s.cfg.MetricsAddr))
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Warn("metrics listener failed", slog.String("error", err.Error()))
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+}
+
+// countingCache is a cache.Cache that records its reads as hits or misses.
+type countingCache struct {
+	cache.Cache
+	metrics *Metrics
+}
+
+// Get retrieves a value by key, recording whether it was found.
+func (c countingCache) Get(ctx context.Context, key string) ([]byte, error) {
+	value, err := c.Cache.Get(ctx, key)
+	c.metrics.recordCache(err == nil && value != nil)
+	return value, err
+}
@@ -0,0 +1,80 @@
+package server
+
+import (
+	"cmp"
+	"strings"
+
+	"github.com/mozillazg/go-unidecode"
+
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+)
+
+// defaultNamePrefix is the prefix for page kinds NAME_PREFIXES sets none for.
+const defaultNamePrefix = "p_"
+
+// NameSanitizer turns page titles into MCP names, which must match
+// ^[a-z][a-z0-9_-]*$. A title is transliterated, trimmed and lowercased;
+// spaces and tabs after the first kept character become underscores, other
+// characters outside [a-z0-9_-] are dropped, and a name that would then
+// start with a digit, underscore or hyphen gets the prefix of its page
+// kind. The prefix is applied here only, so a name is never prefixed twice.
+type NameSanitizer struct {
+	// Prefixes maps a page kind to its prefix; kinds without one use
+	// defaultNamePrefix.
+	Prefixes map[string]string
+}
+
+// Name returns the MCP name for a page of kind titled title, or "" if the
+// title yields no name.
+func (n NameSanitizer) Name(kind, title string) string {
+	var sb strings.Builder
+	for _, c := range strings.ToLower(strings.TrimSpace(transliterate(title))) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '_', c == '-':
+			sb.WriteRune(c)
+		case (c == ' ' || c == '\t') && sb.Len() > 0:
+			sb.WriteRune('_')
+		}
+	}
+	name := sb.String()
+	if name == "" {
+		return ""
+	}
+	if name[0] < 'a' || name[0] > 'z' {
+		name = cmp.Or(n.Prefixes[kind], defaultNamePrefix) + name
+	}
+	return name
+}
+
+// transliterate romanizes s to ASCII, so "Crème Brûlée" becomes
+// "Creme Brulee", "数据分析" becomes "Shu Ju Fen Xi" and kana and hangul are
+// spelled out too. Characters without a romanization, such as emoji, are
+// dropped.
+func transliterate(s string) string {
+	return unidecode.Unidecode(s)
+}
+
+// names returns the NameSanitizer configured by NAME_PREFIXES.
+func (s *Server) names() NameSanitizer {
+	prefixes := make(map[string]string)
+	for _, kind := range []string{pageTypePrompt, pageTypeResource, pageTypeTool} {
+		if prefix := s.cfg.NamePrefix(kind); prefix != "" {
+			prefixes[kind] = prefix
+		}
+	}
+	return NameSanitizer{Prefixes: prefixes}
+}
+
+// pageName returns the MCP name of a page of kind. A title that yields no
+// name, such as one made only of emoji, falls back to the kind's prefix and
+// the shortened page ID; pageName returns "" only for a page without an ID.
+func (s *Server) pageName(kind string, page notion.Page) string {
+	names := s.names()
+	if name := names.Name(kind, getPageTitle(page)); name != "" {
+		return name
+	}
+	if page.ID == "" {
+		return ""
+	}
+	return cmp.Or(names.Prefixes[kind], defaultNamePrefix) + shortID(page.ID)
+}
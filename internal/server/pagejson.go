@@ -0,0 +1,96 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+)
+
+// pageJSONTemplate is the URI template under which each resource page is
+// also served as JSON: its properties and block tree rather than rendered
+// Markdown.
+const pageJSONTemplate = resourceScheme + "{id}.json"
+
+// mimeJSON is the MIME type of page JSON.
+const mimeJSON = "application/json"
+
+// addPageJSONTemplate registers pageJSONTemplate on server.
+func (s *Server) addPageJSONTemplate(server *mcp.Server) {
+	server.AddResourceTemplate(&mcp.ResourceTemplate{
+		URITemplate: pageJSONTemplate,
+		Name:        "page_json",
+		Description: "A resource page's properties and blocks as JSON, for clients that process the block structure themselves",
+		MIMEType:    mimeJSON,
+	}, s.readPageJSON)
+}
+
+// readPageJSON serves a resource page as JSON. Only registered resource
+// pages are found, so the template exposes nothing the resource list
+// doesn't, whatever other pages the integration can read.
+func (s *Server) readPageJSON(ctx context.Context, request *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
+	uri := request.Params.URI
+	id, ok := strings.CutSuffix(strings.TrimPrefix(uri, resourceScheme), ".json")
+	if !ok || id == "" || strings.ContainsAny(id, "/?#") {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	registered, ok := s.resourcePages.Load(normalizeID(id))
+	if !ok {
+		return nil, mcp.ResourceNotFoundError(uri)
+	}
+	content, err := s.client.GetPageContent(ctx, registered.(notion.Page).ID)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching content: %w", err)
+	}
+	data, err := pageJSON(content)
+	if err != nil {
+		return nil, err
+	}
+	return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{
+		{URI: uri, MIMEType: mimeJSON, Text: string(data)},
+	}}, nil
+}
+
+// pageJSON returns a page's properties and blocks as indented JSON. Signed
+// URLs of Notion-hosted files expire within the hour, so they are dropped
+// and the file marked with "url_omitted": true.
+func pageJSON(content *notion.PageContent) ([]byte, error) {
+	data, err := json.Marshal(struct {
+		Page   notion.Page    `json:"page"`
+		Blocks []notion.Block `json:"blocks"`
+	}{content.Page, content.Blocks})
+	if err != nil {
+		return nil, fmt.Errorf("encode page: %w", err)
+	}
+	var doc any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("encode page: %w", err)
+	}
+	omitSignedURLs(doc)
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// omitSignedURLs removes the URL of every Notion-hosted file object, those
+// with an expiry_time, in a decoded JSON value.
+func omitSignedURLs(v any) {
+	switch v := v.(type) {
+	case map[string]any:
+		if _, signed := v["expiry_time"]; signed {
+			if _, ok := v["url"]; ok {
+				delete(v, "url")
+				v["url_omitted"] = true
+			}
+		}
+		for _, child := range v {
+			omitSignedURLs(child)
+		}
+	case []any:
+		for _, child := range v {
+			omitSignedURLs(child)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+)
+
+// pageQueryWindow is how long a GetAllPages result is reused. The prompt and
+// resource lists are refreshed from the same query, so callers arriving
+// together within the window share one Notion request.
+const pageQueryWindow = 2 * time.Second
+
+// pageQuery deduplicates GetAllPages calls: concurrent callers wait for the
+// query in flight, and later callers reuse its result for pageQueryWindow.
+// The zero value is ready to use.
+type pageQuery struct {
+	mu      sync.Mutex
+	call    *pageCall
+	pages   []notion.Page
+	fetched time.Time
+}
+
+// pageCall is a GetAllPages call in flight.
+type pageCall struct {
+	done  chan struct{}
+	pages []notion.Page
+	err   error
+}
+
+// allPages returns every page of the configured databases, sharing the
+// query with other callers as described on pageQuery. The returned slice is
+// shared and must not be modified.
+func (s *Server) allPages(ctx context.Context) ([]notion.Page, error) {
+	q := &s.pageQuery
+	q.mu.Lock()
+	if !q.fetched.IsZero() && time.Since(q.fetched) < pageQueryWindow {
+		pages := q.pages
+		q.mu.Unlock()
+		return pages, nil
+	}
+	if call := q.call; call != nil {
+		q.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.pages, call.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	call := &pageCall{done: make(chan struct{})}
+	q.call = call
+	q.mu.Unlock()
+
+	call.pages, call.err = s.client.GetAllPages(ctx)
+
+	q.mu.Lock()
+	q.call = nil
+	if call.err == nil {
+		q.pages, q.fetched = call.pages, time.Now()
+	}
+	q.mu.Unlock()
+	close(call.done)
+	return call.pages, call.err
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+)
+
+// propInputSchema is the page property holding a tool's input JSON Schema,
+// which must describe an object. Arguments are validated against it before
+// the tool runs.
+const propInputSchema = "InputSchema"
+
+// defaultInputSchema is the input schema of tools that declare none.
+var defaultInputSchema = map[string]any{"type": "object"}
+
+// toolInputSchema returns the input schema a tool page declares, or nil if
+// it declares none.
+func toolInputSchema(page notion.Page) (*jsonschema.Schema, error) {
+	text := strings.TrimSpace(notion.PropertyText(page.Properties[propInputSchema]))
+	if text == "" {
+		return nil, nil
+	}
+	var schema jsonschema.Schema
+	if err := json.Unmarshal([]byte(text), &schema); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", propInputSchema, err)
+	}
+	if schema.Type != "object" {
+		return nil, fmt.Errorf("%s must have type object, got %q", propInputSchema, schema.Type)
+	}
+	if _, err := schema.Resolve(nil); err != nil {
+		return nil, fmt.Errorf("resolve %s: %w", propInputSchema, err)
+	}
+	schema.PropertyOrder = propertyOrder([]byte(text))
+	return &schema, nil
+}
+
+// propertyOrder returns the names of a JSON Schema's top-level properties in
+// the order they are written. Bash tools receive arguments as positional
+// parameters in this order.
+func propertyOrder(schema []byte) []string {
+	var raw struct {
+		Properties json.RawMessage `json:"properties"`
+	}
+	if json.Unmarshal(schema, &raw) != nil || len(raw.Properties) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw.Properties))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var names []string
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		name, _ := tok.(string)
+		names = append(names, name)
+		var value json.RawMessage
+		if dec.Decode(&value) != nil {
+			return nil
+		}
+	}
+	return names
+}
+
+// toolInput returns the JSON input of a tool call: its arguments, or when
+// it has none the default the tool's input schema declares, else an empty
+// object.
+func toolInput(request *mcp.CallToolRequest, schema *jsonschema.Schema) string {
+	if request != nil && request.Params != nil && len(request.Params.Arguments) > 0 {
+		return string(request.Params.Arguments)
+	}
+	if schema != nil && len(schema.Default) > 0 {
+		return string(schema.Default)
+	}
+	return "{}"
+}
+
+// argumentIssue is one argument that does not match a tool's input schema.
+type argumentIssue struct {
+	Path     string `json:"path"`               // JSON pointer to the argument, "" for the arguments object
+	Expected string `json:"expected,omitempty"` // the type the schema expects
+	Message  string `json:"message"`
+}
+
+// argumentError is the result of a tool call whose arguments do not match
+// the tool's input schema.
+type argumentError struct {
+	Error  string             `json:"error"`
+	Issues []argumentIssue    `json:"issues"`
+	Schema *jsonschema.Schema `json:"schema"`
+}
+
+// validateArguments checks the raw arguments of a tool call against schema.
+// Missing required properties and mismatched types are reported for every
+// offending path; other constraints are reported by the first that fails.
+func validateArguments(schema *jsonschema.Schema, resolved *jsonschema.Resolved, arguments []byte) []argumentIssue {
+	var value any
+	if err := json.Unmarshal(arguments, &value); err != nil {
+		return []argumentIssue{{Expected: "object", Message: "arguments are not valid JSON: " + err.Error()}}
+	}
+	if issues := checkArguments(schema, value, ""); len(issues) > 0 {
+		return issues
+	}
+	if err := resolved.Validate(value); err != nil {
+		return []argumentIssue{{Message: err.Error()}}
+	}
+	return nil
+}
+
+// checkArguments walks value and schema together, collecting missing
+// required properties and type mismatches under path.
+func checkArguments(schema *jsonschema.Schema, value any, path string) []argumentIssue {
+	if schema == nil {
+		return nil
+	}
+	got := jsonType(value)
+	if want := schemaTypes(schema); len(want) > 0 && !slices.ContainsFunc(want, func(t string) bool {
+		return t == got || (t == "number" && got == "integer")
+	}) {
+		return []argumentIssue{{
+			Path:     path,
+			Expected: strings.Join(want, " or "),
+			Message:  fmt.Sprintf("got %s", got),
+		}}
+	}
+
+	var issues []argumentIssue
+	switch v := value.(type) {
+	case map[string]any:
+		for _, name := range schema.Required {
+			if _, ok := v[name]; !ok {
+				issues = append(issues, argumentIssue{
+					Path:     path + "/" + escapePointer(name),
+					Expected: cmp.Or(strings.Join(schemaTypes(schema.Properties[name]), " or "), "any"),
+					Message:  "missing required property",
+				})
+			}
+		}
+		for _, name := range slices.Sorted(maps.Keys(v)) {
+			issues = append(issues, checkArguments(schema.Properties[name], v[name], path+"/"+escapePointer(name))...)
+		}
+	case []any:
+		for i, item := range v {
+			issues = append(issues, checkArguments(schema.Items, item, path+"/"+strconv.Itoa(i))...)
+		}
+	}
+	return issues
+}
+
+// schemaTypes returns the types a schema allows, or nil for any type.
+func schemaTypes(schema *jsonschema.Schema) []string {
+	if schema == nil {
+		return nil
+	}
+	if schema.Type != "" {
+		return []string{schema.Type}
+	}
+	return schema.Types
+}
+
+// jsonType returns the JSON Schema type of a decoded JSON value.
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if v == math.Trunc(v) {
+			return "integer"
+		}
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// escapePointer escapes a property name for use in a JSON pointer.
+func escapePointer(name string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(name)
+}
+
+// argumentErrorResult reports arguments that do not match schema, as JSON
+// text and as structured content.
+func argumentErrorResult(schema *jsonschema.Schema, issues []argumentIssue) *mcp.CallToolResult {
+	out := argumentError{Error: "invalid arguments", Issues: issues, Schema: schema}
+	data, _ := json.Marshal(out)
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: string(data)}},
+		StructuredContent: out,
+		IsError:           true,
+	}
+}
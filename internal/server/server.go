@@ -2,14 +2,24 @@
 package server
 
 import (
+	"cmp"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net/http"
-	"os"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"runtime/debug"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
+	"time"
 
+	"github.com/google/jsonschema-go/jsonschema"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 	"github.com/samber/lo"
 
@@ -27,16 +37,135 @@ const (
 	pageTypeTool     = "tool"
 )
 
+// pageKind maps a page's type value to pageTypePrompt, pageTypeResource or
+// pageTypeTool using the configured type values, ignoring case. It returns
+// "" for pages of any other type or of a kind ENABLED_TYPES leaves out, so
+// those pages are never registered.
+func pageKind(cfg *config.Config, page notion.Page) string {
+	value := notion.GetTypeFromProperties(page.Properties, cfg.NotionTypeField)
+	if value == "" {
+		return ""
+	}
+	for _, kind := range []struct{ configured, kind string }{
+		{cfg.TypePrompt, pageTypePrompt},
+		{cfg.TypeResource, pageTypeResource},
+		{cfg.TypeTool, pageTypeTool},
+	} {
+		if strings.EqualFold(value, cmp.Or(kind.configured, kind.kind)) {
+			if !cfg.TypeEnabled(kind.kind) {
+				return ""
+			}
+			return kind.kind
+		}
+	}
+	return ""
+}
+
+// Per-page cache TTL: pages may set a number property (in seconds) that
+// overrides the global cache TTL, clamped to [minPageCacheTTL, maxPageCacheTTL].
+const (
+	propCacheTTL    = "CacheTTL"
+	minPageCacheTTL = 10 * time.Second
+	maxPageCacheTTL = 24 * time.Hour
+)
+
+// promptArgMaxLength is the optional prompt argument clients use to cap
+// the length (in characters) of the returned prompt text.
+const promptArgMaxLength = "maxLength"
+
+// propArguments is the page property declaring a prompt's arguments, either
+// as a comma-separated list of names or as a JSON array of
+// {"name", "description", "required"} objects.
+const propArguments = "Arguments"
+
+// propEntrypoint is the page property choosing the code block a tool runs,
+// by position (1 for the first code block) or by language.
+const propEntrypoint = "Entrypoint"
+
+// propTimeout is the page property overriding EXEC_TIMEOUT for a tool, as a
+// duration such as "2m". It is capped at EXEC_MAX_TIMEOUT.
+const propTimeout = "Timeout"
+
+// propOutputFormat is the page property overriding TOOL_OUTPUT_FORMAT for a
+// tool: "text" or "json".
+const propOutputFormat = "OutputFormat"
+
+// propCacheable is the checkbox page property marking a tool as a pure
+// function of its input, so results are cached for the page's cache TTL.
+const propCacheable = "Cacheable"
+
+// Tool output formats.
+const (
+	toolFormatText = "text"
+	toolFormatJSON = "json"
+)
+
+// promptTemplateAction matches template actions that refer to prompt
+// arguments or page properties, e.g. {{.Args.topic}} or {{.Props.Category}}.
+var promptTemplateAction = regexp.MustCompile(`\{\{[^}]*\.(Args|Props)\b`)
+
+// notionClient is the part of the Notion API the server uses.
+type notionClient interface {
+	GetAllPages(ctx context.Context) ([]notion.Page, error)
+	GetPage(ctx context.Context, pageID string) (*notion.Page, error)
+	QueryDatabaseSince(ctx context.Context, since time.Time) ([]notion.Page, error)
+	GetPageContent(ctx context.Context, pageID string) (*notion.PageContent, error)
+	AppendBlockChildren(ctx context.Context, blockID string, blocks []notion.Block) error
+}
+
 // Server represents the MCP server.
 type Server struct {
 	cfg      *config.Config
-	client   *notion.Client
+	client   notionClient
 	cache    cache.Cache
 	mcpCache *cache.MCPCache
 	logger   *slog.Logger
 	impl     *mcp.Implementation
 	executor *tools.Executor
 	toolReg  *tools.Registry
+	// images downloads page images when IMAGE_DOWNLOAD is set
+	images *notion.ImageStore
+	// pageQuery shares GetAllPages results between the list refreshes
+	pageQuery pageQuery
+	// metrics counts requests for METRICS_ADDR; nil when it is unset
+	metrics *Metrics
+	// resourcePages holds the registered resource pages by normalized ID,
+	// for the page JSON template
+	resourcePages sync.Map
+	// registered holds the pages registered on the MCP server, kept current
+	// by both the watch loop and the list cache refresh
+	registered struct {
+		sync.Mutex
+		pages []notion.Page
+	}
+}
+
+// Build information, set by release builds with -ldflags, e.g.
+// -X github.com/nixihz/notion-as-mcp/internal/server.Version=v1.2.3.
+// Version is reported to MCP clients and by the version command.
+var (
+	Version = "dev"
+	Commit  = ""
+	Date    = ""
+)
+
+// BuildCommit returns the commit and date the binary was built from: Commit
+// and Date when set, otherwise the VCS stamp the go command embeds.
+func BuildCommit() (commit, date string) {
+	commit, date = Commit, Date
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return commit, date
+	}
+	for _, s := range info.Settings {
+		switch {
+		case s.Key == "vcs.revision" && commit == "":
+			commit = s.Value
+		case s.Key == "vcs.time" && date == "":
+			date = s.Value
+		}
+	}
+	return commit, date
 }
 
 // NewServer creates a new MCP server.
@@ -52,20 +181,77 @@ func NewServer(cfg *config.Config) (*Server, error) {
 	cacheStore, err := cache.NewCache(
 		cache.WithTTL(cfg.CacheTTL),
 		cache.WithDir(cfg.CacheDir),
+		cache.WithSweepInterval(cfg.CacheSweepInterval),
+		cache.WithLogger(log),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("init cache: %w", err)
 	}
+	var metrics *Metrics
+	var clientOpts []notion.ClientOption
+	if cfg.MetricsAddr != "" {
+		metrics = NewMetrics()
+		cacheStore = countingCache{Cache: cacheStore, metrics: metrics}
+		clientOpts = append(clientOpts, notion.WithObserver(metrics.notion.Observe))
+	}
+	if cfg.CacheSnapshot != "" {
+		n, err := cache.ImportSnapshot(context.Background(), cacheStore, cfg.CacheSnapshot)
+		if err != nil {
+			log.Warn("failed to load cache snapshot", slog.String("path", cfg.CacheSnapshot), slog.String("error", err.Error()))
+		} else {
+			log.Info("loaded cache snapshot", slog.String("path", cfg.CacheSnapshot), slog.Int("entries", n))
+		}
+	}
 
-	// Create Notion client
-	client := notion.NewClient(
-		cfg.NotionAPIKey,
-		cfg.NotionDatabaseID,
-		cfg.NotionTypeField,
-	)
+	client, err := NewNotionClient(cfg, clientOpts...)
+	if err != nil {
+		return nil, err
+	}
 
 	// Initialize MCP cache manager
 	mcpCacheManager := cache.NewMCPCache(cacheStore, log)
+	if metrics != nil {
+		metrics.refreshHealth = mcpCacheManager.RefreshHealth
+	}
+
+	// Create executor with configured language runtimes
+	runtimes, err := tools.ParseRuntimes(cfg.ExecRuntimes)
+	if err != nil {
+		return nil, fmt.Errorf("parse exec runtimes: %w", err)
+	}
+	execOpts := []tools.ExecutorOption{tools.WithRuntimes(runtimes)}
+	for _, name := range strings.Split(cfg.ExecEnvPassthrough, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			execOpts = append(execOpts, tools.WithEnvPassthrough(name))
+		}
+	}
+	if cfg.ExecDedent {
+		execOpts = append(execOpts, tools.WithDedent())
+	}
+	denyPatterns, err := tools.ParseDenyPatterns(cfg.ExecBashDeny)
+	if err != nil {
+		return nil, fmt.Errorf("parse exec bash deny patterns: %w", err)
+	}
+	if len(denyPatterns) > 0 {
+		execOpts = append(execOpts, tools.WithDenyPatterns(denyPatterns...))
+	}
+	if cfg.ExecInsecureTLS {
+		log.Warn("EXEC_INSECURE_TLS is set: TLS certificate verification is disabled for TypeScript tools")
+		execOpts = append(execOpts, tools.WithInsecureTLS())
+	}
+	if cfg.ExecMaxConcurrent > 0 {
+		execOpts = append(execOpts, tools.WithMaxConcurrent(cfg.ExecMaxConcurrent, cfg.ExecMaxQueued))
+	}
+	if cfg.ExecMaxOutputBytes > 0 {
+		execOpts = append(execOpts, tools.WithMaxOutput(cfg.ExecMaxOutputBytes))
+	}
+	if cfg.ExecMaxTimeout > 0 {
+		execOpts = append(execOpts, tools.WithMaxTimeout(cfg.ExecMaxTimeout))
+	}
+	executor := tools.NewExecutor(cfg.ExecTimeout, cfg.ExecLanguages, execOpts...)
+	if err := executor.ValidateRuntimes(); err != nil {
+		return nil, fmt.Errorf("validate exec runtimes: %w", err)
+	}
 
 	srv := &Server{
 		cfg:      cfg,
@@ -74,68 +260,118 @@ func NewServer(cfg *config.Config) (*Server, error) {
 		mcpCache: mcpCacheManager,
 		logger:   log,
 		impl: &mcp.Implementation{
-			Name:    "notion-as-mcp",
-			Version: "1.0.0",
+			Name:    cfg.ServerName,
+			Version: Version,
 		},
-		executor: tools.NewExecutor(cfg.ExecTimeout, cfg.ExecLanguages),
+		executor: executor,
 		toolReg:  tools.NewRegistry(),
+		metrics:  metrics,
+	}
+
+	if cfg.ImageDownload {
+		dir, err := cache.ExpandHome(cfg.CacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("image directory: %w", err)
+		}
+		srv.images = notion.NewImageStore(filepath.Join(dir, "images"), cfg.ImageInlineMax)
 	}
 
 	return srv, nil
 }
 
+// NewNotionClient creates the Notion client described by cfg: its endpoint,
+// API version, request timeout and the order to sort pages in. Options in
+// extra are applied after those from cfg.
+func NewNotionClient(cfg *config.Config, extra ...notion.ClientOption) (*notion.Client, error) {
+	sorts, err := notion.ParseSorts(cfg.NotionSorts)
+	if err != nil {
+		return nil, fmt.Errorf("parse notion sorts: %w", err)
+	}
+	opts := []notion.ClientOption{notion.WithSorts(sorts...)}
+	if cfg.NotionBaseURL != "" {
+		opts = append(opts, notion.WithBaseURL(cfg.NotionBaseURL))
+	}
+	if cfg.NotionAPIVersion != "" {
+		opts = append(opts, notion.WithAPIVersion(cfg.NotionAPIVersion))
+	}
+	if cfg.NotionHTTPProxy != "" {
+		proxy, err := url.Parse(cfg.NotionHTTPProxy)
+		if err != nil {
+			// Unwrap the *url.Error, whose message would repeat the password
+			return nil, fmt.Errorf("parse notion http proxy: %w", errors.Unwrap(err))
+		}
+		opts = append(opts, notion.WithProxy(proxy))
+	}
+	// Zero leaves the client's built-in timeout, not no timeout
+	if cfg.NotionHTTPTimeout > 0 {
+		opts = append(opts, notion.WithTimeout(cfg.NotionHTTPTimeout))
+	}
+	if cfg.NotionPageSize > 0 {
+		opts = append(opts, notion.WithPageSize(cfg.NotionPageSize))
+	}
+	if cfg.NotionMaxBlockDepth > 0 {
+		opts = append(opts, notion.WithMaxBlockDepth(cfg.NotionMaxBlockDepth))
+	}
+	opts = append(opts, notion.WithMaxBlocks(cfg.NotionMaxBlocks))
+	if cfg.NotionLogBodies {
+		opts = append(opts, notion.WithLogBodies())
+	}
+	opts = append(opts, extra...)
+	return notion.NewClient(cfg.NotionAPIKey, cfg.NotionDatabaseID, cfg.NotionTypeField, opts...), nil
+}
+
 // Start starts the MCP server with the configured transport.
 func (s *Server) Start(ctx context.Context) error {
-	// Warm cache on startup
-	s.warmCache(ctx)
-
-	// Start periodic refresh in background
-	s.startPeriodicRefresh(ctx)
+	// Warm cache on startup; otherwise serve the lists cached by an earlier
+	// run until the first refresh
+	if s.cfg.RefreshOnStart {
+		s.warmCache(ctx)
+	}
 
 	// Get all pages - try cache first, then fallback to Notion
-	allPages := s.getAllPagesWithCache(ctx)
+	allPages, err := s.getAllPagesWithCache(ctx)
+	pending := err != nil
+	if pending {
+		if s.cfg.StartupFetch != startupFetchRetry {
+			return fmt.Errorf("query pages: %w (%s)", err, queryErrorHint(err))
+		}
+		s.logger.Warn("failed to query pages; starting empty and retrying in the background",
+			slog.String("error", err.Error()), slog.String("hint", queryErrorHint(err)))
+	}
+
+	s.serveMetrics(ctx)
 
 	if s.cfg.TransportType == "streamable" {
-		return s.startStreamable(ctx, allPages)
+		return s.startStreamable(ctx, allPages, pending)
 	}
-	return s.startStdio(ctx, allPages)
+	return s.startStdio(ctx, allPages, pending)
 }
 
 // getAllPagesWithCache tries to get pages from cache first, falls back to Notion.
-func (s *Server) getAllPagesWithCache(ctx context.Context) []notion.Page {
-	// Try to get pages from both caches (resources and prompts)
-	// and merge them to get all pages
+func (s *Server) getAllPagesWithCache(ctx context.Context) ([]notion.Page, error) {
+	// Try the cached list of each kind and merge them to get all pages
 	var allPages []notion.Page
-
-	// Try resources cache
-	resourceData, err := s.mcpCache.Get(ctx, cache.CacheKeyResources)
-	if err == nil && resourceData != nil {
-		var resourcePages []notion.Page
-		if json.Unmarshal(resourceData, &resourcePages) == nil {
-			allPages = append(allPages, resourcePages...)
+	for _, list := range cachedLists {
+		data, err := s.mcpCache.Get(ctx, list.key)
+		if err != nil || data == nil {
+			continue
 		}
-	}
-
-	// Try prompts cache
-	promptData, err := s.mcpCache.Get(ctx, cache.CacheKeyPrompts)
-	if err == nil && promptData != nil {
-		var promptPages []notion.Page
-		if json.Unmarshal(promptData, &promptPages) == nil {
-			allPages = append(allPages, promptPages...)
+		var pages []notion.Page
+		if json.Unmarshal(data, &pages) == nil {
+			allPages = append(allPages, pages...)
 		}
 	}
 
 	if len(allPages) > 0 {
 		s.logger.Info("using cached pages", slog.Int("total", len(allPages)))
-		return allPages
+		return allPages, nil
 	}
 
 	// Cache miss or error, fetch from Notion
 	s.logger.Info("fetching pages from Notion (cache miss)")
-	pages, err := s.client.GetAllPages(ctx)
+	pages, err := s.allPages(ctx)
 	if err != nil {
-		s.logger.Warn("failed to query pages", slog.String("error", err.Error()))
-		return nil
+		return nil, err
 	}
 
 	// Debug: log page types
@@ -152,95 +388,79 @@ func (s *Server) getAllPagesWithCache(ctx context.Context) []notion.Page {
 		slog.Any("type_counts", typeCounts),
 	)
 
-	return pages
+	return pages, nil
 }
 
-// warmCache fetches and caches all pages on startup.
-func (s *Server) warmCache(ctx context.Context) {
-	// Warm resources cache
-	err := s.mcpCache.Warm(ctx, cache.CacheKeyResources, func(ctx context.Context) ([]byte, error) {
-		pages, err := s.client.GetAllPages(ctx)
-		if err != nil {
-			return nil, err
-		}
-		// Filter only resource pages
-		var resourcePages []notion.Page
-		for _, p := range pages {
-			pageType := notion.GetTypeFromProperties(p.Properties, s.cfg.NotionTypeField)
-			if pageType == pageTypeResource {
-				resourcePages = append(resourcePages, p)
-			}
-		}
-		return s.serializePages(resourcePages)
-	})
-	if err != nil {
-		s.logger.Warn("failed to warm resources cache", slog.String("error", err.Error()))
+// queryErrorHint suggests a fix for a failed database query.
+func queryErrorHint(err error) string {
+	switch {
+	case errors.Is(err, notion.ErrUnauthorized):
+		return "check NOTION_API_KEY"
+	case errors.Is(err, notion.ErrNotFound), errors.Is(err, notion.ErrForbidden):
+		return "check NOTION_DATABASE_ID and that the database is shared with the integration"
+	case errors.Is(err, notion.ErrRateLimited):
+		return "Notion is throttling requests; raise POLL_INTERVAL or CACHE_TTL"
 	}
+	return "run notion-as-mcp doctor to diagnose"
+}
 
-	// Warm prompts cache
-	err = s.mcpCache.Warm(ctx, cache.CacheKeyPrompts, func(ctx context.Context) ([]byte, error) {
-		pages, err := s.client.GetAllPages(ctx)
-		if err != nil {
-			return nil, err
-		}
-		// Filter only prompt pages
-		var promptPages []notion.Page
-		for _, p := range pages {
-			pageType := notion.GetTypeFromProperties(p.Properties, s.cfg.NotionTypeField)
-			if pageType == pageTypePrompt {
-				promptPages = append(promptPages, p)
-			}
-		}
-		return s.serializePages(promptPages)
+// cachedLists are the page lists kept in the MCP cache, by cache key.
+var cachedLists = []struct {
+	key  string
+	kind string
+}{
+	{cache.CacheKeyResources, pageTypeResource},
+	{cache.CacheKeyPrompts, pageTypePrompt},
+	{cache.CacheKeyTools, pageTypeTool},
+}
+
+// pagesOfKind returns the pages classified as kind.
+func (s *Server) pagesOfKind(pages []notion.Page, kind string) []notion.Page {
+	return lo.Filter(pages, func(page notion.Page, _ int) bool {
+		return pageKind(s.cfg, page) == kind
 	})
-	if err != nil {
-		s.logger.Warn("failed to warm prompts cache", slog.String("error", err.Error()))
-	}
 }
 
-// startPeriodicRefresh starts background goroutines to periodically refresh caches.
-func (s *Server) startPeriodicRefresh(ctx context.Context) {
-	// Create fetcher for resources
-	resourcesFetcher := func(ctx context.Context) ([]byte, error) {
-		pages, err := s.client.GetAllPages(ctx)
+// warmCache caches the resource, prompt and tool lists on startup,
+// querying Notion once for all of them.
+func (s *Server) warmCache(ctx context.Context) {
+	pages, err := s.allPages(ctx)
+	if err != nil {
+		s.logger.Warn("failed to warm cache", slog.String("error", err.Error()))
+		return
+	}
+	for _, list := range cachedLists {
+		kind := list.kind
+		err := s.mcpCache.Warm(ctx, list.key, func(ctx context.Context) ([]byte, error) {
+			return s.serializePages(s.pagesOfKind(pages, kind))
+		})
 		if err != nil {
-			return nil, err
+			s.logger.Warn("failed to warm cache", slog.String("key", list.key), slog.String("error", err.Error()))
 		}
-		var resourcePages []notion.Page
-		for _, p := range pages {
-			pageType := notion.GetTypeFromProperties(p.Properties, s.cfg.NotionTypeField)
-			if pageType == pageTypeResource {
-				resourcePages = append(resourcePages, p)
-			}
-		}
-		return s.serializePages(resourcePages)
 	}
+}
 
-	// Create fetcher for prompts
-	promptsFetcher := func(ctx context.Context) ([]byte, error) {
-		pages, err := s.client.GetAllPages(ctx)
-		if err != nil {
-			return nil, err
-		}
-		var promptPages []notion.Page
-		for _, p := range pages {
-			pageType := notion.GetTypeFromProperties(p.Properties, s.cfg.NotionTypeField)
-			if pageType == pageTypePrompt {
-				promptPages = append(promptPages, p)
+// startPeriodicRefresh starts background goroutines that query Notion every
+// PollInterval, update the cached lists and re-register the pages on server
+// to match.
+func (s *Server) startPeriodicRefresh(ctx context.Context, server *mcp.Server) {
+	if s.mcpCache == nil || s.cfg.PollInterval <= 0 {
+		return
+	}
+	for _, list := range cachedLists {
+		kind := list.kind
+		s.mcpCache.StartPeriodicRefresh(ctx, list.key, s.cfg.PollInterval, func(ctx context.Context) ([]byte, error) {
+			pages, err := s.allPages(ctx)
+			if err != nil {
+				return nil, err
 			}
-		}
-		return s.serializePages(promptPages)
+			s.resync(ctx, server, pages)
+			return s.serializePages(s.pagesOfKind(pages, kind))
+		})
 	}
 
-	// Start periodic refresh for resources
-	s.mcpCache.StartPeriodicRefresh(ctx, cache.CacheKeyResources, s.cfg.CacheRefreshInterval, resourcesFetcher)
-
-	// Start periodic refresh for prompts
-	s.mcpCache.StartPeriodicRefresh(ctx, cache.CacheKeyPrompts, s.cfg.CacheRefreshInterval, promptsFetcher)
-
-	// Refresh immediately on startup (non-blocking)
-	go s.mcpCache.RefreshOnce(ctx, cache.CacheKeyResources, resourcesFetcher)
-	go s.mcpCache.RefreshOnce(ctx, cache.CacheKeyPrompts, promptsFetcher)
+	// Watch for stalled refresh loops
+	s.mcpCache.StartWatchdog(ctx, s.cfg.PollInterval, s.cfg.RefreshWatchdogRestart)
 }
 
 // serializePages serializes pages to JSON bytes.
@@ -249,12 +469,12 @@ func (s *Server) serializePages(pages []notion.Page) ([]byte, error) {
 }
 
 // startStreamable starts the MCP server with streamable HTTP transport.
-func (s *Server) startStreamable(ctx context.Context, allPages []notion.Page) error {
-	server := mcp.NewServer(s.impl, nil)
+func (s *Server) startStreamable(ctx context.Context, allPages []notion.Page, pending bool) error {
+	server := mcp.NewServer(s.impl, s.serverOptions())
+	server.AddReceivingMiddleware(s.metrics.middleware())
 
 	// Register handlers
-	s.registerPrompts(server, allPages)
-	s.registerResources(server, allPages)
+	s.registerPages(ctx, server, allPages, pending)
 
 	handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
 		return server
@@ -283,17 +503,24 @@ func (s *Server) startStreamable(ctx context.Context, allPages []notion.Page) er
 }
 
 // startStdio starts the MCP server with stdio transport.
-func (s *Server) startStdio(ctx context.Context, allPages []notion.Page) error {
+func (s *Server) startStdio(ctx context.Context, allPages []notion.Page, pending bool) error {
 	s.logger.Info("starting Notion MCP server with stdio transport",
 		slog.String("database_id", s.cfg.NotionDatabaseID),
 		slog.String("type_field", s.cfg.NotionTypeField),
 	)
 
-	server := mcp.NewServer(s.impl, nil)
+	server := mcp.NewServer(s.impl, s.serverOptions())
+	server.AddReceivingMiddleware(s.metrics.middleware())
+
+	// The SDK dispatches each call on its own goroutine and serializes
+	// writes to stdout; optionally bound how many run at once.
+	if s.cfg.StdioMaxConcurrency > 0 {
+		server.AddReceivingMiddleware(concurrencyMiddleware(s.cfg.StdioMaxConcurrency))
+		s.logger.Info("limiting stdio request concurrency", slog.Int("max", s.cfg.StdioMaxConcurrency))
+	}
 
 	// Register handlers
-	s.registerPrompts(server, allPages)
-	s.registerResources(server, allPages)
+	s.registerPages(ctx, server, allPages, pending)
 
 	s.logger.Info("Notion MCP server started")
 
@@ -301,6 +528,77 @@ func (s *Server) startStdio(ctx context.Context, allPages []notion.Page) error {
 	return server.Run(ctx, &mcp.StdioTransport{})
 }
 
+// startupFetchRetry is the STARTUP_FETCH value that starts the server empty
+// when the initial page query fails.
+const startupFetchRetry = "retry"
+
+// Delays between retries of a failed initial page query, doubling from
+// startupRetryDelay up to maxStartupRetryDelay.
+const (
+	startupRetryDelay    = 5 * time.Second
+	maxStartupRetryDelay = 5 * time.Minute
+)
+
+// registerPages registers the prompts, resources and tools of pages on
+// server and starts watching for changes and refreshing the cached lists. If
+// the initial query is still pending, this happens in the background once a
+// retry succeeds.
+func (s *Server) registerPages(ctx context.Context, server *mcp.Server, pages []notion.Page, pending bool) {
+	if pending {
+		go s.retryInitialQuery(ctx, server, startupRetryDelay)
+		return
+	}
+	s.registerPrompts(server, pages)
+	s.registerResources(server, pages)
+	s.registerTools(server, pages)
+	s.setRegistered(pages)
+	s.startWatch(ctx, server, pages)
+	s.startPeriodicRefresh(ctx, server)
+}
+
+// retryInitialQuery queries Notion after delay, backing off until a query
+// succeeds, then registers the pages on server. It returns early when ctx is
+// done.
+func (s *Server) retryInitialQuery(ctx context.Context, server *mcp.Server, delay time.Duration) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		pages, err := s.allPages(ctx)
+		if err == nil {
+			s.logger.Info("fetched pages from Notion after retrying", slog.Int("total", len(pages)))
+			s.registerPages(ctx, server, pages, false)
+			return
+		}
+		delay = min(delay*2, maxStartupRetryDelay)
+		s.logger.Warn("failed to query pages",
+			slog.String("error", err.Error()), slog.Duration("retry_in", delay))
+	}
+}
+
+// concurrencyMiddleware limits the number of requests handled concurrently.
+// Initialization and notifications bypass the limit so a saturated server
+// can still complete the handshake.
+func concurrencyMiddleware(limit int) mcp.Middleware {
+	sem := make(chan struct{}, limit)
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			if method == "initialize" || strings.HasPrefix(method, "notifications/") {
+				return next(ctx, method, req)
+			}
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			defer func() { <-sem }()
+			return next(ctx, method, req)
+		}
+	}
+}
+
 // Stop stops the MCP server.
 func (s *Server) Stop() error {
 	// Stop periodic refresh
@@ -317,165 +615,555 @@ func (s *Server) Stop() error {
 func (s *Server) registerPrompts(server *mcp.Server, allPages []notion.Page) {
 	// Filter pages by type using functional programming
 	promptPages := lo.Filter(allPages, func(page notion.Page, _ int) bool {
-		pageType := notion.GetTypeFromProperties(page.Properties, s.cfg.NotionTypeField)
-		return pageType == pageTypePrompt
+		return pageKind(s.cfg, page) == pageTypePrompt
 	})
 
 	// Register each prompt page
+	names := s.assignNames(promptPages)
 	lo.ForEach(promptPages, func(page notion.Page, _ int) {
-		title := getPageTitle(page)
-		promptName := sanitizeToolName(title)
-		promptDesc := getPageDescription(page)
+		s.addPrompt(server, page, names[page.ID])
+	})
 
-		// Validate prompt name (must match pattern: ^[a-z][a-z0-9_-]*$)
-		if promptName == "" {
-			s.logger.Warn("skipping prompt with empty name", slog.String("page_id", page.ID), slog.String("title", title))
-			return
-		}
+	s.logger.Info("registered prompts", slog.Int("count", len(promptPages)))
+}
 
-		// Ensure name starts with lowercase letter
-		if len(promptName) > 0 && (promptName[0] < 'a' || promptName[0] > 'z') {
-			// Prepend 'p_' if name doesn't start with lowercase letter
-			promptName = "p_" + promptName
+// assignNames returns the name of each prompt and tool page in pages, keyed
+// by page ID. Titles may repeat across databases, so the first page of a
+// kind keeps its name and later ones get their database ID appended, then a
+// counter if that still collides. Resources need no names of their own:
+// their URIs hold the page ID.
+func (s *Server) assignNames(pages []notion.Page) map[string]string {
+	names := make(map[string]string)
+	taken := map[string]map[string]bool{pageTypePrompt: {}, pageTypeTool: {}}
+	for _, page := range pages {
+		kind := pageKind(s.cfg, page)
+		taken := taken[kind]
+		if taken == nil {
+			continue
+		}
+		name := s.pageName(kind, page)
+		if name != "" && taken[name] {
+			base := name
+			if page.DatabaseID != "" {
+				base += "_" + shortID(page.DatabaseID)
+			}
+			name = base
+			for i := 2; taken[name]; i++ {
+				name = fmt.Sprintf("%s_%d", base, i)
+			}
 		}
+		taken[name] = true
+		names[page.ID] = name
+	}
+	return names
+}
 
-		s.logger.Info("registering prompt",
-			"name", promptName,
-			"title", title,
-			"page_id", page.ID,
-		)
-		promptHandler := s.createPromptHandler(page)
-		server.AddPrompt(&mcp.Prompt{
-			Name:        promptName,
-			Description: promptDesc,
-		}, promptHandler)
-	})
+// normalizeID returns a page ID lowercased and without dashes, so the
+// forms Notion accepts compare equal.
+func normalizeID(notionID string) string {
+	return strings.ToLower(strings.ReplaceAll(notionID, "-", ""))
+}
 
-	s.logger.Info("registered prompts", slog.Int("count", len(promptPages)))
+// shortID shortens a page or database ID for use in a name.
+func shortID(notionID string) string {
+	id := normalizeID(notionID)
+	if len(id) > 8 {
+		id = id[:8]
+	}
+	return id
+}
+
+// addPrompt registers a prompt page on server under name, replacing any
+// prompt with the same name.
+func (s *Server) addPrompt(server *mcp.Server, page notion.Page, name string) {
+	title := getPageTitle(page)
+	if name == "" {
+		s.logger.Warn("skipping prompt with empty name", slog.String("page_id", page.ID), slog.String("title", title))
+		return
+	}
+
+	arguments, err := promptArguments(page)
+	if err != nil {
+		s.logger.Warn("skipping prompt with invalid arguments", slog.String("page_id", page.ID), slog.String("error", err.Error()))
+		return
+	}
+
+	s.logger.Info("registering prompt",
+		"name", name,
+		"title", title,
+		"page_id", page.ID,
+	)
+	server.AddPrompt(&mcp.Prompt{
+		Name:        name,
+		Description: getPageDescription(page),
+		Arguments:   arguments,
+	}, s.createPromptHandler(page, arguments))
 }
 
 // registerResources registers resource handlers.
 func (s *Server) registerResources(server *mcp.Server, allPages []notion.Page) {
 	resourcePages := lo.Filter(allPages, func(page notion.Page, _ int) bool {
-		pageType := notion.GetTypeFromProperties(page.Properties, s.cfg.NotionTypeField)
-		return pageType == pageTypeResource
+		return pageKind(s.cfg, page) == pageTypeResource
 	})
 
 	// Register each resource page
 	lo.ForEach(resourcePages, func(page notion.Page, _ int) {
-		title := getPageTitle(page)
-		resourceName := sanitizeToolName(title)
-		resourceDesc := getPageDescription(page)
+		s.addResource(server, page)
+	})
+	if s.cfg.TypeEnabled(pageTypeResource) {
+		s.addPageJSONTemplate(server)
+	}
 
-		// Validate resource name (must match pattern: ^[a-z][a-z0-9_-]*$)
-		if resourceName == "" {
-			s.logger.Warn("skipping resource with empty name", slog.String("page_id", page.ID), slog.String("title", title))
-			return
-		}
+	s.logger.Info("registered resources", "count", len(resourcePages))
+}
 
-		// Ensure name starts with lowercase letter
-		if len(resourceName) > 0 && (resourceName[0] < 'a' || resourceName[0] > 'z') {
-			// Prepend 'r_' if name doesn't start with lowercase letter
-			resourceName = "r_" + resourceName
-		}
+// resourceScheme prefixes the URI of every resource page.
+const resourceScheme = "notion://resource/"
 
-		s.logger.Info("registering resource",
-			"name", resourceName,
-			"title", title,
-			"page_id", page.ID,
-		)
-		resourceHandler := s.createResourceHandler(page)
-		server.AddResource(&mcp.Resource{
-			URI:         "file:///notion/" + page.ID,
-			Name:        resourceName,
-			Description: resourceDesc,
-		}, resourceHandler)
-	})
+// resourceURI returns the MCP resource URI for a page.
+func resourceURI(page notion.Page) string {
+	return resourceScheme + page.ID
+}
 
-	s.logger.Info("registered resources", "count", len(resourcePages))
+// pageIDFromURI returns the page ID of a resource URI, ignoring trailing
+// slashes, a query string and a fragment.
+func pageIDFromURI(uri string) (string, bool) {
+	rest, ok := strings.CutPrefix(uri, resourceScheme)
+	if !ok {
+		return "", false
+	}
+	if i := strings.IndexAny(rest, "?#"); i >= 0 {
+		rest = rest[:i]
+	}
+	id := strings.TrimRight(rest, "/")
+	if id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
 }
 
-// registerTools registers tool handlers.
+// addResource registers a resource page on server, replacing any resource
+// with the same URI.
+func (s *Server) addResource(server *mcp.Server, page notion.Page) {
+	title := getPageTitle(page)
+	name := s.pageName(pageTypeResource, page)
+	if name == "" {
+		s.logger.Warn("skipping resource with empty name", slog.String("page_id", page.ID), slog.String("title", title))
+		return
+	}
+
+	s.logger.Info("registering resource",
+		"name", name,
+		"title", title,
+		"page_id", page.ID,
+	)
+	s.resourcePages.Store(normalizeID(page.ID), page)
+	server.AddResource(&mcp.Resource{
+		URI:         resourceURI(page),
+		Name:        name,
+		Description: getPageDescription(page),
+	}, s.createResourceHandler(page))
+}
+
+// registerTools registers tool handlers. Pages of a kind ENABLED_TYPES
+// leaves out are never registered.
 func (s *Server) registerTools(server *mcp.Server, allPages []notion.Page) {
 	// Filter pages by type
 	toolPages := lo.Filter(allPages, func(page notion.Page, _ int) bool {
-		pageType := notion.GetTypeFromProperties(page.Properties, s.cfg.NotionTypeField)
-		return pageType == pageTypeTool
+		return pageKind(s.cfg, page) == pageTypeTool
 	})
 
 	// Register each tool page
+	names := s.assignNames(toolPages)
 	lo.ForEach(toolPages, func(page notion.Page, _ int) {
-		title := getPageTitle(page)
-		toolName := sanitizeToolName(getPageTitle(page))
-		toolDesc := getPageDescription(page)
-
-		s.logger.Info("registering tool",
-			"name", toolName,
-			"title", title,
-			"page_id", page.ID,
-		)
-		toolHandler := s.createToolHandler(page)
-		if os.Getenv("ENV") == "development" || os.Getenv("GO_ENV") == "development" {
-			result, err := toolHandler(context.Background(), nil)
-			if err != nil {
-				fmt.Print(result)
-				s.logger.Warn("failed to create tool handler", "error", err.Error())
-				return
-			}
-		}
-
-		server.AddTool(&mcp.Tool{
-			Name:        toolName,
-			Description: toolDesc,
-		}, toolHandler)
+		s.addTool(server, page, names[page.ID])
 	})
 
 	s.logger.Info("registered tools", slog.Int("count", len(toolPages)))
 }
 
-// createPromptHandler creates a handler for a specific prompt.
-func (s *Server) createPromptHandler(page notion.Page) mcp.PromptHandler {
+// addTool registers a tool page on server under toolName, replacing any
+// tool with the same name. Its code is read now, so an edited tool must be
+// added again. A page that has no runnable code or a broken schema is not
+// served, and an edit that breaks a served tool removes it.
+func (s *Server) addTool(server *mcp.Server, page notion.Page, toolName string) {
+	title := getPageTitle(page)
+	if toolName == "" {
+		s.logger.Warn("skipping tool with empty name", slog.String("page_id", page.ID), slog.String("title", title))
+		return
+	}
+	schema, err := toolInputSchema(page)
+	if err != nil {
+		s.logger.Warn("skipping tool with invalid input schema", slog.String("page_id", page.ID), slog.String("error", err.Error()))
+		server.RemoveTools(toolName)
+		return
+	}
+	var inputSchema any = defaultInputSchema
+	if schema != nil {
+		inputSchema = schema
+	}
+
+	toolHandler := s.createToolHandler(page)
+	if toolHandler == nil {
+		server.RemoveTools(toolName)
+		return
+	}
+	s.logger.Info("registering tool",
+		"name", toolName,
+		"title", title,
+		"page_id", page.ID,
+	)
+	server.AddTool(&mcp.Tool{
+		Name:        toolName,
+		Description: getPageDescription(page),
+		InputSchema: inputSchema,
+	}, toolHandler)
+}
+
+// createPromptHandler creates a handler for a specific prompt that accepts
+// the declared arguments.
+func (s *Server) createPromptHandler(page notion.Page, declared []*mcp.PromptArgument) mcp.PromptHandler {
 	return func(ctx context.Context, request *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		var args map[string]string
+		if request != nil && request.Params != nil {
+			args = request.Params.Arguments
+		}
+		if err := checkRequiredArguments(declared, args); err != nil {
+			return nil, err
+		}
+
 		// Get page content
 		content, err := s.client.GetPageContent(ctx, page.ID)
 		if err != nil {
 			return nil, fmt.Errorf("error fetching content: %w", err)
 		}
-		markdown := notion.PageToMarkdown(content)
-
-		title := getPageTitle(page)
-		return &mcp.GetPromptResult{
-			Description: title,
-			Messages: []*mcp.PromptMessage{
-				{
-					Role: "user",
-					Content: &mcp.TextContent{
-						Text: markdown,
-					},
-				},
-			},
-		}, nil
+		var images map[string]notion.Block
+		if s.cfg.PromptImageMaxBytes > 0 {
+			swapped := *content
+			swapped.Blocks, images = promptImages(content.Blocks)
+			content = &swapped
+		}
+		// Resolve {{prop:Name}} placeholders from the freshly fetched page
+		markdown := notion.ExpandPropertyPlaceholders(s.pageToMarkdown(content), content.Page.Properties)
+		markdown, err = renderPromptTemplate(markdown, args, content.Page.Properties)
+		if err != nil {
+			return nil, err
+		}
+		result, err := buildPromptResult(getPageTitle(page), markdown, args)
+		if err != nil {
+			return nil, err
+		}
+		result.Messages = s.withPromptImages(ctx, result.Messages, images)
+		return result, nil
 	}
 }
 
+// renderPromptTemplate executes text as a text/template with the request
+// arguments as .Args and the page properties as .Props. Text without such
+// placeholders is returned unchanged. Missing values render as empty, so
+// {{or .Args.topic "default"}} supplies a default.
+func renderPromptTemplate(text string, args map[string]string, props map[string]notion.Property) (string, error) {
+	if !promptTemplateAction.MatchString(text) {
+		return text, nil
+	}
+
+	tmpl, err := template.New("prompt").Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("parse prompt template: %w", err)
+	}
+
+	data := struct {
+		Args  map[string]string
+		Props map[string]string
+	}{
+		Args:  args,
+		Props: make(map[string]string, len(props)),
+	}
+	for name, prop := range props {
+		data.Props[name] = notion.PropertyText(prop)
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("render prompt template: %w", err)
+	}
+	return sb.String(), nil
+}
+
+// promptArguments returns the arguments a prompt page declares in its
+// Arguments property, followed by the built-in maxLength argument.
+func promptArguments(page notion.Page) ([]*mcp.PromptArgument, error) {
+	args, err := parsePromptArguments(notion.PropertyText(page.Properties[propArguments]))
+	if err != nil {
+		return nil, err
+	}
+	return append(args, &mcp.PromptArgument{
+		Name:        promptArgMaxLength,
+		Description: "Maximum number of characters to return; longer prompts are truncated at a block or sentence boundary",
+	}), nil
+}
+
+// parsePromptArguments parses an Arguments property value. Arguments
+// declared as a plain list are optional.
+func parsePromptArguments(text string) ([]*mcp.PromptArgument, error) {
+	text = strings.TrimSpace(text)
+	var args []*mcp.PromptArgument
+	if strings.HasPrefix(text, "[") {
+		if err := json.Unmarshal([]byte(text), &args); err != nil {
+			return nil, fmt.Errorf("invalid %s JSON: %w", propArguments, err)
+		}
+	} else {
+		for _, name := range strings.Split(text, ",") {
+			args = append(args, &mcp.PromptArgument{Name: strings.TrimSpace(name)})
+		}
+	}
+
+	return lo.Filter(args, func(arg *mcp.PromptArgument, _ int) bool {
+		arg.Name = strings.TrimSpace(arg.Name)
+		return arg.Name != "" && arg.Name != promptArgMaxLength
+	}), nil
+}
+
+// checkRequiredArguments returns an error naming the first required
+// argument that is missing or empty.
+func checkRequiredArguments(declared []*mcp.PromptArgument, args map[string]string) error {
+	for _, arg := range declared {
+		if arg.Required && args[arg.Name] == "" {
+			return fmt.Errorf("missing required argument %q", arg.Name)
+		}
+	}
+	return nil
+}
+
+// buildPromptResult builds a prompt result from rendered markdown,
+// applying the client-requested maxLength argument if present.
+func buildPromptResult(title, markdown string, args map[string]string) (*mcp.GetPromptResult, error) {
+	if raw, ok := args[promptArgMaxLength]; ok && raw != "" {
+		maxLength, err := strconv.Atoi(raw)
+		if err != nil || maxLength <= 0 {
+			return nil, fmt.Errorf("invalid %s %q: must be a positive integer", promptArgMaxLength, raw)
+		}
+		markdown = truncateText(markdown, maxLength)
+	}
+
+	return &mcp.GetPromptResult{
+		Description: title,
+		Messages:    promptMessages(markdown),
+	}, nil
+}
+
+// promptRoleHeading matches a heading that starts a prompt message, e.g.
+// "## System", "## User" or "## Assistant".
+var promptRoleHeading = regexp.MustCompile(`(?i)^#{1,3}\s+(system|user|assistant)\s*$`)
+
+// promptMessages splits prompt markdown into messages at role headings
+// outside code blocks. MCP has no system role, so System sections become
+// user messages, as does any text before the first heading; empty sections
+// are dropped. Markdown without role headings is a single user message.
+func promptMessages(markdown string) []*mcp.PromptMessage {
+	var (
+		messages []*mcp.PromptMessage
+		role     mcp.Role = "user"
+		body     strings.Builder
+		fenced   bool
+		split    bool
+	)
+	flush := func() {
+		if text := strings.TrimSpace(body.String()); text != "" {
+			messages = append(messages, &mcp.PromptMessage{Role: role, Content: &mcp.TextContent{Text: text}})
+		}
+		body.Reset()
+	}
+	for _, line := range strings.SplitAfter(markdown, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") {
+			fenced = !fenced
+		}
+		if m := promptRoleHeading.FindStringSubmatch(trimmed); m != nil && !fenced {
+			flush()
+			split = true
+			role = "user"
+			if strings.EqualFold(m[1], "assistant") {
+				role = "assistant"
+			}
+			continue
+		}
+		body.WriteString(line)
+	}
+	flush()
+
+	if !split || len(messages) == 0 {
+		return []*mcp.PromptMessage{{Role: "user", Content: &mcp.TextContent{Text: markdown}}}
+	}
+	return messages
+}
+
+// truncateText shortens text to at most maxLength characters, cutting at the
+// last block (blank line) boundary, falling back to the last sentence end and
+// finally to a hard cut. A note describing the truncation is appended after
+// the kept text and is not counted towards maxLength.
+func truncateText(text string, maxLength int) string {
+	runes := []rune(text)
+	if len(runes) <= maxLength {
+		return text
+	}
+
+	kept := string(runes[:maxLength])
+	if idx := strings.LastIndex(kept, "\n\n"); idx > 0 {
+		kept = kept[:idx]
+	} else if idx := lastSentenceEnd(kept); idx > 0 {
+		kept = kept[:idx]
+	}
+	kept = strings.TrimRight(kept, " \t\n")
+
+	return fmt.Sprintf("%s\n\n[truncated: showing %d of %d characters]",
+		kept, len([]rune(kept)), len(runes))
+}
+
+// lastSentenceEnd returns the index just past the last sentence terminator
+// followed by whitespace, or -1 if there is none.
+func lastSentenceEnd(text string) int {
+	for i := len(text) - 2; i >= 0; i-- {
+		switch text[i] {
+		case '.', '!', '?':
+			if next := text[i+1]; next == ' ' || next == '\n' {
+				return i + 1
+			}
+		}
+	}
+	return -1
+}
+
 // createResourceHandler creates a handler for a specific resource.
 func (s *Server) createResourceHandler(page notion.Page) mcp.ResourceHandler {
 	return func(ctx context.Context, request *mcp.ReadResourceRequest) (*mcp.ReadResourceResult, error) {
-		// Get page content
-		content, err := s.client.GetPageContent(ctx, page.ID)
+		if id, ok := pageIDFromURI(request.Params.URI); !ok || id != page.ID {
+			return nil, mcp.ResourceNotFoundError(request.Params.URI)
+		}
+		contents, err := s.resourceContents(ctx, page)
 		if err != nil {
-			return nil, fmt.Errorf("error fetching content: %w", err)
+			return nil, err
 		}
-		markdown := notion.PageToMarkdown(content)
-		return &mcp.ReadResourceResult{
-			Contents: []*mcp.ResourceContents{
-				{
-					URI:  "file:///resource/" + page.ID,
-					Text: markdown,
-				},
-			},
-		}, nil
+		return &mcp.ReadResourceResult{Contents: []*mcp.ResourceContents{contents}}, nil
+	}
+}
+
+// textContents returns markdown as resource contents. A page that is a
+// single code block is served as the bare code.
+func textContents(uri, markdown string) *mcp.ResourceContents {
+	text, mimeType := markdown, mimeMarkdown
+	if code, language, ok := singleCodeBlock(markdown); ok {
+		text, mimeType = code, codeMIMEType(language)
+	}
+	return &mcp.ResourceContents{URI: uri, MIMEType: mimeType, Text: text}
+}
+
+// mimeMarkdown is the MIME type of rendered pages.
+const mimeMarkdown = "text/markdown"
+
+// codeMIMETypes maps code fence languages to the MIME type of a resource
+// page holding only a block of that language. Others are text/plain.
+var codeMIMETypes = map[string]string{
+	"json":       "application/json",
+	"yaml":       "application/yaml",
+	"xml":        "application/xml",
+	"sql":        "application/sql",
+	"html":       "text/html",
+	"css":        "text/css",
+	"csv":        "text/csv",
+	"markdown":   "text/markdown",
+	"javascript": "text/javascript",
+	"typescript": "text/x-typescript",
+	"python":     "text/x-python",
+	"go":         "text/x-go",
+	"bash":       "text/x-shellscript",
+}
+
+// codeMIMEType returns the MIME type for code in a fence language.
+func codeMIMEType(language string) string {
+	if mimeType, ok := codeMIMETypes[strings.ToLower(language)]; ok {
+		return mimeType
+	}
+	return "text/plain"
+}
+
+// singleCodeBlock reports whether markdown is nothing but one fenced code
+// block, returning its code and fence language. Such resource pages are
+// served as the bare code.
+func singleCodeBlock(markdown string) (code, language string, ok bool) {
+	body, found := strings.CutPrefix(strings.TrimSpace(markdown), "```")
+	if !found {
+		return "", "", false
+	}
+	language, body, found = strings.Cut(body, "\n")
+	if !found {
+		return "", "", false
+	}
+	code, found = strings.CutSuffix(body, "\n```")
+	if !found || strings.Contains(code, "\n```") {
+		return "", "", false
+	}
+	return code, strings.TrimSpace(language), true
+}
+
+// renderPage returns the page's markdown, serving it from the render cache
+// when possible. Rendered pages are cached for the page's own TTL.
+func (s *Server) renderPage(ctx context.Context, pageID string) (string, error) {
+	key := cache.CacheKeyRenderPrefix + pageID
+	if data, err := s.cache.Get(ctx, key); err == nil && data != nil {
+		return string(data), nil
+	}
+
+	content, err := s.client.GetPageContent(ctx, pageID)
+	if err != nil {
+		return "", fmt.Errorf("error fetching content: %w", err)
+	}
+	return s.renderContent(ctx, pageID, content), nil
+}
+
+// renderContent renders fetched page content and caches the markdown.
+func (s *Server) renderContent(ctx context.Context, pageID string, content *notion.PageContent) string {
+	var opts []notion.MarkdownOption
+	if s.cfg.MarkdownFrontMatter {
+		opts = append(opts, notion.WithFrontMatter())
+		// Relations list page IDs if their titles can't be fetched
+		titles, err := notion.NewRelationResolver(s.client).PageTitles(ctx, content.Page)
+		if err != nil {
+			s.logger.Warn("failed to resolve related pages", slog.String("page_id", pageID), slog.String("error", err.Error()))
+		} else {
+			opts = append(opts, notion.WithRelationTitles(titles))
+		}
+	}
+	markdown := s.pageToMarkdown(content, opts...)
+
+	ttl := pageCacheTTL(content.Page, s.cfg.CacheTTL)
+	if err := s.cache.Set(ctx, cache.CacheKeyRenderPrefix+pageID, []byte(markdown), ttl); err != nil {
+		s.logger.Warn("failed to cache rendered page", slog.String("page_id", pageID), slog.String("error", err.Error()))
+	}
+	return markdown
+}
+
+// pageToMarkdown renders content with opts, downloading its images and
+// keeping heading colors if configured.
+func (s *Server) pageToMarkdown(content *notion.PageContent, opts ...notion.MarkdownOption) string {
+	if s.images != nil {
+		opts = append(opts, notion.WithImageStore(s.images))
+	}
+	if s.cfg.MarkdownColors {
+		opts = append(opts, notion.WithColors())
+	}
+	return notion.PageToMarkdown(content, opts...)
+}
+
+// pageCacheTTL returns the render cache TTL for a page: its CacheTTL number
+// property in seconds if set, clamped to sane bounds, else defaultTTL.
+func pageCacheTTL(page notion.Page, defaultTTL time.Duration) time.Duration {
+	prop, ok := page.Properties[propCacheTTL]
+	if !ok || prop.Type != notion.PropertyTypeNumber || prop.Number == nil {
+		return defaultTTL
 	}
+	ttl := time.Duration(*prop.Number * float64(time.Second))
+	return min(max(ttl, minPageCacheTTL), maxPageCacheTTL)
 }
 
 // createToolHandler creates a handler for a specific tool.
@@ -488,24 +1176,76 @@ func (s *Server) createToolHandler(page notion.Page) mcp.ToolHandler {
 		return nil
 	}
 
-	// If no code block, return the text content
-	if !content.HasCode {
+	// Run the page's code block, else a code file attached to it
+	var codeStr, language string
+	fromFile := false
+	if file, ok := s.toolCodeFile(content); ok {
+		language, fromFile = file.language, true
+	} else if content.HasCode {
+		code := s.toolCode(content)
+		codeStr, language = extractCodeString(code.RichText), code.Language
+	} else {
 		s.logger.Warn("no code block found", slog.String("page_id", page.ID))
 		return nil
 	}
-	codeStr := extractCodeString(content.Code.RichText)
-	language := content.Code.Language
+	timeout := toolTimeout(page)
+	format := s.toolOutputFormat(page)
+	cacheable := toolCacheable(page)
+	// registerTools skips pages whose schema is invalid
+	schema, _ := toolInputSchema(page)
+	var (
+		resolved *jsonschema.Resolved
+		order    []string
+	)
+	if schema != nil {
+		resolved, _ = schema.Resolve(nil)
+		order = schema.PropertyOrder
+	}
 
 	return func(ctx context.Context, request *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
-		// Extract code string from RichText
-
-		input := "{ numberList: [ 1, 2, 3, 4, 5 ] }"
-		if request != nil && request.Params != nil && request.Params.Arguments != nil {
-			input = string(request.Params.Arguments)
+		input := toolInput(request, schema)
+		if resolved != nil {
+			if issues := validateArguments(schema, resolved, []byte(input)); len(issues) > 0 {
+				return argumentErrorResult(schema, issues), nil
+			}
 		}
 
-		// Execute the code
-		result, err := s.executor.Execute(ctx, language, codeStr, input)
+		var (
+			result *tools.ExecutionResult
+			err    error
+			cached bool
+			key    string
+		)
+		code := codeStr
+		if fromFile {
+			// Signed file URLs expire, so the file is fetched for each call
+			code, err = s.loadCodeFile(ctx, page.ID)
+		}
+		if err == nil && cacheable {
+			key = cache.CacheKeyToolPrefix + cache.HashContent([]byte(strings.Join([]string{language, format, code, input}, "\x00")))
+			result, cached = s.cachedToolResult(ctx, key)
+		}
+		if err == nil && !cached {
+			// Execute the code, streaming its output as progress if asked to
+			execute := s.executor.ExecuteStream
+			if format == toolFormatJSON {
+				execute = s.executor.ExecuteSplit
+			}
+			result, err = execute(ctx, timeout, language, code, tools.Arguments{Input: input, Order: order}, progressReporter(ctx, request))
+			if errors.Is(err, tools.ErrDenied) {
+				s.logger.Warn("refused to run denied tool code", slog.String("page_id", page.ID), slog.String("error", err.Error()))
+			}
+			s.metrics.recordExecution(language, result, err)
+			if s.cfg.ToolResultWriteback {
+				s.writeBackResult(ctx, page.ID, result, err)
+			}
+			if cacheable && err == nil {
+				s.cacheToolResult(ctx, page, key, result)
+			}
+		}
+		if format == toolFormatJSON {
+			return jsonToolResult(language, result, err, s.cfg.ToolExitCodeErrors), nil
+		}
 		if err != nil {
 			return &mcp.CallToolResult{
 				Content: []mcp.Content{
@@ -520,15 +1260,271 @@ func (s *Server) createToolHandler(page notion.Page) mcp.ToolHandler {
 		if result.Error != "" {
 			output += fmt.Sprintf("\nError: %s", result.Error)
 		}
+		exitFailed := result.ExitCode != 0 && s.cfg.ToolExitCodeErrors
+		if exitFailed {
+			output = fmt.Sprintf("Tool failed with exit code %d\n\n%s", result.ExitCode, output)
+		}
 
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{
 				&mcp.TextContent{Text: output},
 			},
+			IsError: exitFailed || result.Error != "",
 		}, nil
 	}
 }
 
+// progressReporter returns a function sending each output line as a
+// progress notification, or nil if the request has no progress token.
+func progressReporter(ctx context.Context, request *mcp.CallToolRequest) func(string) {
+	if request == nil || request.Session == nil || request.Params == nil {
+		return nil
+	}
+	token := request.Params.GetProgressToken()
+	if token == nil {
+		return nil
+	}
+	var lines float64
+	return func(line string) {
+		lines++
+		_ = request.Session.NotifyProgress(ctx, &mcp.ProgressNotificationParams{
+			ProgressToken: token,
+			Progress:      lines,
+			Message:       line,
+		})
+	}
+}
+
+// toolTimeout returns the timeout a tool page declares, or 0 to use the
+// default if it declares none or an invalid one.
+func toolTimeout(page notion.Page) time.Duration {
+	timeout, err := time.ParseDuration(strings.TrimSpace(notion.PropertyText(page.Properties[propTimeout])))
+	if err != nil || timeout <= 0 {
+		return 0
+	}
+	return timeout
+}
+
+// toolCacheable reports whether a tool page has its Cacheable checkbox set.
+func toolCacheable(page notion.Page) bool {
+	cacheable, _ := notion.AsBool(page.Properties[propCacheable])
+	return cacheable
+}
+
+// cachedToolResult returns the cached result of a cacheable tool run.
+func (s *Server) cachedToolResult(ctx context.Context, key string) (*tools.ExecutionResult, bool) {
+	data, err := s.cache.Get(ctx, key)
+	if err != nil || data == nil {
+		return nil, false
+	}
+	var result tools.ExecutionResult
+	if json.Unmarshal(data, &result) != nil {
+		return nil, false
+	}
+	return &result, true
+}
+
+// cacheToolResult caches the result of a cacheable tool run for the page's
+// TTL. Runs that timed out or were cancelled are not cached.
+func (s *Server) cacheToolResult(ctx context.Context, page notion.Page, key string, result *tools.ExecutionResult) {
+	if result.Error != "" {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	if err := s.cache.Set(ctx, key, data, pageCacheTTL(page, s.cfg.CacheTTL)); err != nil {
+		s.logger.Warn("failed to cache tool result", slog.String("page_id", page.ID), slog.String("error", err.Error()))
+	}
+}
+
+// ToolValidation is the outcome of checking a single tool page.
+type ToolValidation struct {
+	Name     string
+	PageID   string
+	Language string
+	Err      error
+}
+
+// toolOutputFormat returns a tool page's OutputFormat property if it names
+// a format, else TOOL_OUTPUT_FORMAT.
+func (s *Server) toolOutputFormat(page notion.Page) string {
+	switch format := strings.ToLower(strings.TrimSpace(notion.PropertyText(page.Properties[propOutputFormat]))); format {
+	case toolFormatText, toolFormatJSON:
+		return format
+	}
+	return cmp.Or(s.cfg.ToolOutputFormat, toolFormatText)
+}
+
+// toolOutput is a tool result in the json output format.
+type toolOutput struct {
+	Language string `json:"language"`
+	ExitCode int    `json:"exit_code"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	Error    string `json:"error"`
+}
+
+// jsonToolResult returns the result of a tool run as a toolOutput, both as
+// JSON text and as structured content. Code that could not run reports exit
+// code -1. The result is an error if the code could not run or timed out,
+// or if it exited nonzero and exitErrors is set.
+func jsonToolResult(language string, result *tools.ExecutionResult, execErr error, exitErrors bool) *mcp.CallToolResult {
+	out := toolOutput{Language: language, ExitCode: -1}
+	if execErr != nil {
+		out.Error = execErr.Error()
+	} else {
+		out.ExitCode, out.Stdout, out.Stderr, out.Error = result.ExitCode, result.Stdout, result.Stderr, result.Error
+	}
+	data, _ := json.Marshal(out)
+	return &mcp.CallToolResult{
+		Content:           []mcp.Content{&mcp.TextContent{Text: string(data)}},
+		StructuredContent: out,
+		IsError:           out.Error != "" || (exitErrors && out.ExitCode != 0),
+	}
+}
+
+// writeBackResult appends a tool run's output, exit code and time to the
+// tool page. Failures are logged and don't affect the tool call.
+func (s *Server) writeBackResult(ctx context.Context, pageID string, result *tools.ExecutionResult, execErr error) {
+	text, caption := "", fmt.Sprintf("Run at %s", time.Now().UTC().Format(time.RFC3339))
+	if execErr != nil {
+		text = execErr.Error()
+		caption += ", failed"
+	} else {
+		text = result.Output
+		if result.Error != "" {
+			text += "\n" + result.Error
+		}
+		caption += fmt.Sprintf(", exit code %d", result.ExitCode)
+	}
+	if err := s.client.AppendBlockChildren(context.WithoutCancel(ctx), pageID, []notion.Block{notion.NewCodeBlock("plain text", text, caption)}); err != nil {
+		s.logger.Warn("failed to write tool result back to Notion", slog.String("page_id", pageID), slog.String("error", err.Error()))
+	}
+}
+
+// ValidateTools checks every tool page in the database without running any
+// tool code: the language must be allowed, its runtime installed and the
+// code must pass a syntax check.
+func (s *Server) ValidateTools(ctx context.Context) ([]ToolValidation, error) {
+	pages, err := s.client.GetAllPages(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("query pages: %w", err)
+	}
+	toolPages := lo.Filter(pages, func(page notion.Page, _ int) bool {
+		return pageKind(s.cfg, page) == pageTypeTool
+	})
+
+	names := s.assignNames(toolPages)
+	results := make([]ToolValidation, 0, len(toolPages))
+	for _, page := range toolPages {
+		content, err := s.client.GetPageContent(ctx, page.ID)
+		if err != nil {
+			results = append(results, ToolValidation{
+				Name:   names[page.ID],
+				PageID: page.ID,
+				Err:    fmt.Errorf("fetch content: %w", err),
+			})
+			continue
+		}
+		v := s.validateTool(ctx, page, content)
+		v.Name = names[page.ID]
+		results = append(results, v)
+	}
+	return results, nil
+}
+
+// validateTool checks the input schema and the code block or attached code
+// file of a tool page.
+func (s *Server) validateTool(ctx context.Context, page notion.Page, content *notion.PageContent) ToolValidation {
+	v := ToolValidation{Name: s.pageName(pageTypeTool, page), PageID: page.ID}
+	if _, err := toolInputSchema(page); err != nil {
+		v.Err = err
+		return v
+	}
+	if file, ok := s.toolCodeFile(content); ok || !content.HasCode {
+		if !ok {
+			v.Err = fmt.Errorf("no code block found")
+			return v
+		}
+		v.Language = file.language
+		code, err := s.loadCodeFile(ctx, page.ID)
+		if err == nil {
+			err = s.executor.Check(ctx, v.Language, code)
+		}
+		v.Err = err
+		return v
+	}
+	code := s.toolCode(content)
+	v.Language = code.Language
+	v.Err = s.executor.Check(ctx, v.Language, extractCodeString(code.RichText))
+	return v
+}
+
+// toolCode picks the code block a tool page runs: the one marked as its
+// entrypoint, else the first in a language the executor supports, so pages
+// may carry setup notes or examples in other languages. If none is runnable
+// it returns the first, letting execution report why.
+func (s *Server) toolCode(content *notion.PageContent) notion.CodeBlock {
+	if code, ok := s.runnableCode(content); ok {
+		return code
+	}
+	return content.Code
+}
+
+// runnableCode returns the entrypoint code block of a tool page, else the
+// first in a language the executor supports.
+func (s *Server) runnableCode(content *notion.PageContent) (notion.CodeBlock, bool) {
+	if code, ok := entrypointCode(content); ok {
+		return code, true
+	}
+	for _, code := range content.CodeBlocks {
+		if s.executor.Supports(code.Language) {
+			return code, true
+		}
+	}
+	return notion.CodeBlock{}, false
+}
+
+// toolCodeFile returns the code file attached to a tool page if no code
+// block is runnable. Other code blocks, such as results written back by
+// TOOL_RESULT_WRITEBACK, never shadow the file.
+func (s *Server) toolCodeFile(content *notion.PageContent) (codeFile, bool) {
+	if _, ok := s.runnableCode(content); ok {
+		return codeFile{}, false
+	}
+	return s.codeFile(content)
+}
+
+// entrypointCode returns the code block a page marks as its entrypoint:
+// one whose caption mentions "entrypoint", or the one selected by the
+// Entrypoint property.
+func entrypointCode(content *notion.PageContent) (notion.CodeBlock, bool) {
+	for _, code := range content.CodeBlocks {
+		if strings.Contains(strings.ToLower(extractCodeString(code.Caption)), "entrypoint") {
+			return code, true
+		}
+	}
+
+	selector := strings.TrimSpace(notion.PropertyText(content.Page.Properties[propEntrypoint]))
+	if selector == "" {
+		return notion.CodeBlock{}, false
+	}
+	if n, err := strconv.Atoi(selector); err == nil {
+		if n >= 1 && n <= len(content.CodeBlocks) {
+			return content.CodeBlocks[n-1], true
+		}
+		return notion.CodeBlock{}, false
+	}
+	for _, code := range content.CodeBlocks {
+		if strings.EqualFold(code.Language, selector) {
+			return code, true
+		}
+	}
+	return notion.CodeBlock{}, false
+}
+
 // extractCodeString extracts the code string from RichText array.
 func extractCodeString(richTexts []notion.RichText) string {
 	var sb strings.Builder
@@ -538,18 +1534,50 @@ func extractCodeString(richTexts []notion.RichText) string {
 	return sb.String()
 }
 
-// getPageTitle extracts the title from a page.
-func getPageTitle(page notion.Page) string {
-	if title, ok := page.Properties["Name"]; ok {
-		if len(title.Title) > 0 {
-			t := title.Title[0].PlainText
-			if t != "" {
-				return t
+// Entry describes a page the server exposes as a prompt, resource or tool.
+type Entry struct {
+	Type        string `json:"type"`
+	Title       string `json:"title"`
+	Name        string `json:"name"`
+	PageID      string `json:"page_id"`
+	Description string `json:"description"`
+}
+
+// ListEntries classifies pages by the type field and returns the prompts,
+// resources and tools, in that order. Pages of any other type are skipped.
+func ListEntries(pages []notion.Page, cfg *config.Config) []Entry {
+	s := &Server{cfg: cfg}
+	names := s.assignNames(pages)
+	var entries []Entry
+	for _, pageType := range []string{pageTypePrompt, pageTypeResource, pageTypeTool} {
+		for _, page := range pages {
+			if pageKind(cfg, page) != pageType {
+				continue
+			}
+			title := getPageTitle(page)
+			name := s.pageName(pageType, page)
+			if assigned, ok := names[page.ID]; ok {
+				name = assigned
 			}
+			entries = append(entries, Entry{
+				Type:        pageType,
+				Title:       title,
+				Name:        name,
+				PageID:      page.ID,
+				Description: getPageDescription(page),
+			})
 		}
 	}
-	return page.ID
+	return entries
 }
+
+// getPageTitle returns a page's title, or its ID if it has none.
+func getPageTitle(page notion.Page) string {
+	return cmp.Or(notion.PageTitle(page), page.ID)
+}
+
+// getPageDescription returns the first text of a page's Description
+// property, or "" if it has none.
 func getPageDescription(page notion.Page) string {
 	if description, ok := page.Properties["Description"]; ok {
 		if len(description.RichText) > 0 {
@@ -558,42 +1586,3 @@ func getPageDescription(page notion.Page) string {
 	}
 	return ""
 }
-
-// sanitizeToolName converts a page title to a valid tool/prompt name.
-// MCP requires: ^[a-z][a-z0-9_-]*$ (must start with lowercase letter)
-func sanitizeToolName(name string) string {
-	// Convert to lowercase
-	name = strings.ToLower(strings.TrimSpace(name))
-	if name == "" {
-		return ""
-	}
-
-	// Replace spaces and special chars with underscores
-	var result strings.Builder
-	firstChar := true
-	for _, c := range name {
-		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '_' || c == '-' {
-			// If first char is a number, underscore, or hyphen, prepend 'p_'
-			if firstChar && (c >= '0' && c <= '9' || c == '_' || c == '-') {
-				result.WriteString("p_")
-			}
-			result.WriteRune(c)
-			firstChar = false
-		} else if c == ' ' || c == '\t' {
-			if !firstChar {
-				result.WriteRune('_')
-			}
-		}
-	}
-
-	sanitized := result.String()
-	// Ensure it starts with a lowercase letter
-	if sanitized == "" {
-		return ""
-	}
-	if sanitized[0] < 'a' || sanitized[0] > 'z' {
-		return "p_" + sanitized
-	}
-
-	return sanitized
-}
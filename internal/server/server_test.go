@@ -1,12 +1,35 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/google/jsonschema-go/jsonschema"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+
+	"github.com/nixihz/notion-as-mcp/internal/cache"
+	"github.com/nixihz/notion-as-mcp/internal/config"
 	"github.com/nixihz/notion-as-mcp/internal/notion"
+	"github.com/nixihz/notion-as-mcp/internal/tools"
 )
 
-func TestSanitizeToolName(t *testing.T) {
+func TestNameSanitizer(t *testing.T) {
 	tests := []struct {
 		name     string
 		input    string
@@ -97,18 +120,99 @@ func TestSanitizeToolName(t *testing.T) {
 		{
 			name:     "Chinese characters",
 			input:    "测试工具",
-			expected: "",
+			expected: "ce_shi_gong_ju",
+		},
+		{
+			name:     "Korean characters",
+			input:    "데이터 분석",
+			expected: "deiteo_bunseog",
+		},
+		{
+			name:     "Japanese kana",
+			input:    "メモ",
+			expected: "memo",
+		},
+		{
+			name:     "accented letters",
+			input:    "Crème Brûlée",
+			expected: "creme_brulee",
+		},
+		{
+			name:     "letters without decomposition",
+			input:    "Straße Æsir",
+			expected: "strasse_aesir",
+		},
+		{
+			name:     "leading symbol before words",
+			input:    "📌 Pinned notes",
+			expected: "pinned_notes",
+		},
+		{
+			name:     "already prefixed name",
+			input:    "p_123test",
+			expected: "p_123test",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			result := sanitizeToolName(tt.input)
+			result := NameSanitizer{}.Name(pageTypePrompt, tt.input)
 			if result != tt.expected {
-				t.Errorf("sanitizeToolName(%q) = %q, want %q", tt.input, result, tt.expected)
+				t.Errorf("Name(%q) = %q, want %q", tt.input, result, tt.expected)
 			}
 		})
 	}
+
+	t.Run("Prefix per kind", func(t *testing.T) {
+		s := &Server{cfg: &config.Config{NamePrefixes: "prompt=p_,resource=r_,tool=tool_"}}
+		title := notion.Page{Properties: map[string]notion.Property{
+			"Name": {Type: notion.PropertyTypeTitle, Title: []notion.Title{{PlainText: "2024 Review"}}},
+		}}
+		for kind, want := range map[string]string{
+			pageTypePrompt:   "p_2024_review",
+			pageTypeResource: "r_2024_review",
+			pageTypeTool:     "tool_2024_review",
+		} {
+			got := s.pageName(kind, title)
+			if got != want {
+				t.Errorf("pageName(%s) = %q, want %q", kind, got, want)
+			}
+			// Sanitizing a name again leaves it unchanged: one prefix only
+			if again := s.names().Name(kind, got); again != got {
+				t.Errorf("Name(%s, %q) = %q, want it unchanged", kind, got, again)
+			}
+		}
+	})
+
+	t.Run("Default prefix", func(t *testing.T) {
+		s := &Server{cfg: &config.Config{NamePrefixes: "resource=r_"}}
+		if got := s.names().Name(pageTypeTool, "42"); got != defaultNamePrefix+"42" {
+			t.Errorf("Name() = %q, want %q", got, defaultNamePrefix+"42")
+		}
+	})
+
+	t.Run("Page ID fallback", func(t *testing.T) {
+		s := &Server{cfg: &config.Config{NamePrefixes: "tool=t_"}}
+		for title, want := range map[string]string{
+			"数据分析":       "shu_ju_fen_xi",
+			"🚀":          "t_1a2b3c4d",
+			"Café":       "cafe",
+			"Ærøskøbing": "aeroskobing",
+		} {
+			page := notion.Page{
+				ID: "1A2B3C4D-5e6f-7081-92a3-b4c5d6e7f809",
+				Properties: map[string]notion.Property{
+					"Name": {
+						Type:  notion.PropertyTypeTitle,
+						Title: []notion.Title{{PlainText: title}},
+					},
+				},
+			}
+			if got := s.pageName(pageTypeTool, page); got != want {
+				t.Errorf("pageName(%q) = %q, want %q", title, got, want)
+			}
+		}
+	})
 }
 
 func TestGetPageTitle(t *testing.T) {
@@ -319,3 +423,2501 @@ func TestExtractCodeString(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateTool(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	s := &Server{cfg: &config.Config{}, executor: tools.NewExecutor(5*time.Second, "python")}
+	page := notion.Page{
+		ID: "page-1",
+		Properties: map[string]notion.Property{
+			"Name": {Type: notion.PropertyTypeTitle, Title: []notion.Title{{PlainText: "Broken Tool"}}},
+		},
+	}
+	code := func(language, text string) *notion.PageContent {
+		return &notion.PageContent{
+			HasCode: true,
+			Code:    notion.CodeBlock{Language: language, RichText: []notion.RichText{{PlainText: text}}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		content *notion.PageContent
+		wantErr bool
+	}{
+		{"Valid python", code("python", "print('ok')"), false},
+		{"Syntactically invalid python", code("python", "def broken(:\n    pass"), true},
+		{"Disallowed language", code("bash", "echo ok"), true},
+		{"No code block", &notion.PageContent{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := s.validateTool(context.Background(), page, tt.content)
+			if (v.Err != nil) != tt.wantErr {
+				t.Errorf("validateTool() error = %v, wantErr %v", v.Err, tt.wantErr)
+			}
+			if v.Name != "broken_tool" || v.PageID != "page-1" {
+				t.Errorf("validateTool() = {%q, %q}, want {\"broken_tool\", \"page-1\"}", v.Name, v.PageID)
+			}
+		})
+	}
+}
+
+func TestToolCode(t *testing.T) {
+	block := func(language, text string) notion.CodeBlock {
+		return notion.CodeBlock{Language: language, RichText: []notion.RichText{{PlainText: text}}}
+	}
+	content := &notion.PageContent{
+		HasCode:    true,
+		Code:       block("bash", "pip install requests"),
+		CodeBlocks: []notion.CodeBlock{block("bash", "pip install requests"), block("python", "print('main')")},
+	}
+
+	tests := []struct {
+		languages string
+		want      string
+	}{
+		{"bash,python", "bash"},
+		{"python", "python"},
+		{"go", "bash"}, // nothing runnable: the first block reports the error
+	}
+	for _, tt := range tests {
+		s := &Server{executor: tools.NewExecutor(time.Second, tt.languages)}
+		if got := s.toolCode(content).Language; got != tt.want {
+			t.Errorf("toolCode() with languages %q = %s block, want %s", tt.languages, got, tt.want)
+		}
+	}
+}
+
+func TestToolEntrypoint(t *testing.T) {
+	block := func(language, text, caption string) notion.CodeBlock {
+		code := notion.CodeBlock{Language: language, RichText: []notion.RichText{{PlainText: text}}}
+		if caption != "" {
+			code.Caption = []notion.RichText{{PlainText: caption}}
+		}
+		return code
+	}
+	withProp := func(value string) notion.Page {
+		return notion.Page{ID: "t1", Properties: map[string]notion.Property{
+			propEntrypoint: {Type: notion.PropertyTypeRichText, RichText: []notion.RichText{{PlainText: value}}},
+		}}
+	}
+
+	t.Run("Selection", func(t *testing.T) {
+		blocks := []notion.CodeBlock{block("bash", "echo setup", ""), block("python", "print(1)", ""), block("bash", "echo main", "")}
+		tests := []struct {
+			name    string
+			page    notion.Page
+			blocks  []notion.CodeBlock
+			want    string
+			wantSet bool
+		}{
+			{"No marker", notion.Page{}, blocks, "", false},
+			{"Caption", notion.Page{}, []notion.CodeBlock{blocks[0], block("bash", "echo main", "Entrypoint: run this")}, "echo main", true},
+			{"Property by position", withProp("3"), blocks, "echo main", true},
+			{"Property by language", withProp("Python"), blocks, "print(1)", true},
+			{"Property out of range", withProp("4"), blocks, "", false},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				code, ok := entrypointCode(&notion.PageContent{Page: tt.page, CodeBlocks: tt.blocks})
+				if ok != tt.wantSet || extractCodeString(code.RichText) != tt.want {
+					t.Errorf("entrypointCode() = %q, %v, want %q, %v", extractCodeString(code.RichText), ok, tt.want, tt.wantSet)
+				}
+			})
+		}
+	})
+
+	t.Run("Captioned block is executed", func(t *testing.T) {
+		if _, err := exec.LookPath("bash"); err != nil {
+			t.Skip("bash not installed")
+		}
+		first, second := block("bash", "echo first", ""), block("bash", "echo second", "entrypoint")
+		s := &Server{
+			cfg: &config.Config{},
+			client: &fakeClient{contents: map[string]*notion.PageContent{
+				"t1": {HasCode: true, Code: first, CodeBlocks: []notion.CodeBlock{first, second}},
+			}},
+			executor: tools.NewExecutor(5*time.Second, "bash"),
+			logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+
+		handler := s.createToolHandler(notion.Page{ID: "t1"})
+		if handler == nil {
+			t.Fatal("createToolHandler() = nil")
+		}
+		result, err := handler(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		out := result.Content[0].(*mcp.TextContent).Text
+		if !strings.Contains(out, "second") || strings.Contains(out, "first") {
+			t.Errorf("output = %q, want the entrypoint block's output", out)
+		}
+	})
+}
+
+func TestToolTimeout(t *testing.T) {
+	withProp := func(value string) notion.Page {
+		return notion.Page{ID: "t1", Properties: map[string]notion.Property{
+			propTimeout: {Type: notion.PropertyTypeRichText, RichText: []notion.RichText{{PlainText: value}}},
+		}}
+	}
+
+	t.Run("Parsing", func(t *testing.T) {
+		tests := []struct {
+			name string
+			page notion.Page
+			want time.Duration
+		}{
+			{"Unset", notion.Page{}, 0},
+			{"Duration", withProp("2m"), 2 * time.Minute},
+			{"Padded", withProp(" 90s "), 90 * time.Second},
+			{"Invalid", withProp("soon"), 0},
+			{"Negative", withProp("-5s"), 0},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				if got := toolTimeout(tt.page); got != tt.want {
+					t.Errorf("toolTimeout() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	})
+
+	t.Run("Declared timeout cancels execution", func(t *testing.T) {
+		if _, err := exec.LookPath("bash"); err != nil {
+			t.Skip("bash not installed")
+		}
+		code := notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "sleep 10"}}}
+		s := &Server{
+			cfg: &config.Config{},
+			client: &fakeClient{contents: map[string]*notion.PageContent{
+				"t1": {HasCode: true, Code: code, CodeBlocks: []notion.CodeBlock{code}},
+			}},
+			executor: tools.NewExecutor(30*time.Second, "bash"),
+			logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+
+		handler := s.createToolHandler(withProp("2s"))
+		if handler == nil {
+			t.Fatal("createToolHandler() = nil")
+		}
+		start := time.Now()
+		result, err := handler(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed < 2*time.Second || elapsed > 8*time.Second {
+			t.Errorf("tool ran for %v, want it cancelled at 2s", elapsed)
+		}
+		if out := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(out, "timed out after 2s") {
+			t.Errorf("output = %q, want a 2s timeout error", out)
+		}
+		if !result.IsError {
+			t.Error("IsError = false, want true for a timeout")
+		}
+	})
+}
+
+func TestToolResultWriteback(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	code := notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "echo audited; exit 3"}}}
+	run := func(t *testing.T, client *fakeClient, writeback bool) *mcp.CallToolResult {
+		t.Helper()
+		client.contents = map[string]*notion.PageContent{
+			"t1": {HasCode: true, Code: code, CodeBlocks: []notion.CodeBlock{code}},
+		}
+		s := &Server{
+			cfg:      &config.Config{ToolResultWriteback: writeback},
+			client:   client,
+			executor: tools.NewExecutor(5*time.Second, "bash"),
+			logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		result, err := s.createToolHandler(notion.Page{ID: "t1"})(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		return result
+	}
+
+	t.Run("Appended to the page", func(t *testing.T) {
+		client := &fakeClient{}
+		run(t, client, true)
+		if len(client.appended) != 1 {
+			t.Fatalf("appended %d blocks, want 1", len(client.appended))
+		}
+		data, _ := json.Marshal(client.appended[0])
+		for _, want := range []string{`"type":"code"`, "audited", "exit code 3"} {
+			if !strings.Contains(string(data), want) {
+				t.Errorf("appended block %s does not contain %s", data, want)
+			}
+		}
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		client := &fakeClient{}
+		run(t, client, false)
+		if len(client.appended) != 0 {
+			t.Errorf("appended %d blocks with writeback disabled, want 0", len(client.appended))
+		}
+	})
+
+	t.Run("Write failure is not fatal", func(t *testing.T) {
+		client := &fakeClient{appendErr: errors.New("restricted_resource")}
+		result := run(t, client, true)
+		if result.IsError || !strings.Contains(result.Content[0].(*mcp.TextContent).Text, "audited") {
+			t.Errorf("result = %+v, want the tool output", result)
+		}
+	})
+}
+
+func TestToolJSONOutput(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	call := func(t *testing.T, cfg *config.Config, page notion.Page, script string) *mcp.CallToolResult {
+		t.Helper()
+		code := notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: script}}}
+		s := &Server{
+			cfg: cfg,
+			client: &fakeClient{contents: map[string]*notion.PageContent{
+				page.ID: {HasCode: true, Code: code, CodeBlocks: []notion.CodeBlock{code}},
+			}},
+			executor: tools.NewExecutor(5*time.Second, "bash"),
+			logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		result, err := s.createToolHandler(page)(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		return result
+	}
+	decode := func(t *testing.T, result *mcp.CallToolResult) map[string]any {
+		t.Helper()
+		var out map[string]any
+		if err := json.Unmarshal([]byte(result.Content[0].(*mcp.TextContent).Text), &out); err != nil {
+			t.Fatalf("tool output is not JSON: %v", err)
+		}
+		return out
+	}
+
+	t.Run("Nonzero exit is an error", func(t *testing.T) {
+		result := call(t, &config.Config{ToolOutputFormat: "json", ToolExitCodeErrors: true}, notion.Page{ID: "t1"}, "echo done; echo oops >&2; exit 4")
+		want := map[string]any{"language": "bash", "exit_code": 4.0, "stdout": "done\n", "stderr": "oops\n", "error": ""}
+		if got := decode(t, result); !reflect.DeepEqual(got, want) {
+			t.Errorf("output = %v, want %v", got, want)
+		}
+		if !result.IsError {
+			t.Error("IsError = false, want true for a nonzero exit")
+		}
+	})
+
+	t.Run("Page property selects JSON", func(t *testing.T) {
+		page := notion.Page{ID: "t2", Properties: map[string]notion.Property{
+			"OutputFormat": {Type: notion.PropertyTypeSelect, Select: &notion.Select{Name: "JSON"}},
+		}}
+		result := call(t, &config.Config{}, page, "echo ok")
+		if got := decode(t, result); got["exit_code"] != 0.0 || got["stdout"] != "ok\n" {
+			t.Errorf("output = %v, want exit code 0 and stdout ok", got)
+		}
+		if result.IsError {
+			t.Error("IsError = true, want false for a zero exit")
+		}
+	})
+
+	t.Run("Text by default", func(t *testing.T) {
+		result := call(t, &config.Config{}, notion.Page{ID: "t3"}, "echo ok")
+		if text := result.Content[0].(*mcp.TextContent).Text; !strings.HasPrefix(text, "Language: bash\nExit Code: 0") {
+			t.Errorf("output = %q, want the text format", text)
+		}
+	})
+}
+
+func TestToolExitCodeError(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	code := notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "echo nope; exit 2"}}}
+	tests := []struct {
+		name       string
+		exitErrors bool
+		want       bool
+	}{
+		{"Flagged", true, true},
+		{"Disabled", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				cfg: &config.Config{ToolExitCodeErrors: tt.exitErrors},
+				client: &fakeClient{contents: map[string]*notion.PageContent{
+					"t1": {HasCode: true, Code: code, CodeBlocks: []notion.CodeBlock{code}},
+				}},
+				executor: tools.NewExecutor(5*time.Second, "bash"),
+				logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+			}
+			result, err := s.createToolHandler(notion.Page{ID: "t1"})(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("handler failed: %v", err)
+			}
+			if result.IsError != tt.want {
+				t.Errorf("IsError = %v, want %v", result.IsError, tt.want)
+			}
+			out := result.Content[0].(*mcp.TextContent).Text
+			if got := strings.HasPrefix(out, "Tool failed with exit code 2\n"); got != tt.want {
+				t.Errorf("output = %q, exit code headline = %v, want %v", out, got, tt.want)
+			}
+			if !strings.Contains(out, "Exit Code: 2") {
+				t.Errorf("output = %q, want the exit code", out)
+			}
+		})
+	}
+}
+
+func TestToolCacheable(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	runs := filepath.Join(t.TempDir(), "runs")
+	code := notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "echo run >> " + runs + "; echo ok"}}}
+	store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	defer store.Close()
+	s := &Server{
+		cfg:   &config.Config{CacheTTL: time.Minute},
+		cache: store,
+		client: &fakeClient{contents: map[string]*notion.PageContent{
+			"t1": {HasCode: true, Code: code, CodeBlocks: []notion.CodeBlock{code}},
+		}},
+		executor: tools.NewExecutor(5*time.Second, "bash"),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	handler := s.createToolHandler(notion.Page{ID: "t1", Properties: map[string]notion.Property{
+		propCacheable: {Type: notion.PropertyTypeCheckbox, Checkbox: true},
+	}})
+	call := func(args string) string {
+		t.Helper()
+		result, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Arguments: json.RawMessage(args)}})
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+	countRuns := func() int {
+		t.Helper()
+		data, err := os.ReadFile(runs)
+		if err != nil {
+			t.Fatalf("read run log: %v", err)
+		}
+		return strings.Count(string(data), "run")
+	}
+
+	first, second := call(`{"n":1}`), call(`{"n":1}`)
+	if n := countRuns(); n != 1 {
+		t.Errorf("tool ran %d times for identical input, want 1", n)
+	}
+	if first != second || !strings.Contains(second, "ok") {
+		t.Errorf("cached output = %q, want %q", second, first)
+	}
+	call(`{"n":2}`)
+	if n := countRuns(); n != 2 {
+		t.Errorf("tool ran %d times after new input, want 2", n)
+	}
+}
+
+func TestToolCodeFile(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/files/tool.py" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.WriteString(w, "import sys\nprint('python', sys.version_info[0])\n")
+	}))
+	defer ts.Close()
+
+	fileBlock := func(name, src string) notion.Block {
+		return notion.Block{Type: notion.BlockTypeFile, Content: map[string]any{
+			"type":     "external",
+			"external": map[string]any{"url": src},
+			"name":     name,
+		}}
+	}
+	client := &fakeClient{contents: map[string]*notion.PageContent{
+		"t1": {Blocks: []notion.Block{
+			fileBlock("notes.txt", ts.URL+"/files/notes.txt"),
+			fileBlock("tool.py", ts.URL+"/files/tool.py"),
+		}},
+		"t2": {Blocks: []notion.Block{fileBlock("tool.rb", ts.URL+"/files/tool.rb")}},
+	}}
+	s := &Server{
+		cfg:      &config.Config{},
+		client:   client,
+		executor: tools.NewExecutor(5*time.Second, "python"),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	handler := s.createToolHandler(notion.Page{ID: "t1"})
+	if handler == nil {
+		t.Fatal("createToolHandler() = nil for a page with a code file")
+	}
+	result, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}})
+	if err != nil {
+		t.Fatalf("handler failed: %v", err)
+	}
+	text := result.Content[0].(*mcp.TextContent).Text
+	if result.IsError || !strings.Contains(text, "Language: python") || !strings.Contains(text, "python 3") {
+		t.Errorf("result = %q, want the file run by python", text)
+	}
+
+	// Ruby is not an allowed language
+	if handler := s.createToolHandler(notion.Page{ID: "t2"}); handler != nil {
+		t.Error("createToolHandler() registered a code file in a disallowed language")
+	}
+
+	t.Run("Written-back result does not replace the file", func(t *testing.T) {
+		s.cfg.ToolResultWriteback = true
+		defer func() { s.cfg.ToolResultWriteback = false }()
+		if _, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}); err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		if len(client.appended) != 1 || client.appended[0].Type != notion.BlockTypeCode {
+			t.Fatalf("appended = %+v, want the result as a code block", client.appended)
+		}
+
+		// The page is registered again with the result on it
+		written := notion.CodeBlock{Language: "plain text", RichText: []notion.RichText{{PlainText: "python 3"}}}
+		page := client.contents["t1"]
+		client.contents["t1"] = &notion.PageContent{
+			Blocks:     append(page.Blocks, client.appended...),
+			HasCode:    true,
+			Code:       written,
+			CodeBlocks: []notion.CodeBlock{written},
+		}
+		handler := s.createToolHandler(notion.Page{ID: "t1"})
+		if handler == nil {
+			t.Fatal("createToolHandler() = nil after the result was written back")
+		}
+		result, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}})
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		if text := result.Content[0].(*mcp.TextContent).Text; result.IsError || !strings.Contains(text, "Language: python") {
+			t.Errorf("result = %q, want the file still run by python", text)
+		}
+	})
+}
+
+func TestToolInputSchema(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	code := notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "echo ok"}}}
+	s := &Server{
+		cfg: &config.Config{},
+		client: &fakeClient{contents: map[string]*notion.PageContent{
+			"t1": {HasCode: true, Code: code, CodeBlocks: []notion.CodeBlock{code}},
+		}},
+		executor: tools.NewExecutor(5*time.Second, "bash"),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	schema := `{"type":"object","properties":{"name":{"type":"string"},"count":{"type":"integer"}},"required":["name"]}`
+	handler := s.createToolHandler(notion.Page{ID: "t1", Properties: map[string]notion.Property{
+		propInputSchema: {Type: notion.PropertyTypeRichText, RichText: []notion.RichText{{PlainText: schema}}},
+	}})
+	call := func(args string) *mcp.CallToolResult {
+		t.Helper()
+		result, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Arguments: json.RawMessage(args)}})
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		return result
+	}
+
+	tests := []struct {
+		args string
+		want []argumentIssue
+	}{
+		{`{"count":2}`, []argumentIssue{{Path: "/name", Expected: "string", Message: "missing required property"}}},
+		{`{"name":"a","count":"two"}`, []argumentIssue{{Path: "/count", Expected: "integer", Message: "got string"}}},
+		{`[]`, []argumentIssue{{Path: "", Expected: "object", Message: "got array"}}},
+	}
+	for _, tt := range tests {
+		result := call(tt.args)
+		if !result.IsError {
+			t.Errorf("call(%s) IsError = false, want true", tt.args)
+			continue
+		}
+		out, ok := result.StructuredContent.(argumentError)
+		if !ok {
+			t.Fatalf("call(%s) StructuredContent = %T, want argumentError", tt.args, result.StructuredContent)
+		}
+		if !reflect.DeepEqual(out.Issues, tt.want) {
+			t.Errorf("call(%s) issues = %+v, want %+v", tt.args, out.Issues, tt.want)
+		}
+		text := result.Content[0].(*mcp.TextContent).Text
+		if !strings.Contains(text, `"schema":{"type":"object"`) {
+			t.Errorf("call(%s) text = %s, want the schema", tt.args, text)
+		}
+	}
+
+	if result := call(`{"name":"a","count":2}`); result.IsError {
+		t.Errorf("valid call IsError = true: %s", result.Content[0].(*mcp.TextContent).Text)
+	}
+	if _, err := toolInputSchema(notion.Page{Properties: map[string]notion.Property{
+		propInputSchema: {Type: notion.PropertyTypeRichText, RichText: []notion.RichText{{PlainText: `{"type":"string"}`}}},
+	}}); err == nil {
+		t.Error("toolInputSchema() accepted a non-object schema")
+	}
+}
+
+func TestToolInput(t *testing.T) {
+	withDefault := &jsonschema.Schema{Type: "object", Default: json.RawMessage(`{"limit":10}`)}
+	tests := []struct {
+		name    string
+		request *mcp.CallToolRequest
+		schema  *jsonschema.Schema
+		want    string
+	}{
+		{"no request", nil, nil, "{}"},
+		{"no arguments", &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}, nil, "{}"},
+		{"schema default", &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{}}, withDefault, `{"limit":10}`},
+		{"arguments", &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Arguments: json.RawMessage(`{"limit":5}`)}}, withDefault, `{"limit":5}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := toolInput(tt.request, tt.schema); got != tt.want {
+				t.Errorf("toolInput() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	code := notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "echo ok"}}}
+	store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewCache() error = %v", err)
+	}
+	defer store.Close()
+	metrics := NewMetrics()
+	s := &Server{
+		cfg:   &config.Config{CacheTTL: time.Minute},
+		cache: countingCache{Cache: store, metrics: metrics},
+		client: &fakeClient{contents: map[string]*notion.PageContent{
+			"t1": {HasCode: true, Code: code, CodeBlocks: []notion.CodeBlock{code}},
+		}},
+		executor: tools.NewExecutor(5*time.Second, "bash"),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		metrics:  metrics,
+	}
+	handler := s.createToolHandler(notion.Page{ID: "t1", Properties: map[string]notion.Property{
+		propCacheable: {Type: notion.PropertyTypeCheckbox, Checkbox: true},
+	}})
+	for range 2 {
+		if _, err := handler(context.Background(), &mcp.CallToolRequest{Params: &mcp.CallToolParamsRaw{Arguments: json.RawMessage(`{}`)}}); err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+	}
+	count := metrics.middleware()(func(context.Context, string, mcp.Request) (mcp.Result, error) { return nil, nil })
+	count(context.Background(), "tools/call", nil)
+	metrics.notion.Observe(notion.RequestEvent{Method: "GET", Endpoint: "/pages/{id}", Status: 200, Duration: 250 * time.Millisecond})
+	metrics.refreshHealth = func() []cache.RefreshHealth {
+		return []cache.RefreshHealth{{Key: cache.CacheKeyPrompts, Interval: time.Minute, LastRefresh: time.Unix(1700000000, 0)}}
+	}
+
+	ts := httptest.NewServer(metrics)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("scrape failed: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	for _, want := range []string{
+		`notion_mcp_requests_total{method="tools/call"} 1` + "\n",
+		"# TYPE notion_mcp_tool_executions_total counter\n",
+		`notion_mcp_tool_executions_total{language="bash",outcome="success"} 1` + "\n",
+		`notion_mcp_cache_requests_total{result="hit"} 1` + "\n",
+		`notion_mcp_cache_requests_total{result="miss"} 1` + "\n",
+		`notion_mcp_notion_requests_total{endpoint="GET /pages/{id}"} 1` + "\n",
+		`notion_mcp_notion_request_duration_seconds_sum{endpoint="GET /pages/{id}"} 0.25` + "\n",
+		`notion_mcp_cache_refresh_healthy{key="mcp:prompts"} 0` + "\n",
+		`notion_mcp_cache_refresh_last_success_timestamp_seconds{key="mcp:prompts"} 1700000000` + "\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestToolProgress(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	script := "echo one; sleep 0.1; echo two; sleep 0.1; echo three"
+	code := notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: script}}}
+	s := &Server{
+		cfg: &config.Config{},
+		client: &fakeClient{contents: map[string]*notion.PageContent{
+			"t1": {HasCode: true, Code: code, CodeBlocks: []notion.CodeBlock{code}},
+		}},
+		executor: tools.NewExecutor(5*time.Second, "bash"),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	server.AddTool(&mcp.Tool{Name: "lines", InputSchema: map[string]any{"type": "object"}}, s.createToolHandler(notion.Page{ID: "t1"}))
+
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server.Connect() failed: %v", err)
+	}
+	defer serverSession.Close()
+
+	progress := make(chan *mcp.ProgressNotificationParams, 10)
+	client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "1.0.0"}, &mcp.ClientOptions{
+		ProgressNotificationHandler: func(_ context.Context, req *mcp.ProgressNotificationClientRequest) {
+			progress <- req.Params
+		},
+	})
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client.Connect() failed: %v", err)
+	}
+	defer session.Close()
+
+	params := &mcp.CallToolParams{Name: "lines", Meta: mcp.Meta{"progressToken": "tok"}}
+	result, err := session.CallTool(ctx, params)
+	if err != nil {
+		t.Fatalf("CallTool() failed: %v", err)
+	}
+	if out := result.Content[0].(*mcp.TextContent).Text; !strings.Contains(out, "one\ntwo\nthree") {
+		t.Errorf("result = %q, want the complete output", out)
+	}
+
+	for i, want := range []string{"one", "two", "three"} {
+		select {
+		case p := <-progress:
+			if p.Message != want || p.Progress != float64(i+1) || p.ProgressToken != "tok" {
+				t.Errorf("progress %d = %+v, want message %q", i+1, p, want)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("got %d progress notifications, want 3", i)
+		}
+	}
+}
+
+func TestTruncateText(t *testing.T) {
+	tests := []struct {
+		name      string
+		input     string
+		maxLength int
+		expected  string
+	}{
+		{
+			name:      "shorter than limit",
+			input:     "short prompt",
+			maxLength: 100,
+			expected:  "short prompt",
+		},
+		{
+			name:      "cuts at block boundary",
+			input:     "First block.\n\nSecond block is longer.",
+			maxLength: 20,
+			expected:  "First block.\n\n[truncated: showing 12 of 37 characters]",
+		},
+		{
+			name:      "falls back to sentence boundary",
+			input:     "One sentence. Two sentence. Three.",
+			maxLength: 20,
+			expected:  "One sentence.\n\n[truncated: showing 13 of 34 characters]",
+		},
+		{
+			name:      "hard cut without boundary",
+			input:     "abcdefghijklmnop",
+			maxLength: 5,
+			expected:  "abcde\n\n[truncated: showing 5 of 16 characters]",
+		},
+		{
+			name:      "counts multibyte characters",
+			input:     "日本語のテキスト",
+			maxLength: 3,
+			expected:  "日本語\n\n[truncated: showing 3 of 8 characters]",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := truncateText(tt.input, tt.maxLength)
+			if result != tt.expected {
+				t.Errorf("truncateText() = %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestBuildPromptResult(t *testing.T) {
+	markdown := "# Title\n\nFirst paragraph.\n\nSecond paragraph that is long."
+
+	t.Run("no truncation by default", func(t *testing.T) {
+		result, err := buildPromptResult("Prompt", markdown, nil)
+		if err != nil {
+			t.Fatalf("buildPromptResult() failed: %v", err)
+		}
+		text := result.Messages[0].Content.(*mcp.TextContent).Text
+		if text != markdown {
+			t.Errorf("text = %q, want %q", text, markdown)
+		}
+	})
+
+	t.Run("length-limited", func(t *testing.T) {
+		result, err := buildPromptResult("Prompt", markdown, map[string]string{"maxLength": "30"})
+		if err != nil {
+			t.Fatalf("buildPromptResult() failed: %v", err)
+		}
+		text := result.Messages[0].Content.(*mcp.TextContent).Text
+		expected := "# Title\n\nFirst paragraph.\n\n[truncated: showing 25 of 57 characters]"
+		if text != expected {
+			t.Errorf("text = %q, want %q", text, expected)
+		}
+		if result.Description != "Prompt" {
+			t.Errorf("Description = %q, want %q", result.Description, "Prompt")
+		}
+	})
+
+	t.Run("invalid maxLength", func(t *testing.T) {
+		for _, v := range []string{"abc", "0", "-5"} {
+			if _, err := buildPromptResult("Prompt", markdown, map[string]string{"maxLength": v}); err == nil {
+				t.Errorf("buildPromptResult() with maxLength %q should return error", v)
+			}
+		}
+	})
+}
+
+func TestPromptMessages(t *testing.T) {
+	type message struct{ role, text string }
+	tests := []struct {
+		name     string
+		markdown string
+		want     []message
+	}{
+		{
+			name:     "no role headings",
+			markdown: "# Review\n\nCheck the code.",
+			want:     []message{{"user", "# Review\n\nCheck the code."}},
+		},
+		{
+			name:     "system and user",
+			markdown: "## System\n\nYou are a reviewer.\n\n## User\n\nReview this diff.\n",
+			want:     []message{{"user", "You are a reviewer."}, {"user", "Review this diff."}},
+		},
+		{
+			name:     "assistant example and preamble",
+			markdown: "Intro.\n\n### user\nHi\n### Assistant\nHello!\n## User\nNow you.",
+			want:     []message{{"user", "Intro."}, {"user", "Hi"}, {"assistant", "Hello!"}, {"user", "Now you."}},
+		},
+		{
+			name:     "empty sections dropped",
+			markdown: "## System\n\n## User\nQuestion",
+			want:     []message{{"user", "Question"}},
+		},
+		{
+			name:     "headings in code blocks ignored",
+			markdown: "## User\n```markdown\n## Assistant\n```",
+			want:     []message{{"user", "```markdown\n## Assistant\n```"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []message
+			for _, m := range promptMessages(tt.markdown) {
+				got = append(got, message{string(m.Role), m.Content.(*mcp.TextContent).Text})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("promptMessages() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPromptTemplate(t *testing.T) {
+	props := map[string]notion.Property{
+		"Category": {Type: notion.PropertyTypeSelect, Select: &notion.Select{Name: "writing"}},
+	}
+
+	tests := []struct {
+		name string
+		text string
+		args map[string]string
+		want string
+	}{
+		{
+			name: "supplied argument and property",
+			text: "Write about {{.Args.topic}} ({{.Props.Category}}).",
+			args: map[string]string{"topic": "otters"},
+			want: "Write about otters (writing).",
+		},
+		{
+			name: "missing argument uses default",
+			text: `Write about {{or .Args.topic "anything"}}.`,
+			want: "Write about anything.",
+		},
+		{
+			name: "missing argument without default renders empty",
+			text: "Topic: {{.Args.topic}}",
+			want: "Topic: ",
+		},
+		{
+			name: "no placeholders left untouched",
+			text: "Use {{ mustache }} syntax and {{prop:Unknown}} as-is.",
+			want: "Use {{ mustache }} syntax and {{prop:Unknown}} as-is.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := renderPromptTemplate(tt.text, tt.args, props)
+			if err != nil {
+				t.Fatalf("renderPromptTemplate() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("renderPromptTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("invalid template", func(t *testing.T) {
+		if _, err := renderPromptTemplate("{{.Args.topic", nil, props); err == nil {
+			t.Error("renderPromptTemplate() with unclosed action should return error")
+		}
+	})
+}
+
+func TestPromptArguments(t *testing.T) {
+	argumentsPage := func(value string) notion.Page {
+		return notion.Page{Properties: map[string]notion.Property{
+			"Arguments": {Type: notion.PropertyTypeRichText, RichText: []notion.RichText{{PlainText: value}}},
+		}}
+	}
+	names := func(args []*mcp.PromptArgument) []string {
+		var names []string
+		for _, arg := range args {
+			names = append(names, arg.Name)
+		}
+		return names
+	}
+
+	t.Run("comma-separated list", func(t *testing.T) {
+		args, err := promptArguments(argumentsPage("topic, tone,,"))
+		if err != nil {
+			t.Fatalf("promptArguments() failed: %v", err)
+		}
+		if want := []string{"topic", "tone", promptArgMaxLength}; !reflect.DeepEqual(names(args), want) {
+			t.Errorf("promptArguments() = %v, want %v", names(args), want)
+		}
+	})
+
+	t.Run("JSON array", func(t *testing.T) {
+		args, err := promptArguments(argumentsPage(`[{"name":"topic","description":"What to write about","required":true},{"name":"tone"}]`))
+		if err != nil {
+			t.Fatalf("promptArguments() failed: %v", err)
+		}
+		if want := []string{"topic", "tone", promptArgMaxLength}; !reflect.DeepEqual(names(args), want) {
+			t.Fatalf("promptArguments() = %v, want %v", names(args), want)
+		}
+		if !args[0].Required || args[0].Description != "What to write about" || args[1].Required {
+			t.Errorf("promptArguments() = %+v, %+v, want topic required with description and tone optional", args[0], args[1])
+		}
+	})
+
+	t.Run("no property", func(t *testing.T) {
+		args, err := promptArguments(notion.Page{})
+		if err != nil || len(args) != 1 || args[0].Name != promptArgMaxLength {
+			t.Errorf("promptArguments() = %v, %v, want only %s", names(args), err, promptArgMaxLength)
+		}
+	})
+
+	t.Run("invalid JSON", func(t *testing.T) {
+		if _, err := promptArguments(argumentsPage(`[{"name":`)); err == nil {
+			t.Error("promptArguments() with invalid JSON should return error")
+		}
+	})
+}
+
+func TestPromptRequiredArguments(t *testing.T) {
+	page := typedPage("p1", "prompt", "Essay", time.Time{})
+	page.Properties["Arguments"] = notion.Property{
+		Type:     notion.PropertyTypeRichText,
+		RichText: []notion.RichText{{PlainText: `[{"name":"topic","required":true},{"name":"tone"}]`}},
+	}
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type"},
+		client: &fakeClient{},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.addPrompt(server, page, s.pageName(pageTypePrompt, page))
+	session := connectTestClient(t, server)
+
+	res, err := session.ListPrompts(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListPrompts() failed: %v", err)
+	}
+	if args := res.Prompts[0].Arguments; len(args) != 3 || !args[0].Required || args[1].Required {
+		t.Errorf("declared arguments = %+v, want topic required, tone optional, maxLength", args)
+	}
+
+	tests := []struct {
+		name    string
+		args    map[string]string
+		wantErr bool
+	}{
+		{"required and optional supplied", map[string]string{"topic": "otters", "tone": "dry"}, false},
+		{"optional omitted", map[string]string{"topic": "otters"}, false},
+		{"required missing", map[string]string{"tone": "dry"}, true},
+		{"required empty", map[string]string{"topic": ""}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "essay", Arguments: tt.args})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetPrompt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "topic") {
+				t.Errorf("GetPrompt() error = %v, want it to name the missing argument", err)
+			}
+		})
+	}
+}
+
+func TestGetPromptNotFound(t *testing.T) {
+	paragraph := func(s string) []notion.Block {
+		text := []notion.RichText{{Type: "text", Text: notion.Text{Content: s}, PlainText: s}}
+		return []notion.Block{{Type: notion.BlockTypeParagraph, Paragraph: &notion.Paragraph{RichText: text}}}
+	}
+	s := &Server{
+		cfg: &config.Config{NotionTypeField: "Type"},
+		client: &fakeClient{contents: map[string]*notion.PageContent{
+			"p1": {Blocks: paragraph("first greeting")},
+			"p2": {Blocks: paragraph("second greeting")},
+		}},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	// Both titles sanitize to "greeting"
+	ctx := context.Background()
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.registerPrompts(server, []notion.Page{
+		typedPage("p1", "prompt", "Greeting", time.Time{}),
+		typedPage("p2", "prompt", "greeting!", time.Time{}),
+	})
+	session := connectTestClient(t, server)
+
+	for name, want := range map[string]string{"greeting": "first greeting", "greeting_2": "second greeting"} {
+		res, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: name})
+		if err != nil {
+			t.Fatalf("GetPrompt(%q) failed: %v", name, err)
+		}
+		if got := res.Messages[0].Content.(*mcp.TextContent).Text; !strings.Contains(got, want) {
+			t.Errorf("GetPrompt(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	_, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "farewell"})
+	if err == nil || !strings.Contains(err.Error(), `unknown prompt "farewell"`) {
+		t.Errorf("GetPrompt(farewell) error = %v, want an unknown prompt error", err)
+	}
+}
+
+func TestConcurrencyMiddleware(t *testing.T) {
+	// run issues a slow tool call followed by a fast prompt read and returns
+	// the order in which their handlers completed.
+	run := func(t *testing.T, limit int) []string {
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+		if limit > 0 {
+			server.AddReceivingMiddleware(concurrencyMiddleware(limit))
+		}
+
+		started := make(chan struct{})
+		completed := make(chan string, 2)
+		server.AddTool(&mcp.Tool{
+			Name:        "slow",
+			InputSchema: map[string]any{"type": "object"},
+		}, func(ctx context.Context, req *mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			close(started)
+			time.Sleep(200 * time.Millisecond)
+			completed <- "tool"
+			return &mcp.CallToolResult{}, nil
+		})
+		server.AddPrompt(&mcp.Prompt{Name: "fast"}, func(ctx context.Context, req *mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+			completed <- "prompt"
+			return buildPromptResult("fast", "fast prompt", nil)
+		})
+
+		ctx := context.Background()
+		serverTransport, clientTransport := mcp.NewInMemoryTransports()
+		serverSession, err := server.Connect(ctx, serverTransport, nil)
+		if err != nil {
+			t.Fatalf("server.Connect() failed: %v", err)
+		}
+		defer serverSession.Close()
+
+		client := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "1.0.0"}, nil)
+		session, err := client.Connect(ctx, clientTransport, nil)
+		if err != nil {
+			t.Fatalf("client.Connect() failed: %v", err)
+		}
+		defer session.Close()
+
+		toolDone := make(chan struct{})
+		go func() {
+			defer close(toolDone)
+			if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "slow"}); err != nil {
+				t.Errorf("CallTool() failed: %v", err)
+			}
+		}()
+
+		<-started
+		if _, err := session.GetPrompt(ctx, &mcp.GetPromptParams{Name: "fast"}); err != nil {
+			t.Errorf("GetPrompt() failed: %v", err)
+		}
+		<-toolDone
+
+		return []string{<-completed, <-completed}
+	}
+
+	t.Run("unlimited handles requests concurrently", func(t *testing.T) {
+		order := run(t, 0)
+		if order[0] != "prompt" {
+			t.Errorf("completion order = %v, want prompt first", order)
+		}
+	})
+
+	t.Run("limit of one serializes requests", func(t *testing.T) {
+		order := run(t, 1)
+		if order[0] != "tool" {
+			t.Errorf("completion order = %v, want tool first", order)
+		}
+	})
+}
+
+func TestPageCacheTTL(t *testing.T) {
+	number := func(v float64) *float64 { return &v }
+	defaultTTL := 5 * time.Minute
+
+	tests := []struct {
+		name     string
+		page     notion.Page
+		expected time.Duration
+	}{
+		{
+			name:     "no property uses default",
+			page:     notion.Page{},
+			expected: defaultTTL,
+		},
+		{
+			name: "empty number uses default",
+			page: notion.Page{Properties: map[string]notion.Property{
+				"CacheTTL": {Type: notion.PropertyTypeNumber},
+			}},
+			expected: defaultTTL,
+		},
+		{
+			name: "override in seconds",
+			page: notion.Page{Properties: map[string]notion.Property{
+				"CacheTTL": {Type: notion.PropertyTypeNumber, Number: number(3600)},
+			}},
+			expected: time.Hour,
+		},
+		{
+			name: "clamped to minimum",
+			page: notion.Page{Properties: map[string]notion.Property{
+				"CacheTTL": {Type: notion.PropertyTypeNumber, Number: number(1)},
+			}},
+			expected: minPageCacheTTL,
+		},
+		{
+			name: "negative clamped to minimum",
+			page: notion.Page{Properties: map[string]notion.Property{
+				"CacheTTL": {Type: notion.PropertyTypeNumber, Number: number(-60)},
+			}},
+			expected: minPageCacheTTL,
+		},
+		{
+			name: "clamped to maximum",
+			page: notion.Page{Properties: map[string]notion.Property{
+				"CacheTTL": {Type: notion.PropertyTypeNumber, Number: number(30 * 24 * 3600)},
+			}},
+			expected: maxPageCacheTTL,
+		},
+		{
+			name: "non-number property ignored",
+			page: notion.Page{Properties: map[string]notion.Property{
+				"CacheTTL": {Type: notion.PropertyTypeRichText},
+			}},
+			expected: defaultTTL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := pageCacheTTL(tt.page, defaultTTL)
+			if result != tt.expected {
+				t.Errorf("pageCacheTTL() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+// fakeClient serves a mutable set of pages.
+type fakeClient struct {
+	mu        sync.Mutex
+	pages     []notion.Page
+	contents  map[string]*notion.PageContent
+	queries   int           // calls to GetAllPages
+	gate      chan struct{} // if set, GetAllPages waits for it to close
+	appended  []notion.Block
+	appendErr error
+	failures  []error // returned by the first calls to GetAllPages
+}
+
+func (f *fakeClient) GetAllPages(ctx context.Context) ([]notion.Page, error) {
+	if f.gate != nil {
+		<-f.gate
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries++
+	if len(f.failures) > 0 {
+		err := f.failures[0]
+		f.failures = f.failures[1:]
+		return nil, err
+	}
+	return append([]notion.Page(nil), f.pages...), nil
+}
+
+func (f *fakeClient) QueryDatabaseSince(ctx context.Context, since time.Time) ([]notion.Page, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var pages []notion.Page
+	for _, page := range f.pages {
+		if page.LastEditedTime.After(since) {
+			pages = append(pages, page)
+		}
+	}
+	return pages, nil
+}
+
+func (f *fakeClient) GetPageContent(ctx context.Context, pageID string) (*notion.PageContent, error) {
+	if content, ok := f.contents[pageID]; ok {
+		return content, nil
+	}
+	return &notion.PageContent{Page: notion.Page{ID: pageID}}, nil
+}
+
+func (f *fakeClient) GetPage(ctx context.Context, pageID string) (*notion.Page, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, page := range f.pages {
+		if page.ID == pageID {
+			return &page, nil
+		}
+	}
+	return nil, notion.ErrNotFound
+}
+
+func (f *fakeClient) AppendBlockChildren(ctx context.Context, blockID string, blocks []notion.Block) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.appended = append(f.appended, blocks...)
+	return f.appendErr
+}
+
+func (f *fakeClient) setPages(pages ...notion.Page) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pages = pages
+}
+
+// typedPage builds a page of the given type with a title and edit time.
+func typedPage(id, pageType, title string, edited time.Time) notion.Page {
+	return notion.Page{
+		ID:             id,
+		LastEditedTime: edited,
+		Properties: map[string]notion.Property{
+			"Type": {Type: notion.PropertyTypeSelect, Select: &notion.Select{Name: pageType}},
+			"Name": {Type: notion.PropertyTypeTitle, Title: []notion.Title{{PlainText: title}}},
+		},
+	}
+}
+
+// promptNames lists the prompts the session sees.
+func promptNames(t *testing.T, session *mcp.ClientSession) []string {
+	t.Helper()
+	res, err := session.ListPrompts(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListPrompts() failed: %v", err)
+	}
+	var names []string
+	for _, p := range res.Prompts {
+		names = append(names, p.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestWatch(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	first := typedPage("p1", "prompt", "First", t0)
+	doc := typedPage("r1", "resource", "Doc", t0)
+
+	client := &fakeClient{}
+	client.setPages(first, doc)
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type", Watch: true, PollInterval: 10 * time.Millisecond},
+		client: client,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, s.serverOptions())
+	pages, _ := client.GetAllPages(ctx)
+	s.registerPrompts(server, pages)
+	s.registerResources(server, pages)
+	s.setRegistered(pages)
+
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server.Connect() failed: %v", err)
+	}
+	defer serverSession.Close()
+
+	changed := make(chan struct{}, 10)
+	updated := make(chan string, 10)
+	mcpClient := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "1.0.0"}, &mcp.ClientOptions{
+		PromptListChangedHandler: func(context.Context, *mcp.PromptListChangedRequest) {
+			changed <- struct{}{}
+		},
+		ResourceUpdatedHandler: func(_ context.Context, req *mcp.ResourceUpdatedNotificationRequest) {
+			updated <- req.Params.URI
+		},
+	})
+	session, err := mcpClient.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client.Connect() failed: %v", err)
+	}
+	defer session.Close()
+
+	if got := promptNames(t, session); !reflect.DeepEqual(got, []string{"first"}) {
+		t.Fatalf("initial prompts = %v, want [first]", got)
+	}
+
+	t.Run("New page is registered between polls", func(t *testing.T) {
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		defer stopWatch()
+		s.startWatch(watchCtx, server, pages)
+		client.setPages(first, doc, typedPage("p2", "prompt", "Second", t0))
+
+		// A notification may still be pending from the initial registration
+		deadline := time.After(5 * time.Second)
+		for {
+			select {
+			case <-changed:
+			case <-deadline:
+				t.Fatal("no prompt list-changed notification for the new page")
+			}
+			if got := promptNames(t, session); reflect.DeepEqual(got, []string{"first", "second"}) {
+				return
+			}
+		}
+	})
+
+	t.Run("Edited resource notifies its subscribers", func(t *testing.T) {
+		other := typedPage("r2", "resource", "Other", t0)
+		known := s.syncRegistrations(ctx, server, s.watchedPages([]notion.Page{first, doc}), []notion.Page{first, doc, other})
+		defer server.RemoveResources(resourceURI(other))
+		if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: resourceURI(doc)}); err != nil {
+			t.Fatalf("Subscribe() failed: %v", err)
+		}
+		if err := session.Subscribe(ctx, &mcp.SubscribeParams{URI: resourceURI(other)}); err != nil {
+			t.Fatalf("Subscribe() failed: %v", err)
+		}
+
+		edited := typedPage("r1", "resource", "Doc", t0.Add(time.Minute))
+		s.syncRegistrations(ctx, server, known, []notion.Page{first, edited, other})
+		select {
+		case uri := <-updated:
+			if uri != resourceURI(doc) {
+				t.Errorf("updated URI = %q, want %q", uri, resourceURI(doc))
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no resource updated notification for the edited page")
+		}
+		select {
+		case uri := <-updated:
+			t.Errorf("unexpected updated notification for %q", uri)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("Renamed and deleted pages are unregistered", func(t *testing.T) {
+		known := s.watchedPages([]notion.Page{first, doc})
+		renamed := typedPage("p1", "prompt", "Renamed", t0.Add(time.Minute))
+		known = s.syncRegistrations(ctx, server, known, []notion.Page{renamed})
+
+		if got := promptNames(t, session); !reflect.DeepEqual(got, []string{"renamed", "second"}) {
+			t.Errorf("prompts = %v, want [renamed second]", got)
+		}
+		res, err := session.ListResources(ctx, nil)
+		if err != nil {
+			t.Fatalf("ListResources() failed: %v", err)
+		}
+		if len(res.Resources) != 0 {
+			t.Errorf("resources = %d, want 0 after the page was deleted", len(res.Resources))
+		}
+		if len(known) != 1 || known[0].ID != "p1" {
+			t.Errorf("syncRegistrations() index = %v, want only p1", known)
+		}
+	})
+}
+
+func TestWatchTools(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	bash := func(code string) *notion.PageContent {
+		return &notion.PageContent{HasCode: true, Code: notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: code}}}}
+	}
+	client := &fakeClient{contents: map[string]*notion.PageContent{"t1": bash("echo before")}}
+	s := &Server{
+		cfg:      &config.Config{NotionTypeField: "Type", Watch: true},
+		client:   client,
+		executor: tools.NewExecutor(5*time.Second, "bash"),
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, s.serverOptions())
+	tool := typedPage("t1", "tool", "Greet", t0)
+	known := s.syncRegistrations(context.Background(), server, nil, []notion.Page{tool})
+	session := connectTestClient(t, server)
+
+	call := func(t *testing.T) string {
+		t.Helper()
+		result, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "greet"})
+		if err != nil {
+			t.Fatalf("CallTool() failed: %v", err)
+		}
+		return result.Content[0].(*mcp.TextContent).Text
+	}
+	if out := call(t); !strings.Contains(out, "before") {
+		t.Fatalf("initial result = %q, want the original code's output", out)
+	}
+
+	t.Run("Edited tool runs its new code", func(t *testing.T) {
+		client.contents["t1"] = bash("echo after")
+		edited := typedPage("t1", "tool", "Greet", t0.Add(time.Minute))
+		known = s.syncRegistrations(context.Background(), server, known, []notion.Page{edited})
+		if out := call(t); !strings.Contains(out, "after") {
+			t.Errorf("result = %q, want the edited code's output", out)
+		}
+	})
+
+	t.Run("Deleted tool is unregistered", func(t *testing.T) {
+		s.syncRegistrations(context.Background(), server, known, nil)
+		res, err := session.ListTools(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("ListTools() failed: %v", err)
+		}
+		if len(res.Tools) != 0 {
+			t.Errorf("tools = %+v, want none after the page was deleted", res.Tools)
+		}
+	})
+}
+
+func TestWarmCache(t *testing.T) {
+	store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewCache() failed: %v", err)
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := &fakeClient{contents: map[string]*notion.PageContent{
+		"t1": {HasCode: true, Code: notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "wc -w"}}}},
+	}}
+	client.setPages(
+		typedPage("p1", "prompt", "Greeting", time.Time{}),
+		typedPage("r1", "resource", "Style Guide", time.Time{}),
+		typedPage("t1", "tool", "Word Count", time.Time{}),
+	)
+	s := &Server{
+		cfg:      &config.Config{NotionTypeField: "Type", RefreshOnStart: true},
+		client:   client,
+		cache:    store,
+		mcpCache: cache.NewMCPCache(store, logger),
+		logger:   logger,
+	}
+
+	ctx := context.Background()
+	s.warmCache(ctx)
+	if client.queries != 1 {
+		t.Errorf("warmCache() queried Notion %d times, want 1", client.queries)
+	}
+
+	// Pages changed in Notion are not seen until the cache refreshes
+	client.setPages(typedPage("p2", "prompt", "Farewell", time.Time{}))
+	pages, err := s.getAllPagesWithCache(ctx)
+	if err != nil {
+		t.Fatalf("getAllPagesWithCache() failed: %v", err)
+	}
+	if client.queries != 1 {
+		t.Errorf("getAllPagesWithCache() queried Notion with a warm cache")
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.registerPages(ctx, server, pages, false)
+	session := connectTestClient(t, server)
+
+	if got := promptNames(t, session); !reflect.DeepEqual(got, []string{"greeting"}) {
+		t.Errorf("prompts = %v, want [greeting]", got)
+	}
+	res, err := session.ListResources(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(res.Resources) != 1 || res.Resources[0].Name != "style_guide" {
+		t.Errorf("resources = %+v, want [style_guide]", res.Resources)
+	}
+	tools, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTools() failed: %v", err)
+	}
+	if len(tools.Tools) != 1 || tools.Tools[0].Name != "word_count" {
+		t.Errorf("tools = %+v, want [word_count]", tools.Tools)
+	}
+}
+
+func TestPeriodicRefresh(t *testing.T) {
+	store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewCache() failed: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	client := &fakeClient{}
+	client.setPages(typedPage("p1", "prompt", "Greeting", time.Time{}))
+	mcpCache := cache.NewMCPCache(store, logger)
+	t.Cleanup(mcpCache.StopAll)
+	s := &Server{
+		cfg:      &config.Config{NotionTypeField: "Type", PollInterval: 10 * time.Millisecond},
+		client:   client,
+		cache:    store,
+		mcpCache: mcpCache,
+		logger:   logger,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pages, err := s.getAllPagesWithCache(ctx)
+	if err != nil {
+		t.Fatalf("getAllPagesWithCache() failed: %v", err)
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.registerPages(ctx, server, pages, false)
+	session := connectTestClient(t, server)
+
+	// The refresh re-registers the lists it caches, without watch mode
+	client.setPages(typedPage("p2", "prompt", "Farewell", time.Time{}))
+	cachedIDs := func() []string {
+		var cached []notion.Page
+		data, _ := mcpCache.Get(ctx, cache.CacheKeyPrompts)
+		_ = json.Unmarshal(data, &cached)
+		var ids []string
+		for _, page := range cached {
+			ids = append(ids, page.ID)
+		}
+		return ids
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got, ids := promptNames(t, session), cachedIDs()
+		if reflect.DeepEqual(got, []string{"farewell"}) && reflect.DeepEqual(ids, []string{"p2"}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("prompts = %v, cached = %v, want [farewell] and [p2]", got, ids)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStartupFetch(t *testing.T) {
+	newServer := func(t *testing.T, client *fakeClient, startupFetch string) *Server {
+		t.Helper()
+		store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+		if err != nil {
+			t.Fatalf("NewCache() error = %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		return &Server{
+			cfg:      &config.Config{NotionTypeField: "Type", StartupFetch: startupFetch, TransportType: "stdio"},
+			client:   client,
+			cache:    store,
+			mcpCache: cache.NewMCPCache(store, logger),
+			logger:   logger,
+		}
+	}
+
+	t.Run("Fail fast", func(t *testing.T) {
+		client := &fakeClient{failures: []error{notion.ErrUnauthorized}}
+		s := newServer(t, client, "fail")
+		err := s.Start(context.Background())
+		if !errors.Is(err, notion.ErrUnauthorized) {
+			t.Fatalf("Start() error = %v, want %v", err, notion.ErrUnauthorized)
+		}
+		if !strings.Contains(err.Error(), "NOTION_API_KEY") {
+			t.Errorf("Start() error = %q, want a hint", err)
+		}
+	})
+
+	t.Run("Retry", func(t *testing.T) {
+		client := &fakeClient{failures: []error{errors.New("connection refused")}}
+		client.setPages(typedPage("p1", "prompt", "Greeting", time.Time{}))
+		s := newServer(t, client, "retry")
+		ctx := context.Background()
+
+		pages, err := s.getAllPagesWithCache(ctx)
+		if err == nil {
+			t.Fatal("getAllPagesWithCache() succeeded, want the first query to fail")
+		}
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+		session := connectTestClient(t, server)
+		s.registerPages(ctx, server, pages, false)
+		if got := promptNames(t, session); len(got) != 0 {
+			t.Errorf("prompts before retry = %v, want none", got)
+		}
+
+		s.retryInitialQuery(ctx, server, time.Millisecond)
+		if client.queries != 2 {
+			t.Errorf("queries = %d, want 2", client.queries)
+		}
+		if got := promptNames(t, session); !reflect.DeepEqual(got, []string{"greeting"}) {
+			t.Errorf("prompts after retry = %v, want [greeting]", got)
+		}
+	})
+}
+
+func TestAllPagesShared(t *testing.T) {
+	client := &fakeClient{gate: make(chan struct{})}
+	client.setPages(typedPage("p1", "prompt", "Greeting", time.Time{}))
+	s := &Server{cfg: &config.Config{NotionTypeField: "Type"}, client: client}
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pages, err := s.allPages(ctx)
+			if err != nil || len(pages) != 1 {
+				t.Errorf("allPages() = %d pages, %v, want 1 page", len(pages), err)
+			}
+		}()
+	}
+	close(client.gate)
+	wg.Wait()
+	if client.queries != 1 {
+		t.Errorf("50 concurrent allPages() calls queried Notion %d times, want 1", client.queries)
+	}
+
+	// Once the window has passed, Notion is queried again
+	s.pageQuery.fetched = time.Now().Add(-pageQueryWindow)
+	if _, err := s.allPages(ctx); err != nil {
+		t.Fatalf("allPages() failed: %v", err)
+	}
+	if client.queries != 2 {
+		t.Errorf("allPages() after the window queried Notion %d times in total, want 2", client.queries)
+	}
+}
+
+func TestMergePages(t *testing.T) {
+	t0 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	pages := []notion.Page{
+		typedPage("p1", "prompt", "First", t0),
+		typedPage("r1", "resource", "Doc", t0),
+	}
+	changed := []notion.Page{
+		typedPage("p2", "prompt", "Second", t0.Add(2*time.Minute)),
+		typedPage("r1", "resource", "Doc v2", t0.Add(time.Minute)),
+	}
+
+	merged := mergePages(pages, changed)
+	var got []string
+	for _, page := range merged {
+		got = append(got, page.ID+":"+getPageTitle(page))
+	}
+	if want := []string{"p1:First", "r1:Doc v2", "p2:Second"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mergePages() = %v, want %v", got, want)
+	}
+	if getPageTitle(pages[1]) != "Doc" {
+		t.Error("mergePages() modified its input")
+	}
+	if got, want := lastEdited(merged), t0.Add(2*time.Minute); !got.Equal(want) {
+		t.Errorf("lastEdited() = %v, want %v", got, want)
+	}
+}
+
+// connectTestClient connects a client to server over in-memory transports.
+// Both sessions are closed when the test ends.
+func connectTestClient(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	serverSession, err := server.Connect(ctx, serverTransport, nil)
+	if err != nil {
+		t.Fatalf("server.Connect() failed: %v", err)
+	}
+	t.Cleanup(func() { serverSession.Close() })
+	session, err := mcp.NewClient(&mcp.Implementation{Name: "client", Version: "1.0.0"}, nil).Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client.Connect() failed: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestPageKind(t *testing.T) {
+	cfg := &config.Config{NotionTypeField: "Type", TypePrompt: "提示", TypeResource: "Snippet", TypeTool: "script"}
+
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"提示", pageTypePrompt},
+		{"snippet", pageTypeResource},
+		{"SNIPPET", pageTypeResource},
+		{"Script", pageTypeTool},
+		{"prompt", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := pageKind(cfg, typedPage("id", tt.value, "Title", time.Time{})); got != tt.want {
+				t.Errorf("pageKind(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+
+	t.Run("defaults when unset", func(t *testing.T) {
+		cfg := &config.Config{NotionTypeField: "Type"}
+		if got := pageKind(cfg, typedPage("id", "Resource", "Title", time.Time{})); got != pageTypeResource {
+			t.Errorf("pageKind() = %q, want %q", got, pageTypeResource)
+		}
+	})
+}
+
+func TestRegisterCustomTypes(t *testing.T) {
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type", TypePrompt: "提示", TypeResource: "snippet", TypeTool: "script"},
+		client: &fakeClient{},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	pages := []notion.Page{
+		typedPage("p1", "提示", "Greeting", time.Time{}),
+		typedPage("r1", "Snippet", "Boilerplate", time.Time{}),
+		typedPage("t1", "script", "Word Count", time.Time{}),
+		typedPage("x1", "prompt", "Default Type", time.Time{}),
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.registerPrompts(server, pages)
+	s.registerResources(server, pages)
+	session := connectTestClient(t, server)
+
+	if got := promptNames(t, session); !reflect.DeepEqual(got, []string{"greeting"}) {
+		t.Errorf("prompts = %v, want [greeting]", got)
+	}
+	res, err := session.ListResources(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(res.Resources) != 1 || res.Resources[0].Name != "boilerplate" {
+		t.Errorf("resources = %+v, want [boilerplate]", res.Resources)
+	}
+
+	var tools []string
+	for _, e := range ListEntries(pages, s.cfg) {
+		if e.Type == pageTypeTool {
+			tools = append(tools, e.Name)
+		}
+	}
+	if !reflect.DeepEqual(tools, []string{"word_count"}) {
+		t.Errorf("tool entries = %v, want [word_count]", tools)
+	}
+}
+
+func TestResourceURI(t *testing.T) {
+	store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewCache() failed: %v", err)
+	}
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type"},
+		client: &fakeClient{},
+		cache:  store,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	pageID := "1a2b3c4d-0000-0000-0000-000000000000"
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.registerResources(server, []notion.Page{typedPage(pageID, "resource", "Style Guide", time.Time{})})
+	session := connectTestClient(t, server)
+
+	res, err := session.ListResources(context.Background(), nil)
+	if err != nil || len(res.Resources) != 1 {
+		t.Fatalf("ListResources() = %v, %v, want one resource", res, err)
+	}
+	uri := res.Resources[0].URI
+	if id, ok := pageIDFromURI(uri); !ok || id != pageID {
+		t.Errorf("pageIDFromURI(%q) = %q, %v, want %q", uri, id, ok, pageID)
+	}
+
+	read, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: uri})
+	if err != nil {
+		t.Fatalf("ReadResource() failed: %v", err)
+	}
+	if len(read.Contents) != 1 || read.Contents[0].URI != uri {
+		t.Errorf("contents = %+v, want one with URI %s", read.Contents, uri)
+	}
+
+	tests := []struct {
+		uri    string
+		wantID string
+		wantOK bool
+	}{
+		{"notion://resource/abc", "abc", true},
+		{"notion://resource/abc/", "abc", true},
+		{"notion://resource/abc?version=2", "abc", true},
+		{"notion://resource/abc/#top", "abc", true},
+		{"notion://resource/", "", false},
+		{"notion://resource/abc/def", "", false},
+		{"file:///notion/abc", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			id, ok := pageIDFromURI(tt.uri)
+			if id != tt.wantID || ok != tt.wantOK {
+				t.Errorf("pageIDFromURI(%q) = %q, %v, want %q, %v", tt.uri, id, ok, tt.wantID, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestResourceMIMEType(t *testing.T) {
+	store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewCache() failed: %v", err)
+	}
+	text := func(s string) []notion.RichText {
+		return []notion.RichText{{Type: "text", Text: notion.Text{Content: s}, PlainText: s}}
+	}
+	codeBlock := func(language, code string) notion.Block {
+		return notion.Block{Type: notion.BlockTypeCode, Content: map[string]any{
+			"language":  language,
+			"rich_text": []any{map[string]any{"plain_text": code}},
+		}}
+	}
+	contents := map[string]*notion.PageContent{
+		"doc": {Blocks: []notion.Block{
+			{Type: notion.BlockTypeParagraph, Paragraph: &notion.Paragraph{RichText: text("Use the active voice.")}},
+		}},
+		"schema": {Blocks: []notion.Block{codeBlock("json", `{"type": "object"}`)}},
+		"notes":  {Blocks: []notion.Block{codeBlock("plain text", "just notes")}},
+	}
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type"},
+		client: &fakeClient{contents: contents},
+		cache:  store,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.registerResources(server, []notion.Page{
+		typedPage("doc", "resource", "Doc", time.Time{}),
+		typedPage("schema", "resource", "Schema", time.Time{}),
+		typedPage("notes", "resource", "Notes", time.Time{}),
+	})
+	session := connectTestClient(t, server)
+
+	tests := []struct {
+		pageID   string
+		wantMIME string
+		wantText string
+	}{
+		{"doc", "text/markdown", "Use the active voice."},
+		{"schema", "application/json", `{"type": "object"}`},
+		{"notes", "text/plain", "just notes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pageID, func(t *testing.T) {
+			read, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: "notion://resource/" + tt.pageID})
+			if err != nil {
+				t.Fatalf("ReadResource() failed: %v", err)
+			}
+			got := read.Contents[0]
+			if got.MIMEType != tt.wantMIME || strings.TrimSpace(got.Text) != tt.wantText {
+				t.Errorf("contents = (%q, %q), want (%q, %q)", got.MIMEType, got.Text, tt.wantMIME, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestResourceAttachment(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n1 0 obj << >> endobj\n%%EOF\n")
+	revised := []byte("%PDF-1.4\n2 0 obj << >> endobj\n%%EOF\n")
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/pdf")
+		if r.URL.Path == "/revised.pdf" {
+			w.Write(revised)
+			return
+		}
+		w.Write(pdf)
+	}))
+	defer files.Close()
+
+	pdfBlock := notion.Block{Type: notion.BlockTypePDF, Content: map[string]any{
+		"type": "file",
+		"file": map[string]any{"url": files.URL + "/spec.pdf?sig=abc"},
+	}}
+	empty := notion.Block{Type: notion.BlockTypeParagraph, Paragraph: &notion.Paragraph{}}
+
+	read := func(t *testing.T, maxBlob int) *mcp.ResourceContents {
+		t.Helper()
+		store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+		if err != nil {
+			t.Fatalf("NewCache() failed: %v", err)
+		}
+		s := &Server{
+			cfg: &config.Config{NotionTypeField: "Type", ResourceMaxBlobBytes: maxBlob},
+			client: &fakeClient{contents: map[string]*notion.PageContent{
+				"spec": {Blocks: []notion.Block{pdfBlock, empty}},
+			}},
+			cache:  store,
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+		s.registerResources(server, []notion.Page{typedPage("spec", "resource", "Spec", time.Time{})})
+		session := connectTestClient(t, server)
+
+		res, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: "notion://resource/spec"})
+		if err != nil {
+			t.Fatalf("ReadResource() failed: %v", err)
+		}
+		return res.Contents[0]
+	}
+
+	t.Run("Blob", func(t *testing.T) {
+		got := read(t, 1024)
+		if got.MIMEType != "application/pdf" || string(got.Blob) != string(pdf) || got.Text != "" {
+			t.Errorf("contents = (%q, %q, text %q), want the PDF as an application/pdf blob", got.MIMEType, got.Blob, got.Text)
+		}
+	})
+
+	t.Run("Oversized file is a link", func(t *testing.T) {
+		got := read(t, 10)
+		if got.MIMEType != "text/markdown" || got.Blob != nil || !strings.Contains(got.Text, "("+files.URL+"/spec.pdf?sig=abc)") {
+			t.Errorf("contents = (%q, %d blob bytes, text %q), want a markdown link", got.MIMEType, len(got.Blob), got.Text)
+		}
+	})
+
+	t.Run("Edited attachment is read again", func(t *testing.T) {
+		store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+		if err != nil {
+			t.Fatalf("NewCache() failed: %v", err)
+		}
+		page := typedPage("spec", "resource", "Spec", time.Time{})
+		client := &fakeClient{contents: map[string]*notion.PageContent{
+			"spec": {Page: page, Blocks: []notion.Block{pdfBlock}},
+		}}
+		s := &Server{
+			cfg:    &config.Config{NotionTypeField: "Type", ResourceMaxBlobBytes: 1024, CacheTTL: time.Hour},
+			client: client,
+			cache:  store,
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+		s.registerResources(server, []notion.Page{page})
+		session := connectTestClient(t, server)
+		readBlob := func() string {
+			t.Helper()
+			res, err := session.ReadResource(context.Background(), &mcp.ReadResourceParams{URI: "notion://resource/spec"})
+			if err != nil {
+				t.Fatalf("ReadResource() failed: %v", err)
+			}
+			return string(res.Contents[0].Blob)
+		}
+
+		if got := readBlob(); got != string(pdf) {
+			t.Fatalf("first read = %q, want the original PDF", got)
+		}
+		edited := typedPage("spec", "resource", "Spec", time.Time{}.Add(time.Minute))
+		client.contents["spec"] = &notion.PageContent{Page: edited, Blocks: []notion.Block{{Type: notion.BlockTypePDF, Content: map[string]any{
+			"type": "file",
+			"file": map[string]any{"url": files.URL + "/revised.pdf"},
+		}}}}
+		s.syncRegistrations(context.Background(), server, []notion.Page{page}, []notion.Page{edited})
+		if got := readBlob(); got != string(revised) {
+			t.Errorf("read after the edit = %q, want the revised PDF", got)
+		}
+	})
+}
+
+func TestPromptImages(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n fake image")
+	var requested sync.Map
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested.Store(r.URL.Path, true)
+		if r.URL.Path == "/missing.png" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(png)
+	}))
+	defer files.Close()
+
+	image := func(src string) notion.Block {
+		return notion.Block{Type: notion.BlockTypeImage, Content: map[string]any{
+			"type":     "external",
+			"external": map[string]any{"url": src},
+		}}
+	}
+	text := func(s string) notion.Block {
+		rt := []notion.RichText{{Type: "text", Text: notion.Text{Content: s}, PlainText: s}}
+		return notion.Block{Type: notion.BlockTypeParagraph, Paragraph: &notion.Paragraph{RichText: rt}}
+	}
+	blocks := []notion.Block{
+		text("Describe this chart."),
+		image(files.URL + "/chart.png"),
+		text("Then this one."),
+		image(files.URL + "/missing.png"),
+	}
+
+	getWith := func(t *testing.T, maxImage int, blocks []notion.Block, args map[string]string) []*mcp.PromptMessage {
+		t.Helper()
+		s := &Server{
+			cfg:    &config.Config{NotionTypeField: "Type", PromptImageMaxBytes: maxImage},
+			client: &fakeClient{contents: map[string]*notion.PageContent{"p1": {Blocks: blocks}}},
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		handler := s.createPromptHandler(typedPage("p1", "prompt", "Charts", time.Time{}), nil)
+		result, err := handler(context.Background(), &mcp.GetPromptRequest{Params: &mcp.GetPromptParams{Name: "charts", Arguments: args}})
+		if err != nil {
+			t.Fatalf("handler failed: %v", err)
+		}
+		return result.Messages
+	}
+	get := func(t *testing.T, maxImage int) []*mcp.PromptMessage {
+		t.Helper()
+		return getWith(t, maxImage, blocks, nil)
+	}
+
+	t.Run("Images interleaved with text", func(t *testing.T) {
+		messages := get(t, 1024)
+		if len(messages) != 3 {
+			t.Fatalf("got %d messages, want text, image, text", len(messages))
+		}
+		if got := messages[0].Content.(*mcp.TextContent).Text; got != "Describe this chart." {
+			t.Errorf("first message = %q, want the first paragraph", got)
+		}
+		img, ok := messages[1].Content.(*mcp.ImageContent)
+		if !ok || img.MIMEType != "image/png" || string(img.Data) != string(png) {
+			t.Errorf("second message = %+v, want the PNG", messages[1].Content)
+		}
+		// The image that fails to download stays a link
+		if got := messages[2].Content.(*mcp.TextContent).Text; !strings.Contains(got, "Then this one.") || !strings.Contains(got, "("+files.URL+"/missing.png)") {
+			t.Errorf("last message = %q, want the paragraph and a link to the missing image", got)
+		}
+	})
+
+	t.Run("Oversized images stay links", func(t *testing.T) {
+		messages := get(t, 4)
+		if len(messages) != 1 {
+			t.Fatalf("got %d messages, want 1", len(messages))
+		}
+		if got := messages[0].Content.(*mcp.TextContent).Text; !strings.Contains(got, "("+files.URL+"/chart.png)") {
+			t.Errorf("message = %q, want a link to the chart", got)
+		}
+	})
+
+	t.Run("Images in arguments are not fetched", func(t *testing.T) {
+		arg := "![](" + files.URL + "/secret.png)"
+		messages := getWith(t, 1024, []notion.Block{text("Look at {{.Args.image}}"), text("{{.Args.image}}")}, map[string]string{"image": arg})
+		if _, ok := requested.Load("/secret.png"); ok {
+			t.Error("the server fetched an image URL from a prompt argument")
+		}
+		if len(messages) != 1 || !strings.Contains(messages[0].Content.(*mcp.TextContent).Text, arg) {
+			t.Errorf("messages = %+v, want the argument kept as text", messages)
+		}
+	})
+}
+
+func TestPageJSON(t *testing.T) {
+	doc := typedPage("doc", "resource", "Style Guide", time.Time{})
+	doc.Properties["Category"] = notion.Property{Type: notion.PropertyTypeSelect, Select: &notion.Select{Name: "Writing"}}
+	bold := []notion.RichText{{Type: "text", Text: notion.Text{Content: "Be brief."}, PlainText: "Be brief.", Annotations: notion.Annotations{Bold: true}}}
+	prompt := typedPage("p1", "prompt", "Greeting", time.Time{})
+	client := &fakeClient{contents: map[string]*notion.PageContent{
+		"doc": {Page: doc, Blocks: []notion.Block{
+			{Type: notion.BlockTypeParagraph, Paragraph: &notion.Paragraph{RichText: bold}, Content: notion.Paragraph{RichText: bold}},
+			{Type: notion.BlockTypeImage, Content: map[string]any{
+				"type": "file",
+				"file": map[string]any{"url": "https://files.example/logo.png?X-Amz-Signature=abc", "expiry_time": "2025-01-01T01:00:00Z"},
+			}},
+		}},
+		"p1":    {Page: prompt},
+		"stray": {Page: typedPage("stray", "resource", "Elsewhere", time.Time{})},
+	}}
+	client.setPages(doc, prompt)
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type"},
+		client: client,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.registerResources(server, []notion.Page{doc})
+	session := connectTestClient(t, server)
+	ctx := context.Background()
+
+	res, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "notion://resource/doc.json"})
+	if err != nil {
+		t.Fatalf("ReadResource() failed: %v", err)
+	}
+	got := res.Contents[0]
+	if got.MIMEType != "application/json" || !json.Valid([]byte(got.Text)) {
+		t.Fatalf("contents = (%q, %q), want JSON", got.MIMEType, got.Text)
+	}
+	for _, want := range []string{`"type": "paragraph"`, `"type": "image"`, `"bold": true`, `"name": "Writing"`, `"url_omitted": true`} {
+		if !strings.Contains(got.Text, want) {
+			t.Errorf("page JSON does not contain %s:\n%s", want, got.Text)
+		}
+	}
+	if strings.Contains(got.Text, "X-Amz-Signature") {
+		t.Errorf("page JSON contains a signed URL:\n%s", got.Text)
+	}
+
+	// Only resource pages of the database are served
+	if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "notion://resource/p1.json"}); err == nil {
+		t.Error("ReadResource() of a prompt page's JSON should fail")
+	}
+	if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "notion://resource/stray.json"}); err == nil {
+		t.Error("ReadResource() of a page outside the database should fail")
+	}
+
+	t.Run("Full page ID", func(t *testing.T) {
+		long := typedPage("abcdef12-3456-7890-abcd-ef1234567890", "resource", "Long", time.Time{})
+		client.contents[long.ID] = &notion.PageContent{Page: long}
+		s.registerResources(server, []notion.Page{long})
+		if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "notion://resource/ABCDEF1234567890ABCDEF1234567890.json"}); err != nil {
+			t.Errorf("ReadResource() by the ID without dashes failed: %v", err)
+		}
+		for _, id := range []string{"abcdef12", "abcdef12-3456-7890-abcd-000000000000"} {
+			if _, err := session.ReadResource(ctx, &mcp.ReadResourceParams{URI: "notion://resource/" + id + ".json"}); err == nil {
+				t.Errorf("ReadResource() of %s.json should fail", id)
+			}
+		}
+	})
+}
+
+func TestFrontMatterRelations(t *testing.T) {
+	store, err := cache.NewCache(cache.WithDir(t.TempDir()))
+	if err != nil {
+		t.Fatalf("NewCache() failed: %v", err)
+	}
+	client := &fakeClient{}
+	client.setPages(typedPage("proj", "resource", "Launch", time.Time{}))
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type", MarkdownFrontMatter: true},
+		client: client,
+		cache:  store,
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	relatedTo := func(id string) *notion.PageContent {
+		doc := typedPage("doc", "resource", "Plan", time.Time{})
+		doc.Properties["Projects"] = notion.Property{Type: notion.PropertyTypeRelation, Relation: []notion.Relation{{ID: id}}}
+		return &notion.PageContent{Page: doc}
+	}
+
+	if got := s.renderContent(context.Background(), "doc", relatedTo("proj")); !strings.Contains(got, "Projects:\n    - Launch\n") {
+		t.Errorf("front matter = %q, want the related page's title", got)
+	}
+	// A related page that can't be fetched leaves its ID
+	if got := s.renderContent(context.Background(), "doc", relatedTo("gone")); !strings.Contains(got, "Projects:\n    - gone\n") {
+		t.Errorf("front matter = %q, want the related page's ID", got)
+	}
+}
+
+func TestAttachmentURL(t *testing.T) {
+	file := func(blockType notion.BlockType, url string) notion.Block {
+		return notion.Block{Type: blockType, Content: map[string]any{"external": map[string]any{"url": url}}}
+	}
+	para := func(text string) notion.Block {
+		return notion.Block{Type: notion.BlockTypeParagraph, Paragraph: &notion.Paragraph{RichText: []notion.RichText{{PlainText: text}}}}
+	}
+	tests := []struct {
+		name   string
+		blocks []notion.Block
+		want   string
+	}{
+		{"Single PDF", []notion.Block{file(notion.BlockTypePDF, "https://x/a.pdf")}, "https://x/a.pdf"},
+		{"Image with blank paragraphs", []notion.Block{para(""), file(notion.BlockTypeImage, "https://x/a.png"), para(" ")}, "https://x/a.png"},
+		{"File with text", []notion.Block{para("Read this"), file(notion.BlockTypeFile, "https://x/a.zip")}, ""},
+		{"Two files", []notion.Block{file(notion.BlockTypeFile, "https://x/a"), file(notion.BlockTypeFile, "https://x/b")}, ""},
+		{"No file", []notion.Block{para("Text")}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := attachmentURL(&notion.PageContent{Blocks: tt.blocks})
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("attachmentURL() = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	var pages []notion.Page
+	for i := range 250 {
+		pages = append(pages, typedPage(fmt.Sprintf("p%03d", i), "prompt", fmt.Sprintf("Prompt %03d", i), time.Time{}))
+		pages = append(pages, typedPage(fmt.Sprintf("r%03d", i), "resource", fmt.Sprintf("Doc %03d", i), time.Time{}))
+	}
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type", ListPageSize: 100},
+		client: &fakeClient{},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, s.serverOptions())
+	s.registerPrompts(server, pages)
+	s.registerResources(server, pages)
+	session := connectTestClient(t, server)
+	ctx := context.Background()
+
+	t.Run("Prompts", func(t *testing.T) {
+		var names, sizes []string
+		cursor := ""
+		for {
+			res, err := session.ListPrompts(ctx, &mcp.ListPromptsParams{Cursor: cursor})
+			if err != nil {
+				t.Fatalf("ListPrompts() failed: %v", err)
+			}
+			sizes = append(sizes, fmt.Sprint(len(res.Prompts)))
+			for _, prompt := range res.Prompts {
+				names = append(names, prompt.Name)
+			}
+			if cursor = res.NextCursor; cursor == "" {
+				break
+			}
+		}
+		if got := strings.Join(sizes, ","); got != "100,100,50" {
+			t.Errorf("page sizes = %s, want 100,100,50", got)
+		}
+		if len(names) != 250 || !sort.StringsAreSorted(names) || names[0] != "prompt_000" {
+			t.Errorf("got %d prompts starting %v, want 250 in name order", len(names), names[:min(len(names), 3)])
+		}
+	})
+
+	t.Run("Resources", func(t *testing.T) {
+		seen := make(map[string]bool)
+		pagesRead := 0
+		cursor := ""
+		for {
+			res, err := session.ListResources(ctx, &mcp.ListResourcesParams{Cursor: cursor})
+			if err != nil {
+				t.Fatalf("ListResources() failed: %v", err)
+			}
+			pagesRead++
+			if len(res.Resources) > 100 {
+				t.Errorf("page %d has %d resources, want at most 100", pagesRead, len(res.Resources))
+			}
+			for _, resource := range res.Resources {
+				if seen[resource.URI] {
+					t.Errorf("resource %s listed twice", resource.URI)
+				}
+				seen[resource.URI] = true
+			}
+			if cursor = res.NextCursor; cursor == "" {
+				break
+			}
+		}
+		if len(seen) != 250 || pagesRead != 3 {
+			t.Errorf("got %d resources in %d pages, want 250 in 3", len(seen), pagesRead)
+		}
+	})
+}
+
+func TestSingleCodeBlock(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		wantCode string
+		wantLang string
+		wantOK   bool
+	}{
+		{"Code block", "```json\n{}\n```\n", "{}", "json", true},
+		{"Multiline", "```go\npackage main\n\nfunc main() {}\n```", "package main\n\nfunc main() {}", "go", true},
+		{"Text before", "Intro\n\n```json\n{}\n```\n", "", "", false},
+		{"Caption after", "```json\n{}\n```\n_schema_\n", "", "", false},
+		{"Two blocks", "```json\n{}\n```\n\n```json\n[]\n```\n", "", "", false},
+		{"Plain markdown", "# Title\n", "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, lang, ok := singleCodeBlock(tt.markdown)
+			if code != tt.wantCode || lang != tt.wantLang || ok != tt.wantOK {
+				t.Errorf("singleCodeBlock() = %q, %q, %v, want %q, %q, %v", code, lang, ok, tt.wantCode, tt.wantLang, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestEnabledTypes(t *testing.T) {
+	pages := []notion.Page{
+		typedPage("p1", "prompt", "Greeting", time.Time{}),
+		typedPage("r1", "resource", "Style Guide", time.Time{}),
+		typedPage("t1", "tool", "Word Count", time.Time{}),
+	}
+	client := &fakeClient{contents: map[string]*notion.PageContent{
+		"t1": {HasCode: true, Code: notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "wc -w"}}}},
+	}}
+	serve := func(t *testing.T, enabled string) *mcp.ClientSession {
+		t.Helper()
+		s := &Server{
+			cfg:    &config.Config{NotionTypeField: "Type", EnabledTypes: enabled},
+			client: client,
+			logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		}
+		server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+		s.registerPages(context.Background(), server, pages, false)
+		return connectTestClient(t, server)
+	}
+
+	t.Run("Tools served when enabled", func(t *testing.T) {
+		session := serve(t, "prompt,resource,tool")
+		tools, err := session.ListTools(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("ListTools() failed: %v", err)
+		}
+		if len(tools.Tools) != 1 || tools.Tools[0].Name != "word_count" {
+			t.Errorf("tools = %+v, want word_count", tools.Tools)
+		}
+	})
+
+	session := serve(t, "prompt")
+
+	if got := promptNames(t, session); !reflect.DeepEqual(got, []string{"greeting"}) {
+		t.Errorf("prompts = %v, want [greeting]", got)
+	}
+	resources, err := session.ListResources(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(resources.Resources) != 0 {
+		t.Errorf("resources = %+v, want none", resources.Resources)
+	}
+	tools, err := session.ListTools(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListTools() failed: %v", err)
+	}
+	if len(tools.Tools) != 0 {
+		t.Errorf("tools = %+v, want none", tools.Tools)
+	}
+	cfg := &config.Config{NotionTypeField: "Type", EnabledTypes: "prompt"}
+	if entries := ListEntries(pages, cfg); len(entries) != 1 || entries[0].Type != pageTypePrompt {
+		t.Errorf("ListEntries() = %+v, want only the prompt", entries)
+	}
+}
+
+func TestRegisterMultipleDatabases(t *testing.T) {
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type"},
+		client: &fakeClient{},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	fromDatabase := func(page notion.Page, databaseID string) notion.Page {
+		page.DatabaseID = databaseID
+		return page
+	}
+	pages := []notion.Page{
+		fromDatabase(typedPage("p1", "prompt", "Greeting", time.Time{}), "1111aaaa-0000-0000-0000-000000000000"),
+		fromDatabase(typedPage("r1", "resource", "Style Guide", time.Time{}), "1111aaaa-0000-0000-0000-000000000000"),
+		fromDatabase(typedPage("p2", "prompt", "Greeting", time.Time{}), "2222bbbb-0000-0000-0000-000000000000"),
+		fromDatabase(typedPage("p3", "prompt", "Farewell", time.Time{}), "2222bbbb-0000-0000-0000-000000000000"),
+		fromDatabase(typedPage("r2", "resource", "Style Guide", time.Time{}), "2222bbbb-0000-0000-0000-000000000000"),
+	}
+
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	s.registerPrompts(server, pages)
+	s.registerResources(server, pages)
+	session := connectTestClient(t, server)
+
+	want := []string{"farewell", "greeting", "greeting_2222bbbb"}
+	if got := promptNames(t, session); !reflect.DeepEqual(got, want) {
+		t.Errorf("prompts = %v, want %v", got, want)
+	}
+	res, err := session.ListResources(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("ListResources() failed: %v", err)
+	}
+	if len(res.Resources) != 2 {
+		t.Errorf("resources = %d, want 2", len(res.Resources))
+	}
+}
+
+func TestAssignNames(t *testing.T) {
+	s := &Server{cfg: &config.Config{NotionTypeField: "Type"}}
+	page := func(id, kind, databaseID string) notion.Page {
+		p := typedPage(id, kind, "Greeting", time.Time{})
+		p.DatabaseID = databaseID
+		return p
+	}
+
+	got := s.assignNames([]notion.Page{
+		page("p1", "prompt", "db-one"),
+		page("p2", "prompt", "db-two"),
+		page("p3", "prompt", "db-two"),
+		page("p4", "prompt", ""),
+		page("t1", "tool", "db-one"),
+		page("t2", "tool", "db-two"),
+		page("r1", "resource", "db-one"),
+	})
+	want := map[string]string{
+		"p1": "greeting",
+		"p2": "greeting_dbtwo",
+		"p3": "greeting_dbtwo_2",
+		"p4": "greeting_2",
+		"t1": "greeting",
+		"t2": "greeting_dbtwo",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("assignNames() = %v, want %v", got, want)
+	}
+}
+
+func TestCollidingToolNames(t *testing.T) {
+	tool := func(id, databaseID string) notion.Page {
+		p := typedPage(id, "tool", "Word Count", time.Time{})
+		p.DatabaseID = databaseID
+		return p
+	}
+	first, second := tool("t1", "1111aaaa-0000"), tool("t2", "2222bbbb-0000")
+	code := &notion.PageContent{HasCode: true, Code: notion.CodeBlock{Language: "bash", RichText: []notion.RichText{{PlainText: "wc -w"}}}}
+	s := &Server{
+		cfg:    &config.Config{NotionTypeField: "Type", Watch: true},
+		client: &fakeClient{contents: map[string]*notion.PageContent{"t1": code, "t2": code}},
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+	}
+	server := mcp.NewServer(&mcp.Implementation{Name: "test", Version: "1.0.0"}, s.serverOptions())
+	known := s.syncRegistrations(context.Background(), server, nil, []notion.Page{first, second})
+	session := connectTestClient(t, server)
+
+	toolNames := func(t *testing.T) []string {
+		t.Helper()
+		res, err := session.ListTools(context.Background(), nil)
+		if err != nil {
+			t.Fatalf("ListTools() failed: %v", err)
+		}
+		var names []string
+		for _, tool := range res.Tools {
+			names = append(names, tool.Name)
+		}
+		slices.Sort(names)
+		return names
+	}
+	if got, want := toolNames(t), []string{"word_count", "word_count_2222bbbb"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tools = %v, want %v", got, want)
+	}
+
+	// Deleting one tool leaves the other served
+	s.syncRegistrations(context.Background(), server, known, []notion.Page{second})
+	if got, want := toolNames(t), []string{"word_count"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tools after deleting one = %v, want %v", got, want)
+	}
+}
+
+func TestNewNotionClient(t *testing.T) {
+	var gotPath, gotVersion string
+	var gotBody struct {
+		PageSize int `json:"page_size"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotVersion = r.URL.Path, r.Header.Get("Notion-Version")
+		json.NewDecoder(r.Body).Decode(&gotBody)
+		w.Write([]byte(`{"results": [{"id": "p1"}], "has_more": false}`))
+	}))
+	defer srv.Close()
+
+	client, err := NewNotionClient(&config.Config{
+		NotionAPIKey:     "key",
+		NotionDatabaseID: "db",
+		NotionTypeField:  "Type",
+		NotionAPIVersion: "2025-09-03",
+		NotionBaseURL:    srv.URL + "/proxy/v1/",
+		NotionPageSize:   25,
+	})
+	if err != nil {
+		t.Fatalf("NewNotionClient() failed: %v", err)
+	}
+	pages, err := client.GetAllPages(context.Background())
+	if err != nil {
+		t.Fatalf("GetAllPages() failed: %v", err)
+	}
+
+	if len(pages) != 1 || pages[0].ID != "p1" {
+		t.Errorf("pages = %+v, want [p1]", pages)
+	}
+	if gotPath != "/proxy/v1/databases/db/query" {
+		t.Errorf("request path = %q, want /proxy/v1/databases/db/query", gotPath)
+	}
+	if gotVersion != "2025-09-03" {
+		t.Errorf("Notion-Version = %q, want 2025-09-03", gotVersion)
+	}
+	if gotBody.PageSize != 25 {
+		t.Errorf("page_size = %d, want 25", gotBody.PageSize)
+	}
+}
+
+func TestNewServerImplementation(t *testing.T) {
+	defer func(v string) { Version = v }(Version)
+	Version = "v1.2.3"
+
+	srv, err := NewServer(&config.Config{
+		NotionAPIKey:     "key",
+		NotionDatabaseID: "db",
+		NotionTypeField:  "Type",
+		ServerName:       "acme-notes",
+		CacheDir:         t.TempDir(),
+		CacheTTL:         time.Minute,
+		LogLevel:         "error",
+		ExecTimeout:      time.Second,
+		ExecLanguages:    "bash",
+	})
+	if err != nil {
+		t.Fatalf("NewServer() failed: %v", err)
+	}
+	if srv.impl.Name != "acme-notes" || srv.impl.Version != "v1.2.3" {
+		t.Errorf("Implementation = %+v, want acme-notes v1.2.3", *srv.impl)
+	}
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/samber/lo"
+
+	"github.com/nixihz/notion-as-mcp/internal/cache"
+	"github.com/nixihz/notion-as-mcp/internal/notion"
+)
+
+// serverOptions returns the MCP server options: list responses are split
+// into pages of LIST_PAGE_SIZE. In watch mode prompts, resources and tools
+// are advertised up front, so clients subscribe to list changes even when the
+// database starts out empty, and clients may subscribe to resources to hear
+// when one is edited.
+func (s *Server) serverOptions() *mcp.ServerOptions {
+	opts := &mcp.ServerOptions{PageSize: max(s.cfg.ListPageSize, 0)}
+	if s.cfg.Watch {
+		opts.Capabilities = &mcp.ServerCapabilities{
+			Logging:   &mcp.LoggingCapabilities{},
+			Prompts:   &mcp.PromptCapabilities{ListChanged: true},
+			Resources: &mcp.ResourceCapabilities{ListChanged: true, Subscribe: true},
+			Tools:     &mcp.ToolCapabilities{ListChanged: true},
+		}
+		// The SDK tracks subscriptions; there is nothing else to record
+		opts.SubscribeHandler = func(context.Context, *mcp.SubscribeRequest) error { return nil }
+		opts.UnsubscribeHandler = func(context.Context, *mcp.UnsubscribeRequest) error { return nil }
+	}
+	return opts
+}
+
+// startWatch starts the watch loop in the background if watch mode is on.
+func (s *Server) startWatch(ctx context.Context, server *mcp.Server, pages []notion.Page) {
+	if !s.cfg.Watch {
+		return
+	}
+	if s.cfg.PollInterval <= 0 {
+		s.logger.Warn("watch mode needs a positive POLL_INTERVAL; not watching")
+		return
+	}
+	s.logger.Info("watching Notion for changes", slog.Duration("interval", s.cfg.PollInterval))
+	go s.watch(ctx, server, pages)
+}
+
+// syncMargin is subtracted from the newest edit time seen before asking
+// Notion for pages edited since: Notion rounds edit times down to the minute
+// and its clock may differ from ours.
+const syncMargin = 2 * time.Minute
+
+// fullSyncEvery is how many polls pass between queries of the whole
+// database, which notice deleted pages that incremental polls cannot see.
+const fullSyncEvery = 10
+
+// watch polls Notion every PollInterval and updates the registrations on
+// server to match. The SDK notifies connected clients of list changes. Most
+// polls only fetch the pages edited since the newest edit time seen.
+func (s *Server) watch(ctx context.Context, server *mcp.Server, pages []notion.Page) {
+	mark := lastEdited(pages)
+
+	ticker := time.NewTicker(s.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for polls := 1; ; polls++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var err error
+			if polls%fullSyncEvery == 0 || mark.IsZero() {
+				pages, err = s.client.GetAllPages(ctx)
+			} else {
+				var changed []notion.Page
+				if changed, err = s.client.QueryDatabaseSince(ctx, mark.Add(-syncMargin)); err == nil {
+					pages = mergePages(pages, changed)
+				}
+			}
+			if err != nil {
+				s.logger.Warn("failed to poll pages", slog.String("error", err.Error()))
+				continue
+			}
+			mark = lastEdited(pages)
+			s.resync(ctx, server, pages)
+		}
+	}
+}
+
+// lastEdited returns the newest edit time of pages, or the zero time if
+// there are none.
+func lastEdited(pages []notion.Page) time.Time {
+	var latest time.Time
+	for _, page := range pages {
+		if page.LastEditedTime.After(latest) {
+			latest = page.LastEditedTime
+		}
+	}
+	return latest
+}
+
+// mergePages returns pages with each changed page replacing the page of the
+// same ID, or appended if it is new.
+func mergePages(pages, changed []notion.Page) []notion.Page {
+	merged := append([]notion.Page(nil), pages...)
+	index := make(map[string]int, len(merged))
+	for i, page := range merged {
+		index[page.ID] = i
+	}
+	for _, page := range changed {
+		if i, ok := index[page.ID]; ok {
+			merged[i] = page
+			continue
+		}
+		index[page.ID] = len(merged)
+		merged = append(merged, page)
+	}
+	return merged
+}
+
+// watchedPages returns the prompt, resource and tool pages, in order. The
+// order decides which of several same-titled prompts or tools keeps the
+// plain name.
+func (s *Server) watchedPages(pages []notion.Page) []notion.Page {
+	var watched []notion.Page
+	for _, page := range pages {
+		switch pageKind(s.cfg, page) {
+		case pageTypePrompt, pageTypeResource, pageTypeTool:
+			watched = append(watched, page)
+		}
+	}
+	return watched
+}
+
+// setRegistered records pages as the pages registered on the server.
+func (s *Server) setRegistered(pages []notion.Page) {
+	s.registered.Lock()
+	defer s.registered.Unlock()
+	s.registered.pages = s.watchedPages(pages)
+}
+
+// resync updates the registrations on server to match pages, diffing them
+// against the pages registered so far.
+func (s *Server) resync(ctx context.Context, server *mcp.Server, pages []notion.Page) {
+	s.registered.Lock()
+	defer s.registered.Unlock()
+	s.registered.pages = s.syncRegistrations(ctx, server, s.registered.pages, pages)
+}
+
+// registrationKey identifies what a page is registered as: its type and
+// assigned prompt or tool name, or resource URI.
+func (s *Server) registrationKey(page notion.Page, names map[string]string) string {
+	pageType := pageKind(s.cfg, page)
+	if pageType == pageTypeResource {
+		return pageType + ":" + resourceURI(page)
+	}
+	return pageType + ":" + names[page.ID]
+}
+
+// syncRegistrations diffs pages against the previously registered known
+// pages by last edited time, removing deleted pages and re-registering new
+// or edited ones. An edited tool is added again with its current code.
+// Subscribers to an edited resource are notified that it was updated. It
+// returns the pages now registered.
+func (s *Server) syncRegistrations(ctx context.Context, server *mcp.Server, known, pages []notion.Page) []notion.Page {
+	current := s.watchedPages(pages)
+	knownNames, currentNames := s.assignNames(known), s.assignNames(current)
+	currentByID := lo.KeyBy(current, func(page notion.Page) string { return page.ID })
+	knownByID := lo.KeyBy(known, func(page notion.Page) string { return page.ID })
+
+	for _, old := range known {
+		page, ok := currentByID[old.ID]
+		if ok && s.registrationKey(page, currentNames) == s.registrationKey(old, knownNames) {
+			continue
+		}
+		// Removed, or renamed or retyped so the new registration won't replace it
+		s.logger.Info("unregistering page", slog.String("page_id", old.ID))
+		switch pageKind(s.cfg, old) {
+		case pageTypePrompt:
+			server.RemovePrompts(knownNames[old.ID])
+		case pageTypeTool:
+			server.RemoveTools(knownNames[old.ID])
+		default:
+			s.resourcePages.Delete(normalizeID(old.ID))
+			server.RemoveResources(resourceURI(old))
+		}
+	}
+
+	for _, page := range current {
+		old, ok := knownByID[page.ID]
+		if ok && old.LastEditedTime.Equal(page.LastEditedTime) &&
+			s.registrationKey(page, currentNames) == s.registrationKey(old, knownNames) {
+			continue
+		}
+		if s.cache != nil {
+			_ = s.cache.Delete(ctx, cache.CacheKeyRenderPrefix+page.ID)
+			_ = s.cache.Delete(ctx, cache.CacheKeyBlobPrefix+page.ID)
+		}
+		switch pageKind(s.cfg, page) {
+		case pageTypePrompt:
+			s.addPrompt(server, page, currentNames[page.ID])
+			continue
+		case pageTypeTool:
+			s.addTool(server, page, currentNames[page.ID])
+			continue
+		}
+		s.addResource(server, page)
+		if ok && page.LastEditedTime.After(old.LastEditedTime) &&
+			s.registrationKey(page, currentNames) == s.registrationKey(old, knownNames) {
+			uri := resourceURI(page)
+			s.logger.Debug("resource updated", slog.String("uri", uri))
+			_ = server.ResourceUpdated(ctx, &mcp.ResourceUpdatedNotificationParams{URI: uri})
+		}
+	}
+
+	return current
+}
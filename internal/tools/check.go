@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// syntaxCheck describes how to check code in a language without running it.
+type syntaxCheck struct {
+	file   string   // source file name inside the working directory
+	prefix string   // prepended to the code before it is written
+	args   []string // interpreter arguments placed before the file
+}
+
+// syntaxChecks lists the compile-only checks, keyed by runtime key. They use
+// the default interpreters' flags, so overridden runtimes are not checked.
+var syntaxChecks = map[string]syntaxCheck{
+	"bash":   {file: "main.sh", args: []string{"-n"}},
+	"python": {file: "main.py", args: []string{"-m", "py_compile"}},
+	"js":     {file: "main.js", args: []string{"--check"}},
+	"ruby":   {file: "main.rb", args: []string{"-c"}},
+	"go":     {file: "main.go", args: []string{"vet"}},
+	"php":    {file: "main.php", prefix: "<?php\n", args: []string{"-l"}},
+}
+
+// Check reports whether code could be executed, without running it: the
+// language must be allowed and its runtime installed, bash code must not
+// match a deny pattern, and the code must pass the language's syntax check
+// where one exists.
+func (e *Executor) Check(ctx context.Context, language, code string) error {
+	if !e.isLanguageAllowed(language) {
+		return fmt.Errorf("language %q is not allowed", language)
+	}
+	if err := e.checkDenied(language, code); err != nil {
+		return err
+	}
+
+	key := runtimeKey(language)
+	rt, overridden := e.runtimes[key]
+	if !overridden {
+		var ok bool
+		if rt, ok = defaultRuntimes[key]; !ok {
+			return fmt.Errorf("unsupported language: %s", language)
+		}
+	}
+	if _, err := exec.LookPath(rt.Path); err != nil {
+		return fmt.Errorf("runtime for %s: %w", language, err)
+	}
+
+	check, ok := syntaxChecks[key]
+	if !ok || overridden {
+		return nil
+	}
+	if e.dedent {
+		code = Dedent(code)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	dir, err := os.MkdirTemp("", "notion-as-mcp-check-")
+	if err != nil {
+		return fmt.Errorf("create working directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, check.file)
+	if err := os.WriteFile(file, []byte(check.prefix+code), 0o600); err != nil {
+		return fmt.Errorf("write source file: %w", err)
+	}
+
+	args := append(append([]string{}, check.args...), file)
+	output, exitCode, err := e.run(exec.CommandContext(ctx, rt.Path, args...), &execution{dir: dir})
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("syntax check timed out after %s", e.timeout)
+	case err != nil:
+		return fmt.Errorf("run syntax check: %w", err)
+	case exitCode != 0:
+		return fmt.Errorf("syntax check failed: %s", strings.TrimSpace(output))
+	}
+	return nil
+}
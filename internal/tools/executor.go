@@ -2,22 +2,194 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // Executor executes code from Notion code blocks.
 type Executor struct {
-	timeout   time.Duration
-	languages map[string]bool
+	timeout        time.Duration
+	maxTimeout     time.Duration
+	languages      map[string]bool
+	runtimes       map[string]Runtime
+	envPassthrough []string
+	dedent         bool
+	insecureTLS    bool
+	denyPatterns   []*regexp.Regexp
+
+	// slots holds a token per running execution when concurrency is limited
+	slots     chan struct{}
+	waiting   atomic.Int64
+	maxQueued int
+
+	maxOutput int
+}
+
+// Runtime describes the interpreter used to run a language. The code flag
+// (e.g. -c or -e) and the code itself are appended after Args.
+type Runtime struct {
+	Path string
+	Args []string
+}
+
+// defaultRuntimes are used for languages without a configured runtime.
+var defaultRuntimes = map[string]Runtime{
+	"bash":   {Path: "bash"},
+	"python": {Path: "python3"},
+	"js":     {Path: "node"},
+	"ts": {Path: "npx", Args: []string{"ts-node", "--compiler-options",
+		`{"module":"commonjs","moduleResolution":"node"}`}},
+	"ruby": {Path: "ruby"},
+	"go":   {Path: "go", Args: []string{"build"}},
+	"php":  {Path: "php"},
+}
+
+// languageAliases maps alternative language names to their runtime key.
+var languageAliases = map[string]string{
+	"sh":         "bash",
+	"py":         "python",
+	"javascript": "js",
+	"typescript": "ts",
+	"rb":         "ruby",
+	"golang":     "go",
+}
+
+// defaultEnvPassthrough lists the environment variables passed to executed
+// code; everything else in the server's environment is withheld.
+var defaultEnvPassthrough = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TZ", "TMPDIR"}
+
+// ExecutorOption configures an Executor.
+type ExecutorOption func(*Executor)
+
+// WithRuntimes overrides the interpreter used for the given languages.
+func WithRuntimes(runtimes map[string]Runtime) ExecutorOption {
+	return func(e *Executor) {
+		for lang, rt := range runtimes {
+			e.runtimes[runtimeKey(lang)] = rt
+		}
+	}
+}
+
+// WithEnvPassthrough passes the named environment variables through to
+// executed code in addition to the defaults.
+func WithEnvPassthrough(names ...string) ExecutorOption {
+	return func(e *Executor) {
+		e.envPassthrough = append(e.envPassthrough, names...)
+	}
+}
+
+// WithDedent strips common leading whitespace from code before running it.
+func WithDedent() ExecutorOption {
+	return func(e *Executor) {
+		e.dedent = true
+	}
+}
+
+// WithInsecureTLS disables TLS certificate verification for TypeScript code.
+// It exists for endpoints with self-signed certificates and must be opted
+// into explicitly.
+func WithInsecureTLS() ExecutorOption {
+	return func(e *Executor) {
+		e.insecureTLS = true
+	}
+}
+
+// ErrTooManyExecutions is returned by Execute when the execution limit is
+// reached and too many calls are already waiting for a slot.
+var ErrTooManyExecutions = errors.New("too many concurrent executions")
+
+// WithMaxConcurrent limits how many executions run at once. Further calls
+// wait for a slot until their context is done; if maxQueued is positive and
+// that many are already waiting, they fail with ErrTooManyExecutions.
+func WithMaxConcurrent(limit, maxQueued int) ExecutorOption {
+	return func(e *Executor) {
+		if limit > 0 {
+			e.slots = make(chan struct{}, limit)
+			e.maxQueued = maxQueued
+		}
+	}
+}
+
+// ErrDenied is returned by Execute and Check for bash code that matches a
+// pattern passed to WithDenyPatterns.
+var ErrDenied = errors.New("code matches a denied pattern")
+
+// WithDenyPatterns refuses to run bash code matching any of patterns, such
+// as `rm\s+-rf\s+/`. It is an advisory guardrail against obviously
+// dangerous tools, not a sandbox: code can always be written to avoid a
+// pattern.
+func WithDenyPatterns(patterns ...*regexp.Regexp) ExecutorOption {
+	return func(e *Executor) {
+		e.denyPatterns = append(e.denyPatterns, patterns...)
+	}
+}
+
+// ParseDenyPatterns parses a comma-separated list of regular expressions,
+// such as `rm\s+-rf\s+/,curl[^|]*\|\s*(ba)?sh`. Write a comma inside a
+// pattern as \x2c.
+func ParseDenyPatterns(spec string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, err := regexp.Compile(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deny pattern %q: %w", entry, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// checkDenied returns ErrDenied, naming the pattern, if code is bash code
+// matching a deny pattern.
+func (e *Executor) checkDenied(language, code string) error {
+	if runtimeKey(language) != "bash" {
+		return nil
+	}
+	for _, pattern := range e.denyPatterns {
+		if pattern.MatchString(code) {
+			return fmt.Errorf("%w: %s", ErrDenied, pattern)
+		}
+	}
+	return nil
+}
+
+// WithMaxOutput keeps at most limit bytes of an execution's output,
+// dropping the rest and appending a truncation marker.
+func WithMaxOutput(limit int) ExecutorOption {
+	return func(e *Executor) {
+		e.maxOutput = limit
+	}
+}
+
+// WithMaxTimeout caps the per-call timeout passed to ExecuteTimeout. The
+// executor's default timeout is not capped.
+func WithMaxTimeout(limit time.Duration) ExecutorOption {
+	return func(e *Executor) {
+		e.maxTimeout = limit
+	}
 }
 
 // NewExecutor creates a new code executor.
-func NewExecutor(timeout time.Duration, languages string) *Executor {
+func NewExecutor(timeout time.Duration, languages string, opts ...ExecutorOption) *Executor {
 	langMap := make(map[string]bool)
 	for _, lang := range strings.Split(languages, ",") {
 		lang = strings.TrimSpace(lang)
@@ -25,42 +197,166 @@ func NewExecutor(timeout time.Duration, languages string) *Executor {
 			langMap[lang] = true
 		}
 	}
-	return &Executor{
+	e := &Executor{
 		timeout:   timeout,
 		languages: langMap,
+		runtimes:  make(map[string]Runtime),
+
+		envPassthrough: append([]string{}, defaultEnvPassthrough...),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// ParseRuntimes parses a runtime list such as
+// "python=/usr/bin/python3.12,js=bun". Everything after '=' is split on
+// whitespace into the interpreter path and its leading arguments.
+func ParseRuntimes(spec string) (map[string]Runtime, error) {
+	runtimes := make(map[string]Runtime)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		lang, command, ok := strings.Cut(entry, "=")
+		lang = strings.TrimSpace(lang)
+		fields := strings.Fields(command)
+		if !ok || lang == "" || len(fields) == 0 {
+			return nil, fmt.Errorf("invalid runtime %q: want language=interpreter", entry)
+		}
+		runtimes[runtimeKey(lang)] = Runtime{Path: fields[0], Args: fields[1:]}
+	}
+	return runtimes, nil
+}
+
+// ValidateRuntimes checks that every configured interpreter can be found.
+func (e *Executor) ValidateRuntimes() error {
+	for lang, rt := range e.runtimes {
+		if _, err := exec.LookPath(rt.Path); err != nil {
+			return fmt.Errorf("runtime for %s: %w", lang, err)
+		}
 	}
+	return nil
 }
 
-// ExecutionResult represents the result of code execution.
+// runtimeKey returns the runtime key for a language name.
+func runtimeKey(language string) string {
+	if key, ok := languageAliases[language]; ok {
+		return key
+	}
+	return language
+}
+
+// command builds the command for language, appending args (typically the
+// code flag and the code) to the runtime's own arguments.
+func (e *Executor) command(ctx context.Context, language string, args ...string) *exec.Cmd {
+	rt, ok := e.runtimes[language]
+	if !ok {
+		rt = defaultRuntimes[language]
+	}
+	return exec.CommandContext(ctx, rt.Path, append(append([]string{}, rt.Args...), args...)...)
+}
+
+// ExecutionResult represents the result of code execution. Error is set when
+// the code could not run to completion (e.g. it timed out); a nonzero
+// ExitCode alone leaves it empty.
 type ExecutionResult struct {
 	Output   string
 	Error    string
 	ExitCode int
+	// Stdout and Stderr hold the two streams separately, for ExecuteSplit
+	Stdout string
+	Stderr string
 }
 
-// Execute executes code in the specified language.
+// Execute executes code in the specified language. Cancelling ctx, like the
+// timeout expiring, kills the code's whole process group and sets the
+// result's Error.
 func (e *Executor) Execute(ctx context.Context, language, code string, input any) (*ExecutionResult, error) {
+	return e.ExecuteStream(ctx, 0, language, code, input, nil)
+}
+
+// ExecuteTimeout is like Execute but overrides the executor's timeout. A
+// nonpositive timeout uses the default; one above the configured maximum is
+// clamped to it.
+func (e *Executor) ExecuteTimeout(ctx context.Context, timeout time.Duration, language, code string, input any) (*ExecutionResult, error) {
+	return e.ExecuteStream(ctx, timeout, language, code, input, nil)
+}
+
+// ExecuteStream is like ExecuteTimeout but also calls onLine, if not nil,
+// with each line of output as the code writes it. The result still holds
+// the complete output.
+func (e *Executor) ExecuteStream(ctx context.Context, timeout time.Duration, language, code string, input any, onLine func(string)) (*ExecutionResult, error) {
+	return e.execute(ctx, timeout, language, code, input, &execution{onLine: onLine})
+}
+
+// ExecuteSplit is like ExecuteStream but also collects standard output and
+// standard error separately, in the result's Stdout and Stderr. Output then
+// interleaves the two only as closely as their pipes are read.
+func (e *Executor) ExecuteSplit(ctx context.Context, timeout time.Duration, language, code string, input any, onLine func(string)) (*ExecutionResult, error) {
+	return e.execute(ctx, timeout, language, code, input, &execution{onLine: onLine, split: true})
+}
+
+// errTimedOut is the cause of an execution's context when the execution's
+// own timeout, rather than the caller's deadline, expires.
+var errTimedOut = errors.New("execution timed out")
+
+// execute runs code as x describes.
+func (e *Executor) execute(ctx context.Context, timeout time.Duration, language, code string, input any, x *execution) (*ExecutionResult, error) {
 	// Check if language is allowed
 	if !e.isLanguageAllowed(language) {
 		return nil, fmt.Errorf("language %q is not allowed", language)
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	if e.dedent {
+		code = Dedent(code)
+	}
+	if err := e.checkDenied(language, code); err != nil {
+		return nil, err
+	}
+
+	if err := e.acquire(ctx); err != nil {
+		return nil, err
+	}
+	defer e.release()
+
+	// The cap applies to overrides only, never to the executor's own timeout
+	if timeout <= 0 {
+		timeout = e.timeout
+	} else if e.maxTimeout > 0 {
+		timeout = min(timeout, e.maxTimeout)
+	}
+	ctx, cancel := context.WithTimeoutCause(ctx, timeout, errTimedOut)
 	defer cancel()
 
+	// Each execution gets a fresh working directory
+	dir, err := os.MkdirTemp("", "notion-as-mcp-exec-")
+	if err != nil {
+		return nil, fmt.Errorf("create working directory: %w", err)
+	}
+	defer os.RemoveAll(dir)
+	x.dir = dir
+
 	var output string
 	var exitCode int
-	var err error
 
 	switch language {
 	case "bash", "sh":
-		output, exitCode, err = e.executeBash(ctx, code, input)
+		output, exitCode, err = e.executeBash(ctx, x, code, input)
 	case "python", "py":
-		output, exitCode, err = e.executePython(ctx, code, input)
+		output, exitCode, err = e.executePython(ctx, x, code, input)
 	case "js", "javascript":
-		output, exitCode, err = e.executeNode(ctx, code, input)
+		output, exitCode, err = e.executeNode(ctx, x, code, input)
 	case "ts", "typescript":
-		output, exitCode, err = e.executeTsNode(ctx, code, input)
+		output, exitCode, err = e.executeTsNode(ctx, x, code, input)
+	case "ruby", "rb":
+		output, exitCode, err = e.executeRuby(ctx, x, code, input)
+	case "go", "golang":
+		output, exitCode, err = e.executeGo(ctx, x, code, input)
+	case "php":
+		output, exitCode, err = e.executePHP(ctx, x, code, input)
 	default:
 		return nil, fmt.Errorf("unsupported language: %s", language)
 	}
@@ -68,14 +364,56 @@ func (e *Executor) Execute(ctx context.Context, language, code string, input any
 	result := &ExecutionResult{
 		Output:   output,
 		ExitCode: exitCode,
+		Stdout:   x.stdout,
+		Stderr:   x.stderr,
 	}
-	if err != nil {
+	switch {
+	case errors.Is(context.Cause(ctx), errTimedOut):
+		result.Error = fmt.Sprintf("execution timed out after %s", timeout)
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		result.Error = "execution cancelled: the caller's deadline passed"
+	case errors.Is(ctx.Err(), context.Canceled):
+		result.Error = "execution cancelled"
+	case err != nil:
 		result.Error = err.Error()
 	}
 
 	return result, nil
 }
 
+// acquire takes an execution slot, waiting for one to free up if the limit
+// is reached. It is a no-op without a limit.
+func (e *Executor) acquire(ctx context.Context) error {
+	if e.slots == nil {
+		return nil
+	}
+	select {
+	case e.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	if waiting := e.waiting.Add(1); e.maxQueued > 0 && waiting > int64(e.maxQueued) {
+		e.waiting.Add(-1)
+		return ErrTooManyExecutions
+	}
+	defer e.waiting.Add(-1)
+
+	select {
+	case e.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("waiting for an execution slot: %w", ctx.Err())
+	}
+}
+
+// release frees the slot taken by acquire.
+func (e *Executor) release() {
+	if e.slots != nil {
+		<-e.slots
+	}
+}
+
 // isLanguageAllowed checks if a language is in the allowed list.
 func (e *Executor) isLanguageAllowed(language string) bool {
 	if len(e.languages) == 0 {
@@ -85,65 +423,290 @@ func (e *Executor) isLanguageAllowed(language string) bool {
 	return e.languages[language]
 }
 
-// executeBash executes bash code.
-func (e *Executor) executeBash(ctx context.Context, code string, input any) (string, int, error) {
-	cmd := exec.CommandContext(ctx, "bash", "-c", code)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return string(output), exitErr.ExitCode(), nil
-		}
-		return string(output), -1, err
+// Supports reports whether Execute accepts language: it must be allowed and
+// one the executor knows how to run. It does not check the runtime is
+// installed; see Check.
+func (e *Executor) Supports(language string) bool {
+	_, known := defaultRuntimes[runtimeKey(language)]
+	return known && e.isLanguageAllowed(language)
+}
+
+// executeBash executes bash code. Named arguments in the input are passed as
+// MCP_ARG_<name> environment variables and, in the order Arguments gives, as
+// positional parameters, so values are never spliced into the script.
+func (e *Executor) executeBash(ctx context.Context, x *execution, code string, input any) (string, int, error) {
+	args, env := bashArguments(input)
+	cmd := e.command(ctx, "bash", append([]string{"-c", code, "bash"}, args...)...)
+	cmd.Env = append(cmd.Env, env...)
+	return e.run(cmd, x)
+}
+
+// Arguments is tool input whose named arguments bash code also receives as
+// positional parameters: $1 is the argument named Order[0], and so on.
+// Other languages receive Input unchanged.
+type Arguments struct {
+	Input any // a JSON object, either decoded or as JSON text
+	Order []string
+}
+
+// MarshalJSON encodes the arguments as their Input.
+func (a Arguments) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Input)
+}
+
+// bashArguments returns the positional parameters and MCP_ARG_ environment
+// variables for the named arguments in input. Strings are passed as they
+// are, other values as JSON, and a missing argument as "".
+func bashArguments(input any) (args, env []string) {
+	var order []string
+	if a, ok := input.(Arguments); ok {
+		input, order = a.Input, a.Order
 	}
-	return string(output), 0, nil
+	values, _ := input.(map[string]any)
+	if text, ok := input.(string); ok {
+		json.Unmarshal([]byte(text), &values)
+	}
+	for _, name := range slices.Sorted(maps.Keys(values)) {
+		env = append(env, "MCP_ARG_"+envName(name)+"="+argumentText(values[name]))
+	}
+	for _, name := range order {
+		args = append(args, argumentText(values[name]))
+	}
+	return args, env
 }
 
-// executePython executes python code.
-func (e *Executor) executePython(ctx context.Context, code string, input any) (string, int, error) {
-	cmd := exec.CommandContext(ctx, "python3", "-c", code)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return string(output), exitErr.ExitCode(), nil
+// envName replaces the characters of an argument name that may not appear
+// in an environment variable name with underscores.
+func envName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
 		}
-		return string(output), -1, err
+		return '_'
+	}, name)
+}
+
+// argumentText returns the text of an argument value for the shell.
+func argumentText(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
 	}
-	return string(output), 0, nil
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// executePython executes python code.
+func (e *Executor) executePython(ctx context.Context, x *execution, code string, input any) (string, int, error) {
+	return e.run(e.command(ctx, "python", "-c", code), x)
 }
 
 // executeNode executes JavaScript code.
-func (e *Executor) executeNode(ctx context.Context, code string, input any) (string, int, error) {
-	cmd := exec.CommandContext(ctx, "node", "-e", code)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return string(output), exitErr.ExitCode(), nil
-		}
-		return string(output), -1, err
+func (e *Executor) executeNode(ctx context.Context, x *execution, code string, input any) (string, int, error) {
+	return e.run(e.command(ctx, "js", "-e", code), x)
+}
+
+// executeRuby executes Ruby code.
+func (e *Executor) executeRuby(ctx context.Context, x *execution, code string, input any) (string, int, error) {
+	return e.run(e.command(ctx, "ruby", "-e", code), x)
+}
+
+// executeGo executes Go code. The code must be a complete main package;
+// it is written to the working directory, built there, and the binary run,
+// so the code's own exit code is reported. If the build fails, its output
+// and exit code are returned instead.
+func (e *Executor) executeGo(ctx context.Context, x *execution, code string, input any) (string, int, error) {
+	file := filepath.Join(x.dir, "main.go")
+	if err := os.WriteFile(file, []byte(code), 0600); err != nil {
+		return "", -1, fmt.Errorf("write source: %w", err)
+	}
+	binary := filepath.Join(x.dir, "main")
+	if runtime.GOOS == "windows" {
+		binary += ".exe"
 	}
-	return string(output), 0, nil
+	output, exitCode, err := e.run(e.command(ctx, "go", "-o", binary, file), x)
+	if err != nil || exitCode != 0 {
+		return output, exitCode, err
+	}
+	return e.run(exec.CommandContext(ctx, binary), x)
 }
 
-func (e *Executor) executeTsNode(ctx context.Context, code string, input any) (string, int, error) {
+// executePHP executes PHP code (without the opening <?php tag).
+func (e *Executor) executePHP(ctx context.Context, x *execution, code string, input any) (string, int, error) {
+	return e.run(e.command(ctx, "php", "-r", code), x)
+}
+
+// executeTsNode executes TypeScript code that defines a handle function.
+// The JSON input is passed in the MCP_INPUT environment variable rather than
+// spliced into the source, so no escaping is needed.
+func (e *Executor) executeTsNode(ctx context.Context, x *execution, code string, input any) (string, int, error) {
 	jsonInput, err := json.Marshal(input)
 	if err != nil {
 		return "", -1, fmt.Errorf("failed to marshal input: %w", err)
 	}
-	// Escape the JSON string for safe embedding in JavaScript string literal
-	// Escape backslashes first, then single quotes
-	jsonStr := strings.ReplaceAll(string(jsonInput), `\`, `\\`)
-	jsonStr = strings.ReplaceAll(jsonStr, `'`, `\'`)
-	// Use JSON.parse to safely parse the JSON string, and console.log to output the result
-	codeRun := fmt.Sprintf("%s\n console.log(JSON.stringify(handle(JSON.parse('%s'))));", code, jsonStr)
-	cmd := exec.CommandContext(ctx, "npx", "ts-node", "--compiler-options",
-		`{"module":"commonjs","moduleResolution":"node"}`, "-e", codeRun)
-	cmd.Env = append(cmd.Env, "NODE_TLS_REJECT_UNAUTHORIZED=0")
-	output, err := cmd.CombinedOutput()
+	codeRun := code + "\nconsole.log(JSON.stringify(handle(JSON.parse(process.env.MCP_INPUT))));"
+	cmd := e.command(ctx, "ts", "-e", codeRun)
+	cmd.Env = append(cmd.Env, "MCP_INPUT="+string(jsonInput))
+	if e.insecureTLS {
+		cmd.Env = append(cmd.Env, "NODE_TLS_REJECT_UNAUTHORIZED=0")
+	}
+	return e.run(cmd, x)
+}
+
+// execution describes where and how one piece of code runs.
+type execution struct {
+	dir    string
+	onLine func(string) // receives each output line as it is written, if set
+
+	// split collects the output streams separately in stdout and stderr
+	split          bool
+	stdout, stderr string
+}
+
+// run runs cmd in x's directory with a scrubbed environment in its own
+// process group, returning the combined output and exit code. A nonzero exit
+// is not an error.
+func (e *Executor) run(cmd *exec.Cmd, x *execution) (string, int, error) {
+	cmd.Dir = x.dir
+	cmd.Env = append(e.environ(), cmd.Env...)
+	release := setProcessGroup(cmd)
+
+	output := &limitedBuffer{limit: e.maxOutput, onLine: x.onLine}
+	cmd.Stdout = output
+	cmd.Stderr = output
+	if x.split {
+		// Separate writers get separate pipes, copied concurrently
+		var mu sync.Mutex
+		stdout := &limitedBuffer{limit: e.maxOutput}
+		stderr := &limitedBuffer{limit: e.maxOutput}
+		cmd.Stdout = &teeWriter{mu: &mu, w: []io.Writer{output, stdout}}
+		cmd.Stderr = &teeWriter{mu: &mu, w: []io.Writer{output, stderr}}
+		defer func() { x.stdout, x.stderr = stdout.String(), stderr.String() }()
+	}
+	err := cmd.Run()
+	release()
+	output.flush()
 	if err != nil {
 		if exitErr, ok := err.(*exec.ExitError); ok {
-			return string(output), exitErr.ExitCode(), nil
+			return output.String(), exitErr.ExitCode(), nil
+		}
+		return output.String(), -1, err
+	}
+	return output.String(), 0, nil
+}
+
+// teeWriter writes to each of w in turn while holding mu, which other
+// teeWriters writing to the same buffers share.
+type teeWriter struct {
+	mu *sync.Mutex
+	w  []io.Writer
+}
+
+func (t *teeWriter) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, w := range t.w {
+		w.Write(p)
+	}
+	return len(p), nil
+}
+
+// limitedBuffer collects output up to limit bytes (0 = unlimited) and counts
+// the rest. Writes never fail, so the process runs on unaware. If onLine is
+// set it receives each complete line of the kept output.
+type limitedBuffer struct {
+	buf     bytes.Buffer
+	limit   int
+	dropped int
+
+	onLine  func(string)
+	partial []byte // kept output not yet passed to onLine
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if b.limit > 0 {
+		if room := b.limit - b.buf.Len(); len(p) > room {
+			b.dropped += len(p) - room
+			p = p[:room]
+		}
+	}
+	b.buf.Write(p)
+
+	if b.onLine != nil {
+		b.partial = append(b.partial, p...)
+		for {
+			i := bytes.IndexByte(b.partial, '\n')
+			if i < 0 {
+				break
+			}
+			b.onLine(string(b.partial[:i]))
+			b.partial = b.partial[i+1:]
+		}
+	}
+	return n, nil
+}
+
+// flush passes a final unterminated line to onLine.
+func (b *limitedBuffer) flush() {
+	if b.onLine != nil && len(b.partial) > 0 {
+		b.onLine(string(b.partial))
+		b.partial = nil
+	}
+}
+
+// String returns the collected output, followed by a marker if any was
+// dropped.
+func (b *limitedBuffer) String() string {
+	if b.dropped == 0 {
+		return b.buf.String()
+	}
+	return fmt.Sprintf("%s\n[output truncated: %d bytes omitted]\n", b.buf.String(), b.dropped)
+}
+
+// environ returns the environment for child processes: only the allowlisted
+// variables that are set in the server's environment.
+func (e *Executor) environ() []string {
+	env := make([]string, 0, len(e.envPassthrough))
+	for _, name := range e.envPassthrough {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return env
+}
+
+// Dedent removes any whitespace prefix common to every line of text, like
+// Python's textwrap.dedent. Whitespace-only lines are ignored when finding
+// the prefix and are emptied in the result; tabs and spaces never match
+// each other.
+func Dedent(text string) string {
+	lines := strings.Split(text, "\n")
+	margin := ""
+	found := false
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if !found {
+			margin, found = indent, true
+			continue
+		}
+		n := 0
+		for n < len(margin) && n < len(indent) && margin[n] == indent[n] {
+			n++
+		}
+		margin = margin[:n]
+	}
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		} else {
+			lines[i] = line[len(margin):]
 		}
-		return string(output), -1, err
 	}
-	return string(output), 0, nil
+	return strings.Join(lines, "\n")
 }
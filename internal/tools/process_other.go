@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tools
+
+import "os/exec"
+
+// setProcessGroup is a no-op on platforms without process groups; only the
+// direct child is killed on cancellation.
+func setProcessGroup(cmd *exec.Cmd) (release func()) { return func() {} }
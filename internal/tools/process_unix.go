@@ -0,0 +1,53 @@
+//go:build unix
+
+package tools
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// killGracePeriod is how long a cancelled process group has to exit after
+// SIGTERM before it is sent SIGKILL.
+const killGracePeriod = 2 * time.Second
+
+// setProcessGroup places cmd in its own process group so cancellation
+// reaches every process it spawned: the group is sent SIGTERM, then SIGKILL
+// once the grace period has passed. The returned release must be called
+// once cmd has been waited for; it stops a pending SIGKILL so a process
+// group ID the system has since reused is never signalled.
+func setProcessGroup(cmd *exec.Cmd) (release func()) {
+	var (
+		mu       sync.Mutex
+		kill     *time.Timer
+		released bool
+	)
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		pgid := -cmd.Process.Pid
+		err := syscall.Kill(pgid, syscall.SIGTERM)
+		mu.Lock()
+		defer mu.Unlock()
+		if !released {
+			kill = time.AfterFunc(killGracePeriod, func() {
+				// Signal 0 checks that the group still has members
+				if syscall.Kill(pgid, 0) == nil {
+					_ = syscall.Kill(pgid, syscall.SIGKILL)
+				}
+			})
+		}
+		return err
+	}
+	// Stop waiting on output pipes held open by processes that escaped the group
+	cmd.WaitDelay = 2 * killGracePeriod
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		released = true
+		if kill != nil {
+			kill.Stop()
+		}
+	}
+}
@@ -0,0 +1,163 @@
+//go:build unix
+
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestExecutorTimeoutKillsProcessTree(t *testing.T) {
+	e := NewExecutor(300*time.Millisecond, "bash")
+
+	// The child prints the grandchild's PID, then both sleep past the timeout
+	result, err := e.Execute(context.Background(), "bash", "sleep 30 & echo $!; sleep 30", nil)
+	if err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+
+	if !strings.Contains(result.Error, "timed out") {
+		t.Errorf("Error = %q, want timeout error", result.Error)
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(result.Output))
+	if err != nil {
+		t.Fatalf("could not parse grandchild PID from output %q", result.Output)
+	}
+
+	deadline := time.Now().Add(killGracePeriod + time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("grandchild %d still running after timeout", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestExecutorCancelKillsProcessTree(t *testing.T) {
+	e := NewExecutor(time.Minute, "bash")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Cancel as soon as the child reports the grandchild's PID
+	pids := make(chan int, 1)
+	onLine := func(line string) {
+		if pid, err := strconv.Atoi(line); err == nil {
+			pids <- pid
+			cancel()
+		}
+	}
+	start := time.Now()
+	result, err := e.ExecuteStream(ctx, 0, "bash", "sleep 30 & echo $!; sleep 30", nil, onLine)
+	if err != nil {
+		t.Fatalf("ExecuteStream() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > killGracePeriod {
+		t.Errorf("ExecuteStream() returned after %v, want soon after cancellation", elapsed)
+	}
+	if result.Error != "execution cancelled" {
+		t.Errorf("Error = %q, want %q", result.Error, "execution cancelled")
+	}
+
+	var pid int
+	select {
+	case pid = <-pids:
+	default:
+		t.Fatalf("no grandchild PID in output %q", result.Output)
+	}
+	deadline := time.Now().Add(time.Second)
+	for processAlive(pid) {
+		if time.Now().After(deadline) {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+			t.Fatalf("grandchild %d still running after cancellation", pid)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestExecutorRunsInScrubbedWorkdir(t *testing.T) {
+	t.Setenv("NOTION_API_KEY", "secret-key")
+	t.Setenv("EXTRA_VAR", "extra")
+	ctx := context.Background()
+
+	t.Run("Fresh working directory", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash")
+		cwd, _ := os.Getwd()
+
+		result, err := e.Execute(ctx, "bash", "pwd", nil)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		dir := strings.TrimSpace(result.Output)
+		if dir == cwd || !strings.Contains(dir, "notion-as-mcp-exec-") {
+			t.Errorf("working directory = %q, want a fresh temp directory", dir)
+		}
+		if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("working directory %q not removed after execution", dir)
+		}
+	})
+
+	t.Run("Environment is scrubbed", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash")
+
+		result, err := e.Execute(ctx, "bash", `echo "key=${NOTION_API_KEY:-unset} extra=${EXTRA_VAR:-unset}"`, nil)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if result.Output != "key=unset extra=unset\n" {
+			t.Errorf("Output = %q, want secrets withheld", result.Output)
+		}
+	})
+
+	t.Run("Allowlisted variables pass through", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash", WithEnvPassthrough("EXTRA_VAR"))
+
+		result, err := e.Execute(ctx, "bash", `echo "extra=${EXTRA_VAR:-unset}"`, nil)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if result.Output != "extra=extra\n" {
+			t.Errorf("Output = %q, want %q", result.Output, "extra=extra\n")
+		}
+	})
+
+	t.Run("Python sees only allowlisted variables", func(t *testing.T) {
+		if _, err := exec.LookPath("python3"); err != nil {
+			t.Skip("python3 not installed")
+		}
+		e := NewExecutor(5*time.Second, "python", WithEnvPassthrough("EXTRA_VAR"))
+
+		code := "import os; print(os.environ.get('EXTRA_VAR', 'unset'), os.environ.get('NOTION_API_KEY', 'unset'))"
+		result, err := e.Execute(ctx, "python", code, nil)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if result.Output != "extra unset\n" {
+			t.Errorf("Output = %q, want %q", result.Output, "extra unset\n")
+		}
+	})
+}
+
+// processAlive reports whether pid is running. Zombies count as exited,
+// since an orphan may not be reaped promptly inside a container.
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	stat, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return true
+	}
+	// The state follows the parenthesised command name
+	fields := strings.Fields(string(stat[strings.LastIndexByte(string(stat), ')')+1:]))
+	return len(fields) == 0 || fields[0] != "Z"
+}
@@ -3,6 +3,15 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -66,6 +75,25 @@ func TestExecutorIsLanguageAllowed(t *testing.T) {
 	})
 }
 
+func TestExecutorSupports(t *testing.T) {
+	e := NewExecutor(10*time.Second, "bash,python,py,cobol")
+
+	tests := []struct {
+		language string
+		want     bool
+	}{
+		{"bash", true},
+		{"py", true},
+		{"javascript", false}, // known but not allowed
+		{"cobol", false},      // allowed but unknown
+	}
+	for _, tt := range tests {
+		if got := e.Supports(tt.language); got != tt.want {
+			t.Errorf("Supports(%q) = %v, want %v", tt.language, got, tt.want)
+		}
+	}
+}
+
 func TestExecutorExecute(t *testing.T) {
 	ctx := context.Background()
 
@@ -141,9 +169,9 @@ func TestExecutorExecute(t *testing.T) {
 	})
 
 	t.Run("Unsupported language", func(t *testing.T) {
-		e := NewExecutor(5*time.Second, "ruby")
+		e := NewExecutor(5*time.Second, "cobol")
 
-		_, err := e.Execute(ctx, "ruby", "puts 'test'", nil)
+		_, err := e.Execute(ctx, "cobol", "DISPLAY 'test'", nil)
 		if err == nil {
 			t.Error("Execute() with unsupported language should return error")
 		}
@@ -155,10 +183,36 @@ func TestExecutorExecute(t *testing.T) {
 		// This should timeout
 		result, err := e.Execute(ctx, "bash", "sleep 10", nil)
 		if err != nil {
-			// Timeout is expected
-			if result.Error == "" {
-				t.Errorf("Expected timeout error, got: %v", err)
-			}
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if !strings.Contains(result.Error, "timed out after 100ms") {
+			t.Errorf("Error = %q, want timeout error", result.Error)
+		}
+	})
+
+	t.Run("Caller deadline is not reported as timeout", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash")
+		deadlineCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		result, err := e.Execute(deadlineCtx, "bash", "sleep 10", nil)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if strings.Contains(result.Error, "timed out after") || !strings.Contains(result.Error, "deadline") {
+			t.Errorf("Error = %q, want the caller's deadline", result.Error)
+		}
+	})
+
+	t.Run("Nonzero exit is not reported as timeout", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash")
+
+		result, err := e.Execute(ctx, "bash", "exit 3", nil)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if result.ExitCode != 3 || result.Error != "" {
+			t.Errorf("ExitCode = %d, Error = %q, want 3 and no error", result.ExitCode, result.Error)
 		}
 	})
 
@@ -189,6 +243,589 @@ func TestExecutorExecute(t *testing.T) {
 	})
 }
 
+func TestExecutorExecuteAdditionalLanguages(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		binary   string
+		language string
+		code     string
+		wantOut  string
+		wantExit int
+	}{
+		{
+			name:     "Ruby execution",
+			binary:   "ruby",
+			language: "ruby",
+			code:     `puts "Hello from Ruby"`,
+			wantOut:  "Hello from Ruby\n",
+		},
+		{
+			name:     "Ruby exit code",
+			binary:   "ruby",
+			language: "rb",
+			code:     "exit 3",
+			wantExit: 3,
+		},
+		{
+			name:     "Go execution",
+			binary:   "go",
+			language: "go",
+			code:     "package main\n\nimport \"fmt\"\n\nfunc main() { fmt.Println(\"Hello from Go\") }\n",
+			wantOut:  "Hello from Go\n",
+		},
+		{
+			name:     "Go exit code",
+			binary:   "go",
+			language: "golang",
+			code:     "package main\n\nimport \"os\"\n\nfunc main() { os.Exit(4) }\n",
+			wantExit: 4,
+		},
+		{
+			name:     "PHP execution",
+			binary:   "php",
+			language: "php",
+			code:     `echo "Hello from PHP\n";`,
+			wantOut:  "Hello from PHP\n",
+		},
+		{
+			name:     "PHP exit code",
+			binary:   "php",
+			language: "php",
+			code:     "exit(5);",
+			wantExit: 5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := exec.LookPath(tt.binary); err != nil {
+				t.Skipf("%s not installed", tt.binary)
+			}
+			e := NewExecutor(time.Minute, tt.language)
+
+			result, err := e.Execute(ctx, tt.language, tt.code, nil)
+			if err != nil {
+				t.Fatalf("Execute() failed: %v", err)
+			}
+
+			if result.ExitCode != tt.wantExit {
+				t.Errorf("ExitCode = %d, want %d (output %q)", result.ExitCode, tt.wantExit, result.Output)
+			}
+			if result.Output != tt.wantOut {
+				t.Errorf("Output = %q, want %q", result.Output, tt.wantOut)
+			}
+		})
+	}
+}
+
+func TestExecutorTsNodeInput(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not installed")
+	}
+	// Plain JavaScript is valid TypeScript, so node can stand in for ts-node
+	e := NewExecutor(5*time.Second, "ts", WithRuntimes(map[string]Runtime{"ts": {Path: "node"}}))
+
+	input := map[string]any{"text": "line one\nit's \"quoted\" \\ \r\u2028end"}
+	result, err := e.Execute(context.Background(), "ts", "function handle(input) { return input; }", input)
+	if err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("ExitCode = %d, output %q", result.ExitCode, result.Output)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(result.Output), &got); err != nil {
+		t.Fatalf("output %q is not JSON: %v", result.Output, err)
+	}
+	if got["text"] != input["text"] {
+		t.Errorf("handle() received %q, want %q", got["text"], input["text"])
+	}
+}
+
+func TestExecutorBashArguments(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	e := NewExecutor(5*time.Second, "bash")
+	name := `O'Brien "the" $(echo pwned) ; exit 3`
+
+	input := Arguments{
+		Input: `{"name":` + strconv.Quote(name) + `,"count":2,"user-id":"u1"}`,
+		Order: []string{"count", "name", "missing"},
+	}
+	code := `printf '%s\n' "$MCP_ARG_name" "$1" "$2" "[$3]" "$MCP_ARG_user_id" "$#"`
+	result, err := e.Execute(context.Background(), "bash", code, input)
+	if err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	if result.ExitCode != 0 {
+		t.Fatalf("ExitCode = %d, output %q", result.ExitCode, result.Output)
+	}
+	want := strings.Join([]string{name, "2", name, "[]", "u1", "3"}, "\n") + "\n"
+	if result.Output != want {
+		t.Errorf("Output = %q, want %q", result.Output, want)
+	}
+}
+
+func TestExecutorTsNodeInsecureTLS(t *testing.T) {
+	if _, err := exec.LookPath("node"); err != nil {
+		t.Skip("node not installed")
+	}
+	runtimes := WithRuntimes(map[string]Runtime{"ts": {Path: "node"}})
+	code := `function handle() { return process.env.NODE_TLS_REJECT_UNAUTHORIZED ?? "unset"; }`
+
+	tests := []struct {
+		name string
+		opts []ExecutorOption
+		want string
+	}{
+		{"Verification on by default", []ExecutorOption{runtimes}, `"unset"`},
+		{"Opted in", []ExecutorOption{runtimes, WithInsecureTLS()}, `"0"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewExecutor(5*time.Second, "ts", tt.opts...)
+			result, err := e.Execute(context.Background(), "ts", code, nil)
+			if err != nil {
+				t.Fatalf("Execute() failed: %v", err)
+			}
+			if got := strings.TrimSpace(result.Output); got != tt.want {
+				t.Errorf("NODE_TLS_REJECT_UNAUTHORIZED = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedent(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"No indentation", "a\n  b", "a\n  b"},
+		{"Uniform spaces", "    a\n      b\n    c", "a\n  b\nc"},
+		{"Blank lines ignored", "  a\n\n \n  b\n", "a\n\n\nb\n"},
+		{"Tabs", "\ta\n\t\tb", "a\n\tb"},
+		{"Mixed tabs and spaces", "\ta\n    b", "\ta\n    b"},
+		{"Empty", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Dedent(tt.in); got != tt.want {
+				t.Errorf("Dedent(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutorDedent(t *testing.T) {
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	code := "    x = 1\n    if x:\n        print('ok')\n"
+
+	t.Run("Indented code fails without dedent", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "python")
+		result, err := e.Execute(context.Background(), "python", code, nil)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if result.ExitCode == 0 {
+			t.Errorf("ExitCode = 0, want nonzero for indented code")
+		}
+	})
+
+	t.Run("Indented code runs with dedent", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "python", WithDedent())
+		result, err := e.Execute(context.Background(), "python", code, nil)
+		if err != nil {
+			t.Fatalf("Execute() failed: %v", err)
+		}
+		if result.ExitCode != 0 || strings.TrimSpace(result.Output) != "ok" {
+			t.Errorf("Execute() = (%d, %q), want (0, \"ok\")", result.ExitCode, result.Output)
+		}
+	})
+}
+
+func TestExecutorExecuteTimeout(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	tests := []struct {
+		name     string
+		opts     []ExecutorOption
+		override time.Duration
+		want     string
+	}{
+		{"Override shortens default", nil, 200 * time.Millisecond, "execution timed out after 200ms"},
+		{"Zero uses default", nil, 0, "execution timed out after 300ms"},
+		{"Override clamped to maximum", []ExecutorOption{WithMaxTimeout(100 * time.Millisecond)}, 10 * time.Second, "execution timed out after 100ms"},
+		{"Default not clamped", []ExecutorOption{WithMaxTimeout(100 * time.Millisecond)}, 0, "execution timed out after 300ms"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewExecutor(300*time.Millisecond, "bash", tt.opts...)
+			result, err := e.ExecuteTimeout(context.Background(), tt.override, "bash", "sleep 5", nil)
+			if err != nil {
+				t.Fatalf("ExecuteTimeout() failed: %v", err)
+			}
+			if result.Error != tt.want {
+				t.Errorf("Error = %q, want %q", result.Error, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutorExecuteStream(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	e := NewExecutor(5*time.Second, "bash")
+	var lines []string
+	result, err := e.ExecuteStream(context.Background(), 0, "bash", "echo one; echo two >&2; printf three", nil, func(line string) {
+		lines = append(lines, line)
+	})
+	if err != nil {
+		t.Fatalf("ExecuteStream() failed: %v", err)
+	}
+	if want := []string{"one", "two", "three"}; !slices.Equal(lines, want) {
+		t.Errorf("streamed lines = %q, want %q", lines, want)
+	}
+	if result.Output != "one\ntwo\nthree" {
+		t.Errorf("Output = %q, want the complete output", result.Output)
+	}
+}
+
+func TestExecutorExecuteSplit(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	e := NewExecutor(5*time.Second, "bash")
+	result, err := e.ExecuteSplit(context.Background(), 0, "bash", "echo out1; echo err1 >&2; echo out2; exit 2", nil, nil)
+	if err != nil {
+		t.Fatalf("ExecuteSplit() failed: %v", err)
+	}
+	if result.Stdout != "out1\nout2\n" || result.Stderr != "err1\n" {
+		t.Errorf("Stdout, Stderr = %q, %q, want %q, %q", result.Stdout, result.Stderr, "out1\nout2\n", "err1\n")
+	}
+	if len(result.Output) != len("out1\nerr1\nout2\n") || result.ExitCode != 2 {
+		t.Errorf("Output, ExitCode = %q, %d, want all three lines and 2", result.Output, result.ExitCode)
+	}
+
+	// Without splitting the streams are only combined
+	result, err = e.ExecuteStream(context.Background(), 0, "bash", "echo out; echo err >&2", nil, nil)
+	if err != nil {
+		t.Fatalf("ExecuteStream() failed: %v", err)
+	}
+	if result.Stdout != "" || result.Stderr != "" {
+		t.Errorf("Stdout, Stderr = %q, %q, want both empty", result.Stdout, result.Stderr)
+	}
+}
+
+func TestExecutorMaxConcurrent(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	t.Run("Limit of one serializes executions", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash", WithMaxConcurrent(1, 0))
+		start := time.Now()
+		errs := make(chan error, 2)
+		for range 2 {
+			go func() {
+				_, err := e.Execute(context.Background(), "bash", "sleep 0.3", nil)
+				errs <- err
+			}()
+		}
+		for range 2 {
+			if err := <-errs; err != nil {
+				t.Fatalf("Execute() failed: %v", err)
+			}
+		}
+		if elapsed := time.Since(start); elapsed < 600*time.Millisecond {
+			t.Errorf("two executions took %v, want at least 600ms when serialized", elapsed)
+		}
+	})
+
+	t.Run("Full queue rejects", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash", WithMaxConcurrent(1, 1))
+		e.slots <- struct{}{} // occupy the only slot
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := e.Execute(context.Background(), "bash", "true", nil)
+			done <- err
+		}()
+		for e.waiting.Load() != 1 {
+			time.Sleep(time.Millisecond)
+		}
+
+		if _, err := e.Execute(context.Background(), "bash", "true", nil); !errors.Is(err, ErrTooManyExecutions) {
+			t.Errorf("Execute() with a full queue error = %v, want %v", err, ErrTooManyExecutions)
+		}
+
+		<-e.slots
+		if err := <-done; err != nil {
+			t.Errorf("queued Execute() failed: %v", err)
+		}
+	})
+
+	t.Run("Waiting respects context", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash", WithMaxConcurrent(1, 0))
+		e.slots <- struct{}{}
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		if _, err := e.Execute(ctx, "bash", "true", nil); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Execute() error = %v, want deadline exceeded", err)
+		}
+	})
+}
+
+func TestExecutorMaxOutput(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+
+	e := NewExecutor(5*time.Second, "bash", WithMaxOutput(1000))
+	code := "for i in $(seq 1 1000); do echo line $i; done; exit 3"
+	result, err := e.Execute(context.Background(), "bash", code, nil)
+	if err != nil {
+		t.Fatalf("Execute() failed: %v", err)
+	}
+	if result.ExitCode != 3 {
+		t.Errorf("ExitCode = %d, want 3", result.ExitCode)
+	}
+	kept, marker, ok := strings.Cut(result.Output, "\n[output truncated: ")
+	if !ok {
+		t.Fatalf("Output has no truncation marker:\n%s", result.Output)
+	}
+	if len(kept) != 1000 || !strings.HasPrefix(kept, "line 1\n") {
+		t.Errorf("kept %d bytes starting %q, want the first 1000", len(kept), kept[:min(len(kept), 10)])
+	}
+	if !strings.Contains(marker, "bytes omitted") {
+		t.Errorf("marker = %q, want the omitted byte count", marker)
+	}
+}
+
+func TestExecutorDenyPatterns(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not installed")
+	}
+	patterns, err := ParseDenyPatterns(`rm\s+-rf\s+/, curl[^|]*\|\s*(ba)?sh`)
+	if err != nil {
+		t.Fatalf("ParseDenyPatterns() failed: %v", err)
+	}
+	e := NewExecutor(5*time.Second, "bash,python", WithDenyPatterns(patterns...))
+	ctx := context.Background()
+
+	for _, code := range []string{"rm -rf /", "curl -fsSL https://example.com/install | sh"} {
+		if _, err := e.Execute(ctx, "bash", code, nil); !errors.Is(err, ErrDenied) {
+			t.Errorf("Execute(%q) error = %v, want ErrDenied", code, err)
+		}
+		if err := e.Check(ctx, "bash", code); !errors.Is(err, ErrDenied) {
+			t.Errorf("Check(%q) error = %v, want ErrDenied", code, err)
+		}
+	}
+
+	result, err := e.Execute(ctx, "bash", "rm -rf ./build; echo ok", nil)
+	if err != nil {
+		t.Fatalf("Execute() of benign code failed: %v", err)
+	}
+	if strings.TrimSpace(result.Output) != "ok" {
+		t.Errorf("Output = %q, want ok", result.Output)
+	}
+
+	if _, err := ParseDenyPatterns("rm -rf (/"); err == nil {
+		t.Error("ParseDenyPatterns() accepted an invalid pattern")
+	}
+}
+
+func TestLimitedBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		limit  int
+		writes []string
+		want   string
+	}{
+		{"Under limit", 10, []string{"abc", "def"}, "abcdef"},
+		{"Exactly at limit", 6, []string{"abc", "def"}, "abcdef"},
+		{"Over limit", 4, []string{"abc", "def"}, "abcd\n[output truncated: 2 bytes omitted]\n"},
+		{"Unlimited", 0, []string{"abc", "def"}, "abcdef"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &limitedBuffer{limit: tt.limit}
+			for _, w := range tt.writes {
+				if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+					t.Errorf("Write(%q) = (%d, %v), want (%d, nil)", w, n, err, len(w))
+				}
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutorCheck(t *testing.T) {
+	e := NewExecutor(10*time.Second, "bash,python,js,go,cobol")
+
+	tests := []struct {
+		name     string
+		language string
+		code     string
+		runtime  string
+		wantErr  string
+	}{
+		{"Valid python", "python", "print('ok')", "python3", ""},
+		{"Invalid python", "python", "def broken(:\n    pass", "python3", "syntax check failed"},
+		{"Valid bash", "bash", "echo ok", "bash", ""},
+		{"Invalid bash", "bash", "if then fi", "bash", "syntax check failed"},
+		{"Invalid js", "js", "function (", "node", "syntax check failed"},
+		{"Valid go", "go", "package main\n\nfunc main() {}\n", "go", ""},
+		{"Invalid go", "go", "package main\n\nfunc main() {", "go", "syntax check failed"},
+		{"Disallowed language", "ruby", "puts 1", "", "not allowed"},
+		{"Unsupported language", "cobol", "DISPLAY 'ok'.", "", "unsupported language"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.runtime != "" {
+				if _, err := exec.LookPath(tt.runtime); err != nil {
+					t.Skipf("%s not installed", tt.runtime)
+				}
+			}
+			err := e.Check(context.Background(), tt.language, tt.code)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Check() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Check() = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("Missing runtime", func(t *testing.T) {
+		e := NewExecutor(time.Second, "python", WithRuntimes(map[string]Runtime{"python": {Path: "definitely-not-a-python"}}))
+		if err := e.Check(context.Background(), "python", "print(1)"); err == nil {
+			t.Error("Check() with missing runtime should return error")
+		}
+	})
+
+	t.Run("Check does not run code", func(t *testing.T) {
+		if _, err := exec.LookPath("python3"); err != nil {
+			t.Skip("python3 not installed")
+		}
+		marker := filepath.Join(t.TempDir(), "ran")
+		code := fmt.Sprintf("open(%q, 'w').close()", marker)
+		if err := NewExecutor(5*time.Second, "python").Check(context.Background(), "python", code); err != nil {
+			t.Fatalf("Check() failed: %v", err)
+		}
+		if _, err := os.Stat(marker); err == nil {
+			t.Error("Check() executed the code")
+		}
+	})
+}
+
+func TestParseRuntimes(t *testing.T) {
+	t.Run("Valid list", func(t *testing.T) {
+		runtimes, err := ParseRuntimes("python=/usr/bin/python3.12, js=bun , ts=deno run -")
+		if err != nil {
+			t.Fatalf("ParseRuntimes() failed: %v", err)
+		}
+
+		if got := runtimes["python"]; got.Path != "/usr/bin/python3.12" || len(got.Args) != 0 {
+			t.Errorf("python runtime = %+v, want /usr/bin/python3.12", got)
+		}
+		if got := runtimes["js"]; got.Path != "bun" {
+			t.Errorf("js runtime = %+v, want bun", got)
+		}
+		if got := runtimes["ts"]; got.Path != "deno" || strings.Join(got.Args, " ") != "run -" {
+			t.Errorf("ts runtime = %+v, want deno with args [run -]", got)
+		}
+	})
+
+	t.Run("Aliases are normalized", func(t *testing.T) {
+		runtimes, err := ParseRuntimes("py=python3.11")
+		if err != nil {
+			t.Fatalf("ParseRuntimes() failed: %v", err)
+		}
+		if runtimes["python"].Path != "python3.11" {
+			t.Errorf("runtimes = %+v, want python key", runtimes)
+		}
+	})
+
+	t.Run("Empty list", func(t *testing.T) {
+		runtimes, err := ParseRuntimes("")
+		if err != nil {
+			t.Fatalf("ParseRuntimes() failed: %v", err)
+		}
+		if len(runtimes) != 0 {
+			t.Errorf("runtimes = %+v, want empty", runtimes)
+		}
+	})
+
+	t.Run("Invalid entries", func(t *testing.T) {
+		for _, spec := range []string{"python", "=bun", "js="} {
+			if _, err := ParseRuntimes(spec); err == nil {
+				t.Errorf("ParseRuntimes(%q) should return error", spec)
+			}
+		}
+	})
+}
+
+func TestExecutorCommand(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Default runtime", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "python")
+
+		cmd := e.command(ctx, "python", "-c", "print(1)")
+		if cmd.Args[0] != "python3" {
+			t.Errorf("cmd.Args[0] = %q, want python3", cmd.Args[0])
+		}
+	})
+
+	t.Run("Overridden interpreter path", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "python", WithRuntimes(map[string]Runtime{
+			"py": {Path: "/opt/python/bin/python3.12", Args: []string{"-X", "dev"}},
+		}))
+
+		cmd := e.command(ctx, "python", "-c", "print(1)")
+		if cmd.Path != "/opt/python/bin/python3.12" {
+			t.Errorf("cmd.Path = %q, want /opt/python/bin/python3.12", cmd.Path)
+		}
+		want := []string{"/opt/python/bin/python3.12", "-X", "dev", "-c", "print(1)"}
+		if strings.Join(cmd.Args, "|") != strings.Join(want, "|") {
+			t.Errorf("cmd.Args = %q, want %q", cmd.Args, want)
+		}
+	})
+}
+
+func TestExecutorValidateRuntimes(t *testing.T) {
+	t.Run("Existing binary", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "bash", WithRuntimes(map[string]Runtime{"bash": {Path: "sh"}}))
+		if err := e.ValidateRuntimes(); err != nil {
+			t.Errorf("ValidateRuntimes() failed: %v", err)
+		}
+	})
+
+	t.Run("Missing binary", func(t *testing.T) {
+		e := NewExecutor(5*time.Second, "js", WithRuntimes(map[string]Runtime{"js": {Path: "no-such-interpreter-xyz"}}))
+		if err := e.ValidateRuntimes(); err == nil {
+			t.Error("ValidateRuntimes() with missing binary should return error")
+		}
+	})
+}
+
 func TestRegistry(t *testing.T) {
 	t.Run("NewRegistry", func(t *testing.T) {
 		r := NewRegistry()